@@ -0,0 +1,224 @@
+package fas
+
+import (
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// appMetrics is a snapshot of an app's most recent reconciliation that is
+// exported as labeled Prometheus metrics & reported by the status API.
+type appMetrics struct {
+	processGroup    string
+	fleet           ExprVars           // machine counts by region
+	decision        *Decision          // most recent decision
+	values          map[string]float64 // collected metric values, by collector
+	lastReconcileAt time.Time          // time of the last reconciliation
+	lastSuccessAt   time.Time          // time of the last successful reconciliation
+	lastError       string             // most recent reconciliation error
+	lastErrorAt     time.Time
+	actions         map[string]int64 // number of decisions, by action
+	samples         []AppSample      // recent machine counts & targets
+}
+
+// recordAppMetrics updates the per-app snapshot after reconciling r. The prev
+// decision is the app's last decision before reconciling so that only new
+// decisions are counted. If collected is false, metric values & machine
+// counts are not recorded as they may belong to a previously reconciled app.
+func (p *ReconcilerPool) recordAppMetrics(r *Reconciler, prev *Decision, collected bool, err error) {
+	p.appMetrics.Lock()
+	defer p.appMetrics.Unlock()
+
+	m := p.appMetrics.m[r.AppName]
+	if m == nil {
+		m = &appMetrics{actions: make(map[string]int64)}
+		p.appMetrics.m[r.AppName] = m
+	}
+	m.processGroup = r.ProcessGroup
+	m.lastReconcileAt = time.Now()
+
+	if collected {
+		// Machine counts are only available if machines were listed.
+		if fleet := r.MachineCounts(); fleet.CreatedNByRegion != nil {
+			m.fleet = fleet
+		}
+
+		m.values = make(map[string]float64, len(r.Collectors))
+		for _, c := range r.Collectors {
+			if v, ok := r.Value(c.Name()); ok {
+				m.values[c.Name()] = v
+			}
+		}
+	}
+
+	if d := r.State.LastDecision; d != nil && d != prev {
+		decision := *d
+		m.decision = &decision
+		m.actions[d.Action]++
+	}
+
+	m.samples = append(m.samples, newAppSample(r, prev, collected, m.lastReconcileAt, err))
+	if len(m.samples) > MaxAppSampleN {
+		m.samples = m.samples[len(m.samples)-MaxAppSampleN:]
+	}
+
+	if err == nil {
+		m.lastSuccessAt = m.lastReconcileAt
+	} else {
+		m.lastError, m.lastErrorAt = err.Error(), m.lastReconcileAt
+	}
+}
+
+// pruneAppMetrics removes metrics for apps that are no longer managed.
+func (p *ReconcilerPool) pruneAppMetrics(apps map[string]appInfo) {
+	p.appMetrics.Lock()
+	defer p.appMetrics.Unlock()
+
+	for name := range p.appMetrics.m {
+		if _, ok := apps[name]; !ok {
+			delete(p.appMetrics.m, name)
+		}
+	}
+}
+
+// exportedAppNamesLocked returns the sorted names of apps with metrics that
+// match AppMetricsApps, limited to AppMetricsMaxApps. Also returns the number
+// of matching apps that were dropped because of the limit.
+func (p *ReconcilerPool) exportedAppNamesLocked() (names []string, droppedN int) {
+	var patterns []*regexp.Regexp
+	for _, s := range p.AppMetricsApps {
+		if re, err := regexp.Compile(FormatWildcardAsRegexp(s)); err == nil {
+			patterns = append(patterns, re)
+		}
+	}
+
+	for name := range p.appMetrics.m {
+		if len(patterns) > 0 && !matchesAny(patterns, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if p.AppMetricsMaxApps > 0 && len(names) > p.AppMetricsMaxApps {
+		names, droppedN = names[:p.AppMetricsMaxApps], len(names)-p.AppMetricsMaxApps
+	}
+	return names, droppedN
+}
+
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	appMachinesDesc = prometheus.NewDesc(
+		"fas_app_machines",
+		"Current number of machines in each state for an app.",
+		[]string{"app", "process_group", "region", "state"}, nil,
+	)
+	appTargetMachinesDesc = prometheus.NewDesc(
+		"fas_app_target_machines",
+		"Most recent min & max target machine counts for an app.",
+		[]string{"app", "process_group", "machines", "bound"}, nil,
+	)
+	appMetricValueDesc = prometheus.NewDesc(
+		"fas_app_metric_value",
+		"Most recent value collected by each metric collector for an app.",
+		[]string{"app", "process_group", "metric"}, nil,
+	)
+	appLastReconcileDesc = prometheus.NewDesc(
+		"fas_app_last_reconcile_success_timestamp_seconds",
+		"Unix time of the last successful reconciliation for an app.",
+		[]string{"app", "process_group"}, nil,
+	)
+	appActionCountDesc = prometheus.NewDesc(
+		"fas_app_reconcile_count",
+		"Number of reconciliation decisions for an app, by action.",
+		[]string{"app", "process_group", "action"}, nil,
+	)
+	appMetricsDroppedDesc = prometheus.NewDesc(
+		"fas_app_metrics_dropped_apps",
+		"Number of apps whose metrics are not exported because of the app limit.",
+		nil, nil,
+	)
+)
+
+// appMetricsCollector reports labeled metrics for each app.
+type appMetricsCollector struct {
+	pool *ReconcilerPool
+}
+
+func (c *appMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- appMachinesDesc
+	ch <- appTargetMachinesDesc
+	ch <- appMetricValueDesc
+	ch <- appLastReconcileDesc
+	ch <- appActionCountDesc
+	ch <- appMetricsDroppedDesc
+}
+
+func (c *appMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	p := c.pool
+	p.appMetrics.Lock()
+	defer p.appMetrics.Unlock()
+
+	names, droppedN := p.exportedAppNamesLocked()
+	ch <- prometheus.MustNewConstMetric(appMetricsDroppedDesc, prometheus.GaugeValue, float64(droppedN))
+
+	for _, name := range names {
+		m := p.appMetrics.m[name]
+		group := m.processGroup
+
+		// Machine counts are broken down by region, if enabled. Otherwise the
+		// region label is left blank & counts are totaled for the app.
+		if p.AppMetricsRegions {
+			for region := range m.fleet.CreatedNByRegion {
+				c.collectMachines(ch, name, group, region,
+					m.fleet.CreatedNByRegion[region], m.fleet.StartedNByRegion[region], m.fleet.StoppedNByRegion[region])
+			}
+		} else if m.fleet.CreatedNByRegion != nil {
+			c.collectMachines(ch, name, group, "", m.fleet.CreatedN, m.fleet.StartedN, m.fleet.StoppedN)
+		}
+
+		if d := m.decision; d != nil {
+			for _, target := range []struct {
+				machines, bound string
+				v               *int
+			}{
+				{"created", "min", d.MinCreatedN},
+				{"created", "max", d.MaxCreatedN},
+				{"started", "min", d.MinStartedN},
+				{"started", "max", d.MaxStartedN},
+			} {
+				if target.v != nil {
+					ch <- prometheus.MustNewConstMetric(appTargetMachinesDesc, prometheus.GaugeValue, float64(*target.v), name, group, target.machines, target.bound)
+				}
+			}
+		}
+
+		for metric, v := range m.values {
+			ch <- prometheus.MustNewConstMetric(appMetricValueDesc, prometheus.GaugeValue, v, name, group, metric)
+		}
+
+		if !m.lastSuccessAt.IsZero() {
+			ch <- prometheus.MustNewConstMetric(appLastReconcileDesc, prometheus.GaugeValue, float64(m.lastSuccessAt.UnixNano())/1e9, name, group)
+		}
+
+		for action, n := range m.actions {
+			ch <- prometheus.MustNewConstMetric(appActionCountDesc, prometheus.CounterValue, float64(n), name, group, action)
+		}
+	}
+}
+
+func (c *appMetricsCollector) collectMachines(ch chan<- prometheus.Metric, app, group, region string, createdN, startedN, stoppedN int) {
+	ch <- prometheus.MustNewConstMetric(appMachinesDesc, prometheus.GaugeValue, float64(createdN), app, group, region, "created")
+	ch <- prometheus.MustNewConstMetric(appMachinesDesc, prometheus.GaugeValue, float64(startedN), app, group, region, "started")
+	ch <- prometheus.MustNewConstMetric(appMachinesDesc, prometheus.GaugeValue, float64(stoppedN), app, group, region, "stopped")
+}
@@ -0,0 +1,305 @@
+package fas
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default rotation settings for FileAuditLog.
+const (
+	DefaultAuditLogMaxSize  = 10 << 20 // 10MB
+	DefaultAuditLogMaxFiles = 5
+)
+
+// AuditLog represents a persistent record of scaling decisions. This allows
+// operators to determine when & why the autoscaler acted long after the fact.
+type AuditLog interface {
+	// WriteAuditEvent appends an event to the log.
+	WriteAuditEvent(ctx context.Context, e *AuditEvent) error
+
+	// AuditEvents returns events matching the filter, sorted by timestamp.
+	AuditEvents(ctx context.Context, filter AuditFilter) ([]*AuditEvent, error)
+}
+
+// AuditEvent represents a single reconciliation of an app, including the
+// inputs used to make the decision & its outcome.
+type AuditEvent struct {
+	App string `json:"app"`
+
+	// Decision made by the reconciler, including the computed targets, the
+	// action taken & the IDs of affected machines. Only the timestamp is set if
+	// the reconciliation failed before a decision was made.
+	Decision
+
+	// Values of all metrics & variables used by expressions.
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+
+	// Error returned by the reconciliation, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// AuditFilter represents a filter on audit events. Zero values are ignored.
+type AuditFilter struct {
+	App    string // wildcard app name pattern
+	Action string
+	Since  time.Time
+	Until  time.Time
+
+	// Maximum number of events to return. The most recent events are kept.
+	Limit int
+}
+
+// Matcher returns a function that reports whether an event matches the filter.
+func (f *AuditFilter) Matcher() (func(*AuditEvent) bool, error) {
+	re, err := regexp.Compile(FormatWildcardAsRegexp(f.App))
+	if err != nil {
+		return nil, fmt.Errorf("invalid app pattern %q", f.App)
+	}
+
+	return func(e *AuditEvent) bool {
+		switch {
+		case !re.MatchString(e.App):
+			return false
+		case f.Action != "" && e.Action != f.Action:
+			return false
+		case !f.Since.IsZero() && e.Timestamp.Before(f.Since):
+			return false
+		case !f.Until.IsZero() && !e.Timestamp.Before(f.Until):
+			return false
+		}
+		return true
+	}, nil
+}
+
+// Apply sorts events by timestamp & trims them to the filter's limit.
+func (f *AuditFilter) Apply(events []*AuditEvent) []*AuditEvent {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+	if f.Limit > 0 && len(events) > f.Limit {
+		events = events[len(events)-f.Limit:]
+	}
+	return events
+}
+
+var _ AuditLog = (*FileAuditLog)(nil)
+
+// FileAuditLog is an implementation of AuditLog that appends events to a
+// JSONL file. The file is rotated once it exceeds MaxSize & up to MaxFiles
+// rotated files are kept with a numeric suffix (e.g. "audit.jsonl.1").
+type FileAuditLog struct {
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+
+	// Path to the active log file. Must be set before calling Open().
+	Path string
+
+	// Size, in bytes, at which the file is rotated.
+	MaxSize int64
+
+	// Number of rotated files to retain.
+	MaxFiles int
+}
+
+// NewFileAuditLog returns a new instance of FileAuditLog.
+func NewFileAuditLog(path string) *FileAuditLog {
+	return &FileAuditLog{
+		Path:     path,
+		MaxSize:  DefaultAuditLogMaxSize,
+		MaxFiles: DefaultAuditLogMaxFiles,
+	}
+}
+
+// Open opens the active log file for appending.
+func (l *FileAuditLog) Open() error {
+	if l.Path == "" {
+		return fmt.Errorf("audit log path required")
+	}
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0o700); err != nil {
+		return err
+	}
+	return l.openFile()
+}
+
+func (l *FileAuditLog) openFile() (err error) {
+	if l.f, err = os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600); err != nil {
+		return err
+	}
+
+	fi, err := l.f.Stat()
+	if err != nil {
+		return err
+	}
+	l.size = fi.Size()
+	return nil
+}
+
+// Close closes the active log file.
+func (l *FileAuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f != nil {
+		return l.f.Close()
+	}
+	return nil
+}
+
+// WriteAuditEvent appends e to the active log file, rotating it if needed.
+func (l *FileAuditLog) WriteAuditEvent(ctx context.Context, e *AuditEvent) error {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	buf = append(buf, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.MaxSize > 0 && l.size > 0 && l.size+int64(len(buf)) > l.MaxSize {
+		if err := l.rotate(); err != nil {
+			return fmt.Errorf("rotate audit log: %w", err)
+		}
+	}
+
+	n, err := l.f.Write(buf)
+	l.size += int64(n)
+	return err
+}
+
+// rotate shifts each rotated file up by one, removing the oldest, and then
+// moves the active file to the ".1" suffix & reopens a new active file.
+func (l *FileAuditLog) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(l.rotatedPath(l.MaxFiles)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := l.MaxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(l.rotatedPath(i), l.rotatedPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if l.MaxFiles > 0 {
+		if err := os.Rename(l.Path, l.rotatedPath(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(l.Path); err != nil {
+		return err
+	}
+	return l.openFile()
+}
+
+func (l *FileAuditLog) rotatedPath(i int) string {
+	return fmt.Sprintf("%s.%d", l.Path, i)
+}
+
+// AuditEvents reads events matching filter from the rotated & active files.
+func (l *FileAuditLog) AuditEvents(ctx context.Context, filter AuditFilter) ([]*AuditEvent, error) {
+	match, err := filter.Matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Read from the oldest rotated file to the active file.
+	paths := []string{l.Path}
+	for i := 1; i <= l.MaxFiles; i++ {
+		paths = append([]string{l.rotatedPath(i)}, paths...)
+	}
+
+	var events []*AuditEvent
+	for _, path := range paths {
+		if events, err = readAuditFile(path, match, events); err != nil {
+			return nil, err
+		}
+	}
+	return filter.Apply(events), nil
+}
+
+func readAuditFile(path string, match func(*AuditEvent) bool, events []*AuditEvent) ([]*AuditEvent, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return events, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("decode audit event (%s): %w", path, err)
+		}
+		if match(&e) {
+			events = append(events, &e)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// writeAuditEvent records the outcome of reconciling r in the audit log. The
+// prev decision is the app's last decision before reconciling so that only new
+// decisions are recorded. If collected is false, metrics are not recorded as
+// they may belong to a previously reconciled app.
+func (p *ReconcilerPool) writeAuditEvent(ctx context.Context, r *Reconciler, prev *Decision, collected bool, err error) {
+	if p.AuditLog == nil {
+		return
+	}
+
+	e := &AuditEvent{App: r.AppName}
+	if d := r.State.LastDecision; d != nil && d != prev {
+		e.Decision = *d
+	} else if err == nil {
+		return // no decision was made, e.g. a release is in progress
+	} else {
+		e.Timestamp = r.Now()
+	}
+
+	// Skip no-op decisions unless requested as they are made on every interval.
+	if e.Action == ActionNoScale && err == nil && !p.AuditNoScale {
+		return
+	}
+
+	if collected {
+		e.Metrics = make(map[string]float64)
+		for _, name := range r.MetricNames() {
+			if v, ok := r.Value(name); ok {
+				e.Metrics[name] = v
+			}
+		}
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	if err := p.AuditLog.WriteAuditEvent(ctx, e); err != nil {
+		slog.Error("cannot write audit event",
+			slog.String("app", r.AppName),
+			slog.Any("err", err))
+	}
+}
+
+// AuditEvents returns events from the audit log that match the filter.
+// Returns ErrAuditLogDisabled if no audit log is configured.
+func (p *ReconcilerPool) AuditEvents(ctx context.Context, filter AuditFilter) ([]*AuditEvent, error) {
+	if p.AuditLog == nil {
+		return nil, ErrAuditLogDisabled
+	}
+	return p.AuditLog.AuditEvents(ctx, filter)
+}
@@ -0,0 +1,149 @@
+package fas_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/mock"
+	fly "github.com/superfly/fly-go"
+)
+
+func TestFileAuditLog(t *testing.T) {
+	t.Run("Filter", func(t *testing.T) {
+		l := newOpenFileAuditLog(t)
+		timestamp := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, app := range []string{"my-app-1", "my-app-2", "other-app", "my-app-1"} {
+			e := &fas.AuditEvent{App: app}
+			e.Timestamp = timestamp.Add(time.Duration(i) * time.Minute)
+			e.Action = fas.ActionStart
+			if err := l.WriteAuditEvent(context.Background(), e); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		events, err := l.AuditEvents(context.Background(), fas.AuditFilter{App: "my-app-*", Since: timestamp.Add(time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := len(events), 2; got != want {
+			t.Fatalf("len=%v, want %v", got, want)
+		} else if got, want := events[0].App, "my-app-2"; got != want {
+			t.Fatalf("App=%v, want %v", got, want)
+		} else if got, want := events[1].Timestamp, timestamp.Add(3*time.Minute); !got.Equal(want) {
+			t.Fatalf("Timestamp=%v, want %v", got, want)
+		}
+
+		// Only the most recent events are returned when limited.
+		if events, err := l.AuditEvents(context.Background(), fas.AuditFilter{Limit: 1}); err != nil {
+			t.Fatal(err)
+		} else if got, want := len(events), 1; got != want {
+			t.Fatalf("len=%v, want %v", got, want)
+		} else if got, want := events[0].Timestamp, timestamp.Add(3*time.Minute); !got.Equal(want) {
+			t.Fatalf("Timestamp=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Rotate", func(t *testing.T) {
+		l := newOpenFileAuditLog(t)
+		l.MaxSize, l.MaxFiles = 200, 2
+
+		timestamp := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < 20; i++ {
+			e := &fas.AuditEvent{App: "my-app"}
+			e.Timestamp = timestamp.Add(time.Duration(i) * time.Minute)
+			if err := l.WriteAuditEvent(context.Background(), e); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Older events are removed with the oldest rotated file.
+		events, err := l.AuditEvents(context.Background(), fas.AuditFilter{})
+		if err != nil {
+			t.Fatal(err)
+		} else if len(events) == 0 || len(events) >= 20 {
+			t.Fatalf("unexpected event count: %d", len(events))
+		} else if got, want := events[len(events)-1].Timestamp, timestamp.Add(19*time.Minute); !got.Equal(want) {
+			t.Fatalf("Timestamp=%v, want %v", got, want)
+		}
+	})
+}
+
+// Ensure the pool records scaling decisions & failures in the audit log.
+func TestReconcilerPool_AuditLog(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return &fly.Release{Status: "completed"}, nil
+	}
+
+	var flapsClient mock.FlapsClient
+	flapsClient.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+			{ID: "2", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+	flapsClient.StartFunc = func(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+		return &fly.MachineStartResponse{}, nil
+	}
+
+	collector := mock.NewMetricCollector("queue_depth")
+	collector.CollectMetricFunc = func(ctx context.Context, app string) (float64, error) {
+		return 2, nil
+	}
+
+	l := newOpenFileAuditLog(t)
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.AppName = "my-app"
+	p.ReconcileInterval = time.Hour
+	p.AuditLog = l
+	p.NewReconciler = func() *fas.Reconciler {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN = "queue_depth"
+		r.Collectors = []fas.MetricCollector{collector}
+		return r
+	}
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &flapsClient, nil
+	}
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	if _, err := p.Trigger("my-app"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	events, err := p.AuditEvents(context.Background(), fas.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := len(events), 1; got != want {
+		t.Fatalf("len=%v, want %v", got, want)
+	}
+
+	e := events[0]
+	if got, want := e.App, "my-app"; got != want {
+		t.Fatalf("App=%v, want %v", got, want)
+	} else if got, want := e.Action, fas.ActionStart; got != want {
+		t.Fatalf("Action=%v, want %v", got, want)
+	} else if got, want := e.MachineIDs, []string{"1", "2"}; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("MachineIDs=%v, want %v", got, want)
+	} else if got, want := e.Metrics["queue_depth"], 2.0; got != want {
+		t.Fatalf("Metrics[queue_depth]=%v, want %v", got, want)
+	} else if got, want := *e.MinStartedN, 2; got != want {
+		t.Fatalf("MinStartedN=%v, want %v", got, want)
+	}
+}
+
+func newOpenFileAuditLog(tb testing.TB) *fas.FileAuditLog {
+	tb.Helper()
+	l := fas.NewFileAuditLog(filepath.Join(tb.TempDir(), "audit.jsonl"))
+	if err := l.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = l.Close() })
+	return l
+}
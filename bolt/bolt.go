@@ -0,0 +1,180 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultAuditRetention is the default time audit events are kept.
+const DefaultAuditRetention = 7 * 24 * time.Hour
+
+// Bucket names.
+var (
+	appsBucket  = []byte("apps")
+	auditBucket = []byte("audit")
+)
+
+var (
+	_ fas.Store    = (*Store)(nil)
+	_ fas.AuditLog = (*Store)(nil)
+)
+
+// Store is an implementation of fas.Store that persists state to a local
+// BoltDB file. This is typically placed on a Fly volume so it survives restarts.
+type Store struct {
+	db *bolt.DB
+
+	// Path to the database file. Must be set before calling Open().
+	Path string
+
+	// Time to keep audit events. Older events are removed on write.
+	AuditRetention time.Duration
+
+	// Returns the current time. Used for testing.
+	Now func() time.Time
+}
+
+// NewStore returns a new instance of Store.
+func NewStore(path string) *Store {
+	return &Store{
+		Path:           path,
+		AuditRetention: DefaultAuditRetention,
+		Now:            time.Now,
+	}
+}
+
+// Open opens the database file & initializes the schema.
+func (s *Store) Open() (err error) {
+	if s.Path == "" {
+		return fmt.Errorf("bolt store path required")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return err
+	}
+
+	if s.db, err = bolt.Open(s.Path, 0o600, &bolt.Options{Timeout: 5 * time.Second}); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{appsBucket, auditBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close closes the underlying database file.
+func (s *Store) Close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// AppStates returns the state for all apps, sorted by app name.
+func (s *Store) AppStates(ctx context.Context) ([]*fas.AppState, error) {
+	var a []*fas.AppState
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(appsBucket).ForEach(func(k, v []byte) error {
+			var state fas.AppState
+			if err := json.Unmarshal(v, &state); err != nil {
+				return fmt.Errorf("decode app state %q: %w", k, err)
+			}
+			a = append(a, &state)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// PutAppState creates or replaces the state for a single app.
+func (s *Store) PutAppState(ctx context.Context, state *fas.AppState) error {
+	buf, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(appsBucket).Put([]byte(state.AppName), buf)
+	})
+}
+
+// WriteAuditEvent stores e & removes events older than the retention period.
+// Events are keyed by timestamp so they are iterated in time order.
+func (s *Store) WriteAuditEvent(ctx context.Context, e *fas.AuditEvent) error {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(auditBucket)
+		if err := bkt.Put(auditEventKey(e.Timestamp, e.App), buf); err != nil {
+			return err
+		}
+
+		if s.AuditRetention <= 0 {
+			return nil
+		}
+		cutoff := auditEventKey(s.Now().Add(-s.AuditRetention), "")
+		cur := bkt.Cursor()
+		for k, _ := cur.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = cur.Next() {
+			if err := cur.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AuditEvents returns events matching filter, sorted by timestamp.
+func (s *Store) AuditEvents(ctx context.Context, filter fas.AuditFilter) ([]*fas.AuditEvent, error) {
+	match, err := filter.Matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	var a []*fas.AuditEvent
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		cur := tx.Bucket(auditBucket).Cursor()
+
+		k, v := cur.First()
+		if !filter.Since.IsZero() {
+			k, v = cur.Seek(auditEventKey(filter.Since, ""))
+		}
+		for ; k != nil; k, v = cur.Next() {
+			var e fas.AuditEvent
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("decode audit event: %w", err)
+			}
+			if match(&e) {
+				a = append(a, &e)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return filter.Apply(a), nil
+}
+
+// auditEventKey returns a key that sorts by timestamp & then by app name.
+func auditEventKey(t time.Time, app string) []byte {
+	key := binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano()))
+	return append(key, app...)
+}
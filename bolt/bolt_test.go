@@ -0,0 +1,108 @@
+package bolt_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/bolt"
+)
+
+func TestStore_AppStates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	s := bolt.NewStore(path)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+
+	timestamp := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	state := fas.NewAppState("app-b")
+	state.LastDecision = &fas.Decision{Timestamp: timestamp, Action: fas.ActionStart, N: 2}
+	state.CooldownUntil = timestamp.Add(time.Minute)
+	state.AddMetricSample("queue_depth", fas.MetricSample{Timestamp: timestamp, Value: 10})
+	if err := s.PutAppState(context.Background(), state); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutAppState(context.Background(), fas.NewAppState("app-a")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen the store to ensure state persists.
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s = bolt.NewStore(path)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+
+	states, err := s.AppStates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := len(states), 2; got != want {
+		t.Fatalf("len=%v, want %v", got, want)
+	} else if got, want := states[0].AppName, "app-a"; got != want {
+		t.Fatalf("AppName=%v, want %v", got, want)
+	}
+
+	other := states[1]
+	if got, want := other.AppName, "app-b"; got != want {
+		t.Fatalf("AppName=%v, want %v", got, want)
+	} else if got, want := other.LastDecision.Action, fas.ActionStart; got != want {
+		t.Fatalf("Action=%v, want %v", got, want)
+	} else if got, want := other.LastDecision.N, 2; got != want {
+		t.Fatalf("N=%v, want %v", got, want)
+	} else if got, want := other.CooldownUntil, timestamp.Add(time.Minute); !got.Equal(want) {
+		t.Fatalf("CooldownUntil=%v, want %v", got, want)
+	} else if got, want := other.Metrics["queue_depth"][0].Value, 10.0; got != want {
+		t.Fatalf("Value=%v, want %v", got, want)
+	}
+}
+
+func TestStore_AuditEvents(t *testing.T) {
+	s := bolt.NewStore(filepath.Join(t.TempDir(), "db"))
+	s.AuditRetention = time.Hour
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+
+	timestamp := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Now = func() time.Time { return timestamp.Add(90 * time.Minute) }
+
+	for i, app := range []string{"app-a", "app-b", "app-a"} {
+		e := &fas.AuditEvent{App: app, Error: "marker"}
+		e.Timestamp = timestamp.Add(time.Duration(i) * time.Hour)
+		e.Action = fas.ActionStart
+		e.MachineIDs = []string{"1"}
+		if err := s.WriteAuditEvent(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first event is older than the retention period so it is removed.
+	events, err := s.AuditEvents(context.Background(), fas.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := len(events), 2; got != want {
+		t.Fatalf("len=%v, want %v", got, want)
+	} else if got, want := events[0].App, "app-b"; got != want {
+		t.Fatalf("App=%v, want %v", got, want)
+	} else if got, want := events[0].MachineIDs, []string{"1"}; len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("MachineIDs=%v, want %v", got, want)
+	}
+
+	events, err = s.AuditEvents(context.Background(), fas.AuditFilter{App: "app-a", Since: timestamp.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := len(events), 1; got != want {
+		t.Fatalf("len=%v, want %v", got, want)
+	} else if got, want := events[0].Timestamp, timestamp.Add(2*time.Hour); !got.Equal(want) {
+		t.Fatalf("Timestamp=%v, want %v", got, want)
+	}
+}
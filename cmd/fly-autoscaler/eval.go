@@ -2,9 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	fas "github.com/superfly/fly-autoscaler"
 )
@@ -13,6 +18,16 @@ import (
 // This is use as a test command when setting up or debugging the autoscaler.
 type EvalCommand struct {
 	Config *Config
+
+	// If set, expressions are evaluated as if the current time is At.
+	At time.Time
+
+	// If set, the started machine policy is run against the metric values in
+	// the CSV file instead of collecting metrics once.
+	SimulatePath string
+
+	// Number of started machines at the beginning of a simulation.
+	SimulateStartedN int
 }
 
 func NewEvalCommand() *EvalCommand {
@@ -26,6 +41,9 @@ func (c *EvalCommand) Run(ctx context.Context, args []string) (err error) {
 	if err := c.Config.Validate(); err != nil {
 		return err
 	}
+	if c.SimulatePath != "" {
+		return c.simulate(ctx)
+	}
 
 	collectors, err := c.Config.NewMetricCollectors()
 	if err != nil {
@@ -40,12 +58,35 @@ func (c *EvalCommand) Run(ctx context.Context, args []string) (err error) {
 	r.MinStartedMachineN = c.Config.GetMinStartedMachineN()
 	r.MaxStartedMachineN = c.Config.GetMaxStartedMachineN()
 	r.Collectors = collectors
+	r.Variables = c.Config.GetVariables()
+	r.Forecasters = c.Config.NewForecasters()
+	r.Schedules = c.Config.GetSchedules()
+	r.Guardrails = c.Config.GetGuardrails()
+	r.GuardrailStrict = c.Config.GuardrailStrict
+	if r.CreatedMachinePolicy, err = c.Config.CreatedMachinePolicy.NewPolicy(); err != nil {
+		return fmt.Errorf("cannot create created machine policy: %w", err)
+	}
+	if r.StartedMachinePolicy, err = c.Config.StartedMachinePolicy.NewPolicy(); err != nil {
+		return fmt.Errorf("cannot create started machine policy: %w", err)
+	}
+	if !c.At.IsZero() {
+		r.Now = func() time.Time { return c.At }
+	}
 
 	if err := r.CollectMetrics(ctx); err != nil {
 		return fmt.Errorf("metrics collection failed: %w", err)
 	}
+	if err := r.EvalVariables(); err != nil {
+		return fmt.Errorf("cannot evaluate variables: %w", err)
+	}
 
 	var out evalOutput
+	if len(r.Variables) > 0 {
+		out.Variables = make(map[string]float64)
+		for _, v := range r.Variables {
+			out.Variables[v.Name], _ = r.Value(v.Name)
+		}
+	}
 	if v, ok, err := r.CalcMinCreatedMachineN(); err != nil {
 		return fmt.Errorf("cannot calculate min created machine count: %w", err)
 	} else if ok {
@@ -70,6 +111,24 @@ func (c *EvalCommand) Run(ctx context.Context, args []string) (err error) {
 		out.Started.Max = &v
 	}
 
+	// Report the targets after any active schedules & guardrails are applied.
+	decision := &fas.Decision{
+		Timestamp:   r.Now(),
+		MinCreatedN: out.Created.Min,
+		MaxCreatedN: out.Created.Max,
+		MinStartedN: out.Started.Min,
+		MaxStartedN: out.Started.Max,
+	}
+	if out.Paused, err = r.ApplySchedules(decision); err != nil {
+		return fmt.Errorf("cannot apply schedules: %w", err)
+	}
+	if err := r.ApplyGuardrails(decision); err != nil {
+		return err
+	}
+	out.Schedules = decision.Schedules
+	out.Created.Min, out.Created.Max = decision.MinCreatedN, decision.MaxCreatedN
+	out.Started.Min, out.Started.Max = decision.MinStartedN, decision.MaxStartedN
+
 	buf, err := json.MarshalIndent(out, "", "  ")
 	if err != nil {
 		return err
@@ -82,6 +141,9 @@ func (c *EvalCommand) Run(ctx context.Context, args []string) (err error) {
 func (c *EvalCommand) parseFlags(ctx context.Context, args []string) (err error) {
 	fs := flag.NewFlagSet("fly-autoscaler-serve", flag.ContinueOnError)
 	configPath := registerConfigPathFlag(fs)
+	at := fs.String("at", "", "evaluate as if at the given RFC 3339 time")
+	fs.StringVar(&c.SimulatePath, "simulate", "", "simulate the started machine policy over a CSV of metric values")
+	fs.IntVar(&c.SimulateStartedN, "started", 1, "initial started machine count for -simulate")
 	fs.Usage = func() {
 		fmt.Println(`
 The eval command runs collects metrics once and evaluates the given expression.
@@ -102,6 +164,12 @@ Arguments:
 		return fmt.Errorf("too many arguments")
 	}
 
+	if *at != "" {
+		if c.At, err = time.Parse(time.RFC3339, *at); err != nil {
+			return fmt.Errorf("cannot parse -at as RFC 3339 time: %q", *at)
+		}
+	}
+
 	if c.Config, err = NewConfigFromEnv(); err != nil {
 		return err
 	}
@@ -114,7 +182,125 @@ Arguments:
 	return nil
 }
 
+// simulate runs the started machine policy once for each metric value in the
+// CSV file and prints the target after each step. Each step is assumed to be
+// one reconcile interval apart and the target is assumed to be reached before
+// the next step. This can be used to tune a policy against a step response.
+//
+// Each row contains either a metric value or an RFC 3339 time & a value. A
+// header row is skipped.
+func (c *EvalCommand) simulate(ctx context.Context) error {
+	policy, err := c.Config.StartedMachinePolicy.NewPolicy()
+	if err != nil {
+		return fmt.Errorf("cannot create started machine policy: %w", err)
+	} else if policy == nil {
+		return fmt.Errorf("started machine policy required for -simulate")
+	}
+
+	names := policy.MetricNames()
+	if len(names) != 1 {
+		return fmt.Errorf("cannot simulate policy with %d metrics", len(names))
+	}
+
+	f, err := os.Open(c.SimulatePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	rd := csv.NewReader(f)
+	rd.FieldsPerRecord = -1
+	records, err := rd.ReadAll()
+	if err != nil {
+		return fmt.Errorf("cannot read simulation csv: %w", err)
+	}
+
+	now := c.At
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	// Forecasts are made from the simulated values instead of range queries.
+	forecaster := &fas.Forecaster{Metric: names[0]}
+	for _, f := range c.Config.NewForecasters() {
+		if f.Metric == names[0] {
+			forecaster, f.Lookback = f, 0
+		}
+	}
+
+	state := fas.NewAppState(c.Config.AppName)
+	current := c.SimulateStartedN
+	steps := make([]simulateStep, 0, len(records))
+	for i, record := range records {
+		t, value, err := parseSimulateRecord(record, now)
+		if err != nil {
+			if i == 0 {
+				continue // header
+			}
+			return fmt.Errorf("simulation csv line %d: %w", i+1, err)
+		}
+
+		state.AddMetricSample(names[0], fas.MetricSample{Timestamp: t, Value: value})
+		n, err := policy.Desired(&fas.PolicyInput{
+			Name:    fas.PolicyNameStarted,
+			Current: current,
+			Metrics: map[string]float64{names[0]: value},
+			State:   state,
+			Now:     t,
+			Forecast: func(name string, horizon time.Duration) (float64, error) {
+				return forecaster.Forecast(state.Metrics[name], t, horizon)
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("simulation csv line %d: %w", i+1, err)
+		}
+
+		steps = append(steps, simulateStep{Time: t, Value: value, Started: n})
+		current = n
+		now = t.Add(c.Config.Interval)
+	}
+
+	buf, err := json.MarshalIndent(steps, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(buf))
+
+	return nil
+}
+
+// parseSimulateRecord returns the time & metric value for a CSV record. If the
+// record has no time then def is returned.
+func parseSimulateRecord(record []string, def time.Time) (t time.Time, value float64, err error) {
+	t = def
+	switch len(record) {
+	case 1:
+	case 2:
+		if t, err = time.Parse(time.RFC3339, strings.TrimSpace(record[0])); err != nil {
+			return t, 0, fmt.Errorf("invalid time: %q", record[0])
+		}
+	default:
+		return t, 0, fmt.Errorf("expected 1 or 2 fields, got %d", len(record))
+	}
+
+	s := strings.TrimSpace(record[len(record)-1])
+	if value, err = strconv.ParseFloat(s, 64); err != nil {
+		return t, 0, fmt.Errorf("invalid value: %q", s)
+	}
+	return t, value, nil
+}
+
+type simulateStep struct {
+	Time    time.Time `json:"time"`
+	Value   float64   `json:"value"`
+	Started int       `json:"started"`
+}
+
 type evalOutput struct {
+	Variables map[string]float64 `json:"variables,omitempty"`
+	Schedules []string           `json:"schedules,omitempty"`
+	Paused    bool               `json:"paused,omitempty"`
+
 	Created struct {
 		Min *int `json:"min"`
 		Max *int `json:"max"`
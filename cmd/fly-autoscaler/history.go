@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	fashttp "github.com/superfly/fly-autoscaler/http"
+)
+
+// HistoryCommand represents a command to list recent scaling decisions from a
+// running autoscaler's audit log.
+type HistoryCommand struct {
+	Client *fashttp.Client
+	Filter fas.AuditFilter
+
+	// If true, events are printed as JSON instead of a table.
+	JSON bool
+}
+
+func NewHistoryCommand() *HistoryCommand {
+	return &HistoryCommand{}
+}
+
+func (c *HistoryCommand) Run(ctx context.Context, args []string) (err error) {
+	if err := c.parseFlags(ctx, args); err != nil {
+		return err
+	}
+
+	events, err := c.Client.History(ctx, c.Filter)
+	if err != nil {
+		return fmt.Errorf("cannot fetch history: %w", err)
+	}
+
+	if c.JSON {
+		buf, err := json.MarshalIndent(events, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(buf))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TIMESTAMP\tAPP\tACTION\tN\tMACHINES\tERROR")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			e.Timestamp.Format(time.RFC3339),
+			e.App,
+			e.Action,
+			e.N,
+			strings.Join(e.MachineIDs, ","),
+			e.Error,
+		)
+	}
+	return w.Flush()
+}
+
+func (c *HistoryCommand) parseFlags(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("fly-autoscaler-history", flag.ContinueOnError)
+	u := registerURLFlag(fs)
+	fs.StringVar(&c.Filter.App, "app", "", "filter by app name, may contain wildcards")
+	fs.StringVar(&c.Filter.Action, "action", "", "filter by action (e.g. start, create)")
+	since := fs.String("since", "", "only events after an RFC 3339 time or a duration ago (e.g. 24h)")
+	until := fs.String("until", "", "only events before an RFC 3339 time or a duration ago")
+	fs.IntVar(&c.Filter.Limit, "limit", 100, "maximum number of most recent events")
+	fs.BoolVar(&c.JSON, "json", false, "print events as JSON")
+	fs.Usage = func() {
+		fmt.Println(`
+The history command lists recent scaling decisions recorded in the audit log of
+a running autoscaler. The audit log must be enabled on the server. If the
+FAS_AUTH_TOKEN environment variable is set, it is sent as a bearer token.
+
+Usage:
+
+	fly-autoscaler history [arguments]
+
+Arguments:
+`[1:])
+		fs.PrintDefaults()
+		fmt.Println("")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 {
+		return fmt.Errorf("too many arguments")
+	}
+
+	if c.Filter.Since, err = parseTimeFlag(*since); err != nil {
+		return fmt.Errorf("cannot parse -since: %q", *since)
+	}
+	if c.Filter.Until, err = parseTimeFlag(*until); err != nil {
+		return fmt.Errorf("cannot parse -until: %q", *until)
+	}
+
+	c.Client = newClient(*u)
+
+	return nil
+}
+
+func registerURLFlag(fs *flag.FlagSet) *string {
+	return fs.String("url", "", "autoscaler server URL, defaults to FAS_URL or "+fashttp.DefaultURL)
+}
+
+// newClient returns an API client for the server at u, falling back to the
+// FAS_URL environment variable. The FAS_AUTH_TOKEN variable is used as the
+// bearer token, if set.
+func newClient(u string) *fashttp.Client {
+	if u == "" {
+		if u = os.Getenv("FAS_URL"); u == "" {
+			u = fashttp.DefaultURL
+		}
+	}
+	client := fashttp.NewClient(u)
+	client.Token = os.Getenv("FAS_AUTH_TOKEN")
+	return client
+}
+
+// parseTimeFlag parses s as an RFC 3339 time or as a duration before now.
+// Returns the zero time if s is blank.
+func parseTimeFlag(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	} else if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -7,19 +7,34 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
+	_ "time/tzdata" // embed time zones for expression time functions
 
 	fas "github.com/superfly/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/bolt"
+	"github.com/superfly/fly-autoscaler/filelock"
+	fashttp "github.com/superfly/fly-autoscaler/http"
+	"github.com/superfly/fly-autoscaler/notify"
+	"github.com/superfly/fly-autoscaler/postgres"
 	fasprom "github.com/superfly/fly-autoscaler/prometheus"
+	"github.com/superfly/fly-autoscaler/redis"
 	"github.com/superfly/fly-autoscaler/temporal"
 	fly "github.com/superfly/fly-go"
 	"github.com/superfly/fly-go/flaps"
 	"github.com/superfly/fly-go/tokens"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"gopkg.in/yaml.v3"
 )
 
@@ -54,6 +69,18 @@ func run(ctx context.Context, args []string) error {
 	case "eval":
 		return NewEvalCommand().Run(ctx, args)
 
+	case "history":
+		return NewHistoryCommand().Run(ctx, args)
+
+	case "override":
+		return NewOverrideCommand().Run(ctx, args)
+
+	case "pause":
+		return NewPauseCommand().Run(ctx, args)
+
+	case "resume":
+		return NewResumeCommand().Run(ctx, args)
+
 	case "serve":
 		cmd := NewServeCommand()
 		if err := cmd.Run(ctx, args); err != nil {
@@ -105,6 +132,10 @@ Usage:
 The commands are:
 
 	eval         collects metrics once and evaluates server count
+	history      lists recent scaling decisions from the audit log
+	override     fixes an app's machine counts for a period of time
+	pause        pauses scaling of an app for a period of time
+	resume       removes a pause or override from an app
 	serve        runs the autoscaler server process
 	version      prints the version
 `[1:])
@@ -128,11 +159,38 @@ type Config struct {
 	MaxStartedMachineN     string        `yaml:"max-started-machine-count"`
 	Concurrency            int           `yaml:"concurrency"`
 	Interval               time.Duration `yaml:"interval"`
+	Jitter                 time.Duration `yaml:"jitter"`
 	Timeout                time.Duration `yaml:"timeout"`
+	Cooldown               time.Duration `yaml:"cooldown"`
 	AppListRefreshInterval time.Duration `yaml:"app-list-refresh-interval"`
 	APIToken               string        `yaml:"api-token"`
+	APIRateLimit           float64       `yaml:"api-rate-limit"`
+	APIRateBurst           int           `yaml:"api-rate-burst"`
+	AuthToken              string        `yaml:"auth-token"`
+	HTTPAddr               string        `yaml:"http-addr"`
 	Verbose                bool          `yaml:"verbose"`
 
+	CircuitBreakerThreshold  int           `yaml:"circuit-breaker-threshold"`
+	CircuitBreakerBackoff    time.Duration `yaml:"circuit-breaker-backoff"`
+	CircuitBreakerMaxBackoff time.Duration `yaml:"circuit-breaker-max-backoff"`
+
+	Guardrails      []*GuardrailConfig `yaml:"guardrails"`
+	GuardrailStrict bool               `yaml:"guardrail-strict"`
+
+	CreatedMachinePolicy *PolicyConfig `yaml:"created-machine-policy"`
+	StartedMachinePolicy *PolicyConfig `yaml:"started-machine-policy"`
+
+	Variables        []*VariableConfig        `yaml:"variables"`
+	Forecasts        []*ForecastConfig        `yaml:"forecasts"`
+	Schedules        []*ScheduleConfig        `yaml:"schedules"`
+	AppIntervals     []*AppIntervalConfig     `yaml:"app-intervals"`
+	Store            *StoreConfig             `yaml:"store"`
+	LeaderElection   *LeaderElectionConfig    `yaml:"leader-election"`
+	Sharding         *ShardingConfig          `yaml:"sharding"`
+	AppMetrics       *AppMetricsConfig        `yaml:"app-metrics"`
+	Tracing          *TracingConfig           `yaml:"tracing"`
+	AuditLog         *AuditLogConfig          `yaml:"audit-log"`
+	Notifications    *NotificationsConfig     `yaml:"notifications"`
 	MetricCollectors []*MetricCollectorConfig `yaml:"metric-collectors"`
 }
 
@@ -143,6 +201,11 @@ func NewConfig() *Config {
 		Timeout:                fas.DefaultReconcileTimeout,
 		AppListRefreshInterval: fas.DefaultAppListRefreshInterval,
 		ProcessGroup:           fas.DefaultProcessGroup,
+		HTTPAddr:               fashttp.DefaultAddr,
+
+		CircuitBreakerThreshold:  fas.DefaultCircuitBreakerThreshold,
+		CircuitBreakerBackoff:    fas.DefaultCircuitBreakerBackoff,
+		CircuitBreakerMaxBackoff: fas.DefaultCircuitBreakerMaxBackoff,
 	}
 }
 
@@ -158,6 +221,11 @@ func NewConfigFromEnv() (_ *Config, err error) {
 	c.MinStartedMachineN = os.Getenv("FAS_MIN_STARTED_MACHINE_COUNT")
 	c.MaxStartedMachineN = os.Getenv("FAS_MAX_STARTED_MACHINE_COUNT")
 	c.APIToken = os.Getenv("FAS_API_TOKEN")
+	c.AuthToken = os.Getenv("FAS_AUTH_TOKEN")
+
+	if s := os.Getenv("FAS_HTTP_ADDR"); s != "" {
+		c.HTTPAddr = s
+	}
 
 	if s := os.Getenv("FAS_PROCESS_GROUP"); s != "" {
 		c.ProcessGroup = s
@@ -182,17 +250,71 @@ func NewConfigFromEnv() (_ *Config, err error) {
 			return nil, fmt.Errorf("cannot parse FAS_INTERVAL as duration: %q", s)
 		}
 	}
+	if s := os.Getenv("FAS_JITTER"); s != "" {
+		if c.Jitter, err = time.ParseDuration(s); err != nil {
+			return nil, fmt.Errorf("cannot parse FAS_JITTER as duration: %q", s)
+		}
+	}
 	if s := os.Getenv("FAS_TIMEOUT"); s != "" {
 		if c.Timeout, err = time.ParseDuration(s); err != nil {
 			return nil, fmt.Errorf("cannot parse FAS_TIMEOUT as duration: %q", s)
 		}
 	}
+	if s := os.Getenv("FAS_COOLDOWN"); s != "" {
+		if c.Cooldown, err = time.ParseDuration(s); err != nil {
+			return nil, fmt.Errorf("cannot parse FAS_COOLDOWN as duration: %q", s)
+		}
+	}
 	if s := os.Getenv("FAS_APP_LIST_REFRESH_INTERVAL"); s != "" {
 		if c.AppListRefreshInterval, err = time.ParseDuration(s); err != nil {
 			return nil, fmt.Errorf("cannot parse FAS_APP_LIST_REFRESH_INTERVAL as duration: %q", s)
 		}
 	}
 
+	if s := os.Getenv("FAS_API_RATE_LIMIT"); s != "" {
+		if c.APIRateLimit, err = strconv.ParseFloat(s, 64); err != nil {
+			return nil, fmt.Errorf("cannot parse FAS_API_RATE_LIMIT as number: %q", s)
+		}
+	}
+	if s := os.Getenv("FAS_API_RATE_BURST"); s != "" {
+		if c.APIRateBurst, err = strconv.Atoi(s); err != nil {
+			return nil, fmt.Errorf("cannot parse FAS_API_RATE_BURST as integer: %q", s)
+		}
+	}
+	if s := os.Getenv("FAS_CIRCUIT_BREAKER_THRESHOLD"); s != "" {
+		if c.CircuitBreakerThreshold, err = strconv.Atoi(s); err != nil {
+			return nil, fmt.Errorf("cannot parse FAS_CIRCUIT_BREAKER_THRESHOLD as integer: %q", s)
+		}
+	}
+
+	if path := os.Getenv("FAS_STORE_PATH"); path != "" {
+		c.Store = &StoreConfig{
+			Type: "bolt",
+			Path: path,
+		}
+	}
+
+	if typ := os.Getenv("FAS_LEADER_ELECTION_TYPE"); typ != "" {
+		c.LeaderElection = &LeaderElectionConfig{
+			Type: typ,
+			URL:  os.Getenv("FAS_LEADER_ELECTION_URL"),
+			Key:  os.Getenv("FAS_LEADER_ELECTION_KEY"),
+			Path: os.Getenv("FAS_LEADER_ELECTION_PATH"),
+		}
+	}
+
+	if s := os.Getenv("FAS_SHARD_COUNT"); s != "" {
+		c.Sharding = &ShardingConfig{Type: "static"}
+		if c.Sharding.Count, err = strconv.Atoi(s); err != nil {
+			return nil, fmt.Errorf("cannot parse FAS_SHARD_COUNT as integer: %q", s)
+		}
+		if s := os.Getenv("FAS_SHARD_INDEX"); s != "" {
+			if c.Sharding.Index, err = strconv.Atoi(s); err != nil {
+				return nil, fmt.Errorf("cannot parse FAS_SHARD_INDEX as integer: %q", s)
+			}
+		}
+	}
+
 	if addr := os.Getenv("FAS_PROMETHEUS_ADDRESS"); addr != "" {
 		c.MetricCollectors = append(c.MetricCollectors, &MetricCollectorConfig{
 			Type:       "prometheus",
@@ -269,9 +391,26 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("app name required")
 	}
 
-	if !c.IsCreatedMachineCountDefined() && !c.IsStartedMachineCountDefined() {
+	if !c.IsCreatedMachineCountDefined() && !c.IsStartedMachineCountDefined() &&
+		c.CreatedMachinePolicy == nil && c.StartedMachinePolicy == nil {
 		return fmt.Errorf("must define either created machine count or started machine count")
 	}
+	if c.CreatedMachinePolicy != nil {
+		if c.IsCreatedMachineCountDefined() {
+			return fmt.Errorf("cannot define created machine count and created machine policy")
+		}
+		if err := c.CreatedMachinePolicy.Validate(); err != nil {
+			return fmt.Errorf("created-machine-policy: %w", err)
+		}
+	}
+	if c.StartedMachinePolicy != nil {
+		if c.IsStartedMachineCountDefined() {
+			return fmt.Errorf("cannot define started machine count and started machine policy")
+		}
+		if err := c.StartedMachinePolicy.Validate(); err != nil {
+			return fmt.Errorf("started-machine-policy: %w", err)
+		}
+	}
 	if err := c.validateCreatedMachineCount(); err != nil {
 		return err
 	}
@@ -279,18 +418,249 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if c.APIRateLimit < 0 {
+		return fmt.Errorf("api rate limit cannot be negative")
+	} else if c.APIRateBurst < 0 {
+		return fmt.Errorf("api rate burst cannot be negative")
+	}
+	if c.CircuitBreakerThreshold < 0 {
+		return fmt.Errorf("circuit breaker threshold cannot be negative")
+	} else if c.CircuitBreakerThreshold > 0 && c.CircuitBreakerBackoff <= 0 {
+		return fmt.Errorf("circuit breaker backoff required if threshold is set")
+	}
+
+	if _, _, err := net.SplitHostPort(c.HTTPAddr); c.HTTPAddr != "" && err != nil {
+		return fmt.Errorf("invalid http addr: %q", c.HTTPAddr)
+	}
+
 	if !slices.Contains([]string{fly.MachineStateStarted, fly.MachineStateStopped}, c.InitialMachineState) {
 		return fmt.Errorf("initial machine state must be either 'started' or 'stopped'")
 	}
 
+	for i, intervalConfig := range c.AppIntervals {
+		if err := intervalConfig.Validate(); err != nil {
+			return fmt.Errorf("app-intervals[%d]: %w", i, err)
+		}
+	}
+
+	if c.Store != nil {
+		if err := c.Store.Validate(); err != nil {
+			return fmt.Errorf("store: %w", err)
+		}
+	}
+
+	if c.LeaderElection != nil {
+		if err := c.LeaderElection.Validate(); err != nil {
+			return fmt.Errorf("leader-election: %w", err)
+		}
+	}
+
+	if c.Sharding != nil {
+		if c.LeaderElection != nil {
+			return fmt.Errorf("cannot enable both leader election and sharding")
+		}
+		if !strings.Contains(c.AppName, "*") {
+			return fmt.Errorf("sharding requires an app name with a wildcard")
+		}
+		if err := c.Sharding.Validate(); err != nil {
+			return fmt.Errorf("sharding: %w", err)
+		}
+	}
+
+	if c.AppMetrics != nil {
+		if err := c.AppMetrics.Validate(); err != nil {
+			return fmt.Errorf("app-metrics: %w", err)
+		}
+	}
+
+	if c.Tracing != nil {
+		if err := c.Tracing.Validate(); err != nil {
+			return fmt.Errorf("tracing: %w", err)
+		}
+	}
+
+	if c.AuditLog != nil {
+		if err := c.AuditLog.Validate(); err != nil {
+			return fmt.Errorf("audit-log: %w", err)
+		}
+		if c.AuditLog.Type == "store" && (c.Store == nil || c.Store.Type != "bolt") {
+			return fmt.Errorf("audit-log: store type requires a bolt store")
+		}
+	}
+
+	if c.Notifications != nil {
+		if err := c.Notifications.Validate(); err != nil {
+			return fmt.Errorf("notifications: %w", err)
+		}
+	}
+
 	for i, collectorConfig := range c.MetricCollectors {
 		if err := collectorConfig.Validate(); err != nil {
 			return fmt.Errorf("metric-collectors[%d]: %w", i, err)
 		}
 	}
+
+	names := c.MetricNames()
+	for i, forecastConfig := range c.Forecasts {
+		if err := forecastConfig.Validate(); err != nil {
+			return fmt.Errorf("forecasts[%d]: %w", i, err)
+		}
+		if !slices.Contains(names, forecastConfig.Metric) {
+			return fmt.Errorf("forecasts[%d]: unknown metric %q (available metrics: %s)", i, forecastConfig.Metric, strings.Join(names, ", "))
+		}
+	}
+
+	for i, guardrailConfig := range c.Guardrails {
+		if err := guardrailConfig.Validate(); err != nil {
+			return fmt.Errorf("guardrails[%d]: %w", i, err)
+		}
+	}
+
+	for i, scheduleConfig := range c.Schedules {
+		if err := scheduleConfig.Validate(); err != nil {
+			return fmt.Errorf("schedules[%d]: %w", i, err)
+		}
+	}
+
+	if err := c.validateExprs(); err != nil {
+		return err
+	}
 	return nil
 }
 
+// validateExprs compiles each variable & machine count expression against the
+// metric names provided by the configured collectors.
+func (c *Config) validateExprs() error {
+	names := c.MetricNames()
+	for i, variableConfig := range c.Variables {
+		if err := variableConfig.Validate(); err != nil {
+			return fmt.Errorf("variables[%d]: %w", i, err)
+		}
+		if slices.Contains(names, variableConfig.Name) {
+			return fmt.Errorf("variables[%d]: variable %q conflicts with metric name", i, variableConfig.Name)
+		}
+	}
+
+	// Compile variables in evaluation order so each can only reference
+	// metrics & the variables evaluated before it.
+	variables, err := fas.SortVariables(c.GetVariables())
+	if err != nil {
+		return fmt.Errorf("variables: %w", err)
+	}
+	for _, v := range variables {
+		e, err := fas.CompileExpr(v.Expr, names)
+		if err != nil {
+			return fmt.Errorf("variable %q: %w", v.Name, err)
+		} else if err := c.validateExprWindow(e); err != nil {
+			return fmt.Errorf("variable %q: %w", v.Name, err)
+		}
+		names = append(names, v.Name)
+	}
+
+	for _, e := range []struct {
+		key string
+		src string
+	}{
+		{"created-machine-count", c.CreatedMachineN},
+		{"min-created-machine-count", c.MinCreatedMachineN},
+		{"max-created-machine-count", c.MaxCreatedMachineN},
+		{"started-machine-count", c.StartedMachineN},
+		{"min-started-machine-count", c.MinStartedMachineN},
+		{"max-started-machine-count", c.MaxStartedMachineN},
+	} {
+		if e.src == "" {
+			continue
+		}
+		expr, err := fas.CompileExpr(e.src, names)
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.key, err)
+		} else if err := c.validateExprWindow(expr); err != nil {
+			return fmt.Errorf("%s: %w", e.key, err)
+		}
+	}
+
+	for _, p := range []struct {
+		key    string
+		config *PolicyConfig
+	}{
+		{"created-machine-policy", c.CreatedMachinePolicy},
+		{"started-machine-policy", c.StartedMachinePolicy},
+	} {
+		if p.config == nil {
+			continue
+		}
+		if !slices.Contains(names, p.config.Metric) {
+			return fmt.Errorf("%s: unknown metric %q (available metrics: %s)", p.key, p.config.Metric, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// validateExprWindow returns an error if a history function window in e is
+// longer than the metric history retained at the shortest reconcile interval.
+// Samples beyond the history would otherwise be silently ignored.
+func (c *Config) validateExprWindow(e *fas.Expr) error {
+	interval := c.Interval
+	for _, intervalConfig := range c.AppIntervals {
+		if intervalConfig.Interval > 0 {
+			interval = min(interval, intervalConfig.Interval)
+		}
+	}
+
+	if d := fas.MetricHistoryDuration(interval); e.MaxWindow() > d {
+		return fmt.Errorf("window of %s exceeds the %s of metric history retained at a %s interval", e.MaxWindow(), d, interval)
+	}
+	return nil
+}
+
+// GetVariables returns the configured variables in the order they are defined.
+func (c *Config) GetVariables() []fas.Variable {
+	var a []fas.Variable
+	for _, variableConfig := range c.Variables {
+		a = append(a, fas.Variable{
+			Name: variableConfig.Name,
+			Expr: variableConfig.Expr,
+		})
+	}
+	return a
+}
+
+// NewForecasters returns a forecaster for each configured forecast.
+func (c *Config) NewForecasters() []*fas.Forecaster {
+	var a []*fas.Forecaster
+	for _, forecastConfig := range c.Forecasts {
+		a = append(a, forecastConfig.NewForecaster())
+	}
+	return a
+}
+
+// GetGuardrails returns the configured guardrails.
+func (c *Config) GetGuardrails() []fas.Guardrail {
+	var a []fas.Guardrail
+	for _, guardrailConfig := range c.Guardrails {
+		a = append(a, guardrailConfig.Guardrail())
+	}
+	return a
+}
+
+// GetSchedules returns the configured schedules in the order they are defined.
+func (c *Config) GetSchedules() []fas.Schedule {
+	var a []fas.Schedule
+	for _, scheduleConfig := range c.Schedules {
+		a = append(a, scheduleConfig.Schedule())
+	}
+	return a
+}
+
+// MetricNames returns the names of metrics provided by all metric collectors.
+func (c *Config) MetricNames() []string {
+	var a []string
+	for _, collectorConfig := range c.MetricCollectors {
+		a = append(a, collectorConfig.MetricName)
+	}
+	return a
+}
+
 func (c *Config) validateCreatedMachineCount() error {
 	if !c.IsCreatedMachineCountDefined() {
 		return nil
@@ -325,26 +695,64 @@ func (c *Config) validateStartedMachineCount() error {
 	return nil
 }
 
-func (c *Config) NewFlyClient(ctx context.Context) (*fly.Client, error) {
+// AppReconcileIntervals returns the per-app interval overrides.
+func (c *Config) AppReconcileIntervals() []fas.AppReconcileInterval {
+	var a []fas.AppReconcileInterval
+	for _, intervalConfig := range c.AppIntervals {
+		a = append(a, fas.AppReconcileInterval{
+			Pattern:  intervalConfig.App,
+			Interval: intervalConfig.Interval,
+		})
+	}
+	return a
+}
+
+// NewRateLimiter returns a limiter shared by all API calls. Returns nil if
+// API rate limiting is disabled.
+func (c *Config) NewRateLimiter() *fas.RateLimiter {
+	if c.APIRateLimit <= 0 {
+		return nil
+	}
+
+	burst := c.APIRateBurst
+	if burst == 0 {
+		burst = int(math.Ceil(c.APIRateLimit))
+	}
+	return fas.NewRateLimiter(c.APIRateLimit, burst)
+}
+
+// NewFlyClient returns a client for the Fly GraphQL API. If limiter is not nil,
+// then rate limit responses from the API pause the limiter.
+func (c *Config) NewFlyClient(ctx context.Context, limiter *fas.RateLimiter) (*fly.Client, error) {
 	if c.APIToken == "" {
 		return nil, fmt.Errorf("api token required")
 	}
 
-	return fly.NewClientFromOptions(fly.ClientOptions{
-		Tokens: tokens.Parse(c.APIToken),
-	}), nil
+	opts := fly.ClientOptions{Tokens: tokens.Parse(c.APIToken)}
+	if limiter != nil {
+		opts.Transport = &fly.Transport{UnderlyingTransport: limiter.Transport(nil)}
+	}
+	return fly.NewClientFromOptions(opts), nil
 }
 
-func (c *Config) NewFlapsClient() (fas.NewFlapsClientFunc, error) {
+// NewFlapsClient returns a constructor for Machines API clients. If limiter is
+// not nil, then rate limit responses from the API pause the limiter.
+func (c *Config) NewFlapsClient(limiter *fas.RateLimiter) (fas.NewFlapsClientFunc, error) {
 	if c.APIToken == "" {
 		return nil, fmt.Errorf("api token required")
 	}
 	tok := tokens.Parse(c.APIToken)
 
+	var transport http.RoundTripper
+	if limiter != nil {
+		transport = limiter.Transport(nil)
+	}
+
 	return func(ctx context.Context, appName string) (fas.FlapsClient, error) {
 		return flaps.NewWithOptions(ctx, flaps.NewClientOpts{
-			AppName: appName,
-			Tokens:  tok,
+			AppName:   appName,
+			Tokens:    tok,
+			Transport: transport,
 		})
 	}, nil
 }
@@ -361,6 +769,15 @@ func (c *Config) NewMetricCollectors() ([]fas.MetricCollector, error) {
 	return a, nil
 }
 
+// NewStore returns a store based on the store config. Returns an in-memory
+// store if no store is configured. The caller is responsible for closing it.
+func (c *Config) NewStore() (fas.Store, error) {
+	if c.Store == nil {
+		return fas.NewMemoryStore(), nil
+	}
+	return c.Store.NewStore()
+}
+
 func ParseConfig(r io.Reader, config *Config) error {
 	data, err := io.ReadAll(r)
 	if err != nil {
@@ -383,6 +800,666 @@ func ParseConfigFromFile(filename string, config *Config) error {
 	return ParseConfig(f, config)
 }
 
+type PolicyConfig struct {
+	Type string `yaml:"type"`
+
+	// Target tracking
+	Metric        string        `yaml:"metric"`
+	Target        float64       `yaml:"target"`
+	Tolerance     float64       `yaml:"tolerance"`
+	Min           int           `yaml:"min"`
+	Max           int           `yaml:"max"`
+	Stabilization time.Duration `yaml:"stabilization"`
+
+	// Predictive
+	Horizon time.Duration `yaml:"horizon"`
+
+	// PID
+	Setpoint    float64 `yaml:"setpoint"`
+	Kp          float64 `yaml:"kp"`
+	Ki          float64 `yaml:"ki"`
+	Kd          float64 `yaml:"kd"`
+	WindupLimit float64 `yaml:"windup-limit"`
+}
+
+func (c *PolicyConfig) Validate() error {
+	switch typ := c.Type; typ {
+	case fas.PolicyTypeTargetTracking:
+		return c.newTargetTrackingPolicy().Validate()
+	case fas.PolicyTypePID:
+		return c.newPIDPolicy().Validate()
+	case fas.PolicyTypePredictive:
+		return c.newPredictivePolicy().Validate()
+	case "":
+		return fmt.Errorf("policy type required")
+	default:
+		return fmt.Errorf("invalid policy type: %q", typ)
+	}
+}
+
+// NewPolicy returns a policy for the config. Returns nil if c is nil.
+func (c *PolicyConfig) NewPolicy() (fas.Policy, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	switch typ := c.Type; typ {
+	case fas.PolicyTypeTargetTracking:
+		return c.newTargetTrackingPolicy(), nil
+	case fas.PolicyTypePID:
+		return c.newPIDPolicy(), nil
+	case fas.PolicyTypePredictive:
+		return c.newPredictivePolicy(), nil
+	default:
+		return nil, fmt.Errorf("invalid policy type: %q", typ)
+	}
+}
+
+func (c *PolicyConfig) newTargetTrackingPolicy() *fas.TargetTrackingPolicy {
+	return &fas.TargetTrackingPolicy{
+		Metric:              c.Metric,
+		Target:              c.Target,
+		Tolerance:           c.Tolerance,
+		MinN:                c.Min,
+		MaxN:                c.Max,
+		StabilizationWindow: c.Stabilization,
+	}
+}
+
+func (c *PolicyConfig) newPIDPolicy() *fas.PIDPolicy {
+	return &fas.PIDPolicy{
+		Metric:      c.Metric,
+		Setpoint:    c.Setpoint,
+		Kp:          c.Kp,
+		Ki:          c.Ki,
+		Kd:          c.Kd,
+		WindupLimit: c.WindupLimit,
+		MinN:        c.Min,
+		MaxN:        c.Max,
+	}
+}
+
+func (c *PolicyConfig) newPredictivePolicy() *fas.PredictivePolicy {
+	return &fas.PredictivePolicy{
+		Metric:  c.Metric,
+		Horizon: c.Horizon,
+		Target:  c.Target,
+		MinN:    c.Min,
+		MaxN:    c.Max,
+	}
+}
+
+type ForecastConfig struct {
+	Metric   string        `yaml:"metric"`
+	Alpha    float64       `yaml:"alpha"`
+	Beta     float64       `yaml:"beta"`
+	Gamma    float64       `yaml:"gamma"`
+	Season   time.Duration `yaml:"season"`
+	Lookback time.Duration `yaml:"lookback"`
+	Step     time.Duration `yaml:"step"`
+	Refresh  time.Duration `yaml:"refresh"`
+}
+
+func (c *ForecastConfig) Validate() error {
+	return c.NewForecaster().Validate()
+}
+
+func (c *ForecastConfig) NewForecaster() *fas.Forecaster {
+	return &fas.Forecaster{
+		Metric:   c.Metric,
+		Alpha:    c.Alpha,
+		Beta:     c.Beta,
+		Gamma:    c.Gamma,
+		Season:   c.Season,
+		Lookback: c.Lookback,
+		Step:     c.Step,
+		Refresh:  c.Refresh,
+	}
+}
+
+type GuardrailConfig struct {
+	App         string `yaml:"app"`
+	Region      string `yaml:"region"`
+	MinMachines int    `yaml:"min-machines"`
+	MaxMachines int    `yaml:"max-machines"`
+}
+
+func (c *GuardrailConfig) Validate() error {
+	g := c.Guardrail()
+	return g.Validate()
+}
+
+func (c *GuardrailConfig) Guardrail() fas.Guardrail {
+	return fas.Guardrail{
+		App:    c.App,
+		Region: c.Region,
+		MinN:   c.MinMachines,
+		MaxN:   c.MaxMachines,
+	}
+}
+
+type ScheduleConfig struct {
+	Name        string        `yaml:"name"`
+	App         string        `yaml:"app"`
+	Cron        string        `yaml:"cron"`
+	Duration    time.Duration `yaml:"duration"`
+	Timezone    string        `yaml:"timezone"`
+	From        time.Time     `yaml:"from"`
+	Until       time.Time     `yaml:"until"`
+	MinCreatedN *int          `yaml:"min-created-machine-count"`
+	MaxCreatedN *int          `yaml:"max-created-machine-count"`
+	MinStartedN *int          `yaml:"min-started-machine-count"`
+	MaxStartedN *int          `yaml:"max-started-machine-count"`
+	Pause       bool          `yaml:"pause"`
+}
+
+func (c *ScheduleConfig) Validate() error {
+	s := c.Schedule()
+	return s.Validate()
+}
+
+func (c *ScheduleConfig) Schedule() fas.Schedule {
+	return fas.Schedule{
+		Name:        c.Name,
+		App:         c.App,
+		Cron:        c.Cron,
+		Duration:    c.Duration,
+		Timezone:    c.Timezone,
+		From:        c.From,
+		Until:       c.Until,
+		MinCreatedN: c.MinCreatedN,
+		MaxCreatedN: c.MaxCreatedN,
+		MinStartedN: c.MinStartedN,
+		MaxStartedN: c.MaxStartedN,
+		Pause:       c.Pause,
+	}
+}
+
+type VariableConfig struct {
+	Name string `yaml:"name"`
+	Expr string `yaml:"expr"`
+}
+
+func (c *VariableConfig) Validate() error {
+	v := fas.Variable{Name: c.Name, Expr: c.Expr}
+	return v.Validate()
+}
+
+type AppIntervalConfig struct {
+	App      string        `yaml:"app"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+func (c *AppIntervalConfig) Validate() error {
+	if c.App == "" {
+		return fmt.Errorf("app required")
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than zero")
+	}
+	return nil
+}
+
+// AppMetricsConfig enables labeled Prometheus metrics for each app.
+type AppMetricsConfig struct {
+	Apps    []string `yaml:"apps"`     // wildcard patterns
+	MaxApps int      `yaml:"max-apps"` // zero means no limit
+	Regions bool     `yaml:"regions"`
+}
+
+func (c *AppMetricsConfig) Validate() error {
+	if c.MaxApps < 0 {
+		return fmt.Errorf("max apps cannot be negative")
+	}
+	for _, app := range c.Apps {
+		if _, err := regexp.Compile(fas.FormatWildcardAsRegexp(app)); err != nil {
+			return fmt.Errorf("invalid app pattern %q", app)
+		}
+	}
+	return nil
+}
+
+// DefaultTracingServiceName is the service name reported on exported spans.
+const DefaultTracingServiceName = "fly-autoscaler"
+
+// TracingConfig enables exporting OpenTelemetry traces of each reconciliation
+// to an OTLP/HTTP endpoint.
+type TracingConfig struct {
+	Endpoint    string            `yaml:"endpoint"` // host:port
+	URLPath     string            `yaml:"url-path"`
+	Insecure    bool              `yaml:"insecure"`
+	Headers     map[string]string `yaml:"headers"`
+	ServiceName string            `yaml:"service-name"`
+	SampleRate  *float64          `yaml:"sample-rate"`
+}
+
+func (c *TracingConfig) Validate() error {
+	if c.Endpoint == "" {
+		return fmt.Errorf("endpoint required")
+	}
+	if c.SampleRate != nil && (*c.SampleRate < 0 || *c.SampleRate > 1) {
+		return fmt.Errorf("sample rate must be between 0 and 1")
+	}
+	return nil
+}
+
+// NewTracerProvider returns a tracer provider that exports spans over OTLP.
+// The caller is responsible for shutting it down.
+func (c *TracingConfig) NewTracerProvider(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(c.Endpoint)}
+	if c.URLPath != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(c.URLPath))
+	}
+	if c.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if len(c.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(c.Headers))
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := c.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultTracingServiceName
+	}
+
+	sampleRate := 1.0
+	if c.SampleRate != nil {
+		sampleRate = *c.SampleRate
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRate))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+			attribute.String("service.version", Version),
+		)),
+	), nil
+}
+
+// AuditLogConfig enables recording each scaling decision to either a rotating
+// JSONL file or to the bolt state store.
+type AuditLogConfig struct {
+	Type           string        `yaml:"type"`
+	Path           string        `yaml:"path"`      // File
+	MaxSize        int64         `yaml:"max-size"`  // File, in bytes
+	MaxFiles       int           `yaml:"max-files"` // File
+	Retention      time.Duration `yaml:"retention"` // Store
+	IncludeNoScale bool          `yaml:"include-no-scale"`
+}
+
+func (c *AuditLogConfig) Validate() error {
+	switch typ := c.Type; typ {
+	case "file":
+		if c.Path == "" {
+			return fmt.Errorf("file path required")
+		} else if c.MaxSize < 0 {
+			return fmt.Errorf("max size cannot be negative")
+		} else if c.MaxFiles < 0 {
+			return fmt.Errorf("max files cannot be negative")
+		}
+		return nil
+	case "store":
+		if c.Retention < 0 {
+			return fmt.Errorf("retention cannot be negative")
+		}
+		return nil
+	case "":
+		return fmt.Errorf("type required")
+	default:
+		return fmt.Errorf("invalid type: %q", typ)
+	}
+}
+
+// NewAuditLog returns an audit log of the configured type. The "store" type
+// writes events to store, which must implement fas.AuditLog.
+func (c *AuditLogConfig) NewAuditLog(store fas.Store) (fas.AuditLog, error) {
+	switch typ := c.Type; typ {
+	case "file":
+		l := fas.NewFileAuditLog(c.Path)
+		if c.MaxSize > 0 {
+			l.MaxSize = c.MaxSize
+		}
+		if c.MaxFiles > 0 {
+			l.MaxFiles = c.MaxFiles
+		}
+		if err := l.Open(); err != nil {
+			return nil, err
+		}
+		return l, nil
+	case "store":
+		l, ok := store.(fas.AuditLog)
+		if !ok {
+			return nil, fmt.Errorf("store does not support audit events")
+		}
+		if s, ok := store.(*bolt.Store); ok && c.Retention > 0 {
+			s.AuditRetention = c.Retention
+		}
+		return l, nil
+	default:
+		return nil, fmt.Errorf("invalid type: %q", typ)
+	}
+}
+
+// NotificationsConfig enables sending notifications for notable scaling events
+// to one or more sinks.
+type NotificationsConfig struct {
+	MinMachines         int                       `yaml:"min-machines"`
+	ConsecutiveFailures int                       `yaml:"consecutive-failures"`
+	MaxBound            bool                      `yaml:"max-bound"`
+	BatchInterval       time.Duration             `yaml:"batch-interval"`
+	MaxBatchSize        int                       `yaml:"max-batch-size"`
+	RateLimit           time.Duration             `yaml:"rate-limit"`
+	Sinks               []*NotificationSinkConfig `yaml:"sinks"`
+}
+
+func (c *NotificationsConfig) Validate() error {
+	if c.MinMachines < 0 {
+		return fmt.Errorf("min machines cannot be negative")
+	} else if c.ConsecutiveFailures < 0 {
+		return fmt.Errorf("consecutive failures cannot be negative")
+	} else if c.MinMachines == 0 && c.ConsecutiveFailures == 0 && !c.MaxBound {
+		return fmt.Errorf("must set min machines, consecutive failures or max bound")
+	} else if c.BatchInterval < 0 {
+		return fmt.Errorf("batch interval cannot be negative")
+	} else if c.MaxBatchSize < 0 {
+		return fmt.Errorf("max batch size cannot be negative")
+	} else if c.RateLimit < 0 {
+		return fmt.Errorf("rate limit cannot be negative")
+	} else if len(c.Sinks) == 0 {
+		return fmt.Errorf("at least one sink required")
+	}
+
+	for i, sinkConfig := range c.Sinks {
+		if err := sinkConfig.Validate(); err != nil {
+			return fmt.Errorf("sinks[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// NewNotifier returns a notifier that sends to each configured sink. The
+// caller is responsible for opening & closing it.
+func (c *NotificationsConfig) NewNotifier() (*fas.Notifier, error) {
+	n := fas.NewNotifier()
+	n.MinMachines = c.MinMachines
+	n.ConsecutiveFailures = c.ConsecutiveFailures
+	n.MaxBound = c.MaxBound
+	if c.BatchInterval > 0 {
+		n.BatchInterval = c.BatchInterval
+	}
+	if c.MaxBatchSize > 0 {
+		n.MaxBatchSize = c.MaxBatchSize
+	}
+	if c.RateLimit > 0 {
+		n.RateLimit = c.RateLimit
+	}
+
+	for i, sinkConfig := range c.Sinks {
+		sink, err := sinkConfig.NewNotificationSink()
+		if err != nil {
+			return nil, fmt.Errorf("sinks[%d]: %w", i, err)
+		}
+
+		// Only send failures to PagerDuty by default as other types are never
+		// resolved & would leave incidents open.
+		types := sinkConfig.Types
+		if len(types) == 0 && sinkConfig.Type == "pagerduty" {
+			types = notify.DefaultPagerDutyTypes
+		}
+		n.Sinks = append(n.Sinks, fas.NotifierSink{Sink: sink, Types: types})
+	}
+	return n, nil
+}
+
+type NotificationSinkConfig struct {
+	Type       string            `yaml:"type"`
+	URL        string            `yaml:"url"`         // Webhook, Slack & PagerDuty
+	Headers    map[string]string `yaml:"headers"`     // Webhook
+	RoutingKey string            `yaml:"routing-key"` // PagerDuty
+	Severity   string            `yaml:"severity"`    // PagerDuty
+	Template   string            `yaml:"template"`
+	Types      []string          `yaml:"types"`
+}
+
+func (c *NotificationSinkConfig) Validate() error {
+	switch typ := c.Type; typ {
+	case "webhook", "slack":
+		if c.URL == "" {
+			return fmt.Errorf("%s url required", typ)
+		}
+	case "pagerduty":
+		if c.RoutingKey == "" {
+			return fmt.Errorf("pagerduty routing key required")
+		}
+		if c.Severity != "" && !slices.Contains([]string{"critical", "error", "warning", "info"}, c.Severity) {
+			return fmt.Errorf("invalid pagerduty severity: %q", c.Severity)
+		}
+	case "":
+		return fmt.Errorf("type required")
+	default:
+		return fmt.Errorf("invalid type: %q", typ)
+	}
+
+	for _, typ := range c.Types {
+		if !slices.Contains([]string{
+			fas.NotificationTypeScale,
+			fas.NotificationTypeFailure,
+			fas.NotificationTypeRecovered,
+			fas.NotificationTypeMaxBound,
+		}, typ) {
+			return fmt.Errorf("invalid notification type: %q", typ)
+		}
+	}
+
+	if _, err := notify.ParseTemplate(c.Template); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	return nil
+}
+
+func (c *NotificationSinkConfig) NewNotificationSink() (fas.NotificationSink, error) {
+	tmpl, err := notify.ParseTemplate(c.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typ := c.Type; typ {
+	case "webhook":
+		sink := notify.NewWebhookSink(c.URL)
+		for k, v := range c.Headers {
+			sink.Header.Set(k, v)
+		}
+		sink.Template = tmpl
+		return sink, nil
+	case "slack":
+		sink := notify.NewSlackSink(c.URL)
+		sink.Template = tmpl
+		return sink, nil
+	case "pagerduty":
+		sink := notify.NewPagerDutySink(c.RoutingKey)
+		if c.URL != "" {
+			sink.URL = c.URL
+		}
+		if c.Severity != "" {
+			sink.Severity = c.Severity
+		}
+		sink.Template = tmpl
+		return sink, nil
+	default:
+		return nil, fmt.Errorf("invalid type: %q", typ)
+	}
+}
+
+type StoreConfig struct {
+	Type string `yaml:"type"`
+	Path string `yaml:"path"` // Bolt
+}
+
+func (c *StoreConfig) Validate() error {
+	switch typ := c.Type; typ {
+	case "memory":
+		return nil
+	case "bolt":
+		if c.Path == "" {
+			return fmt.Errorf("bolt path required")
+		}
+		return nil
+	case "":
+		return fmt.Errorf("type required")
+	default:
+		return fmt.Errorf("invalid type: %q", typ)
+	}
+}
+
+func (c *StoreConfig) NewStore() (fas.Store, error) {
+	switch typ := c.Type; typ {
+	case "memory":
+		return fas.NewMemoryStore(), nil
+	case "bolt":
+		store := bolt.NewStore(c.Path)
+		if err := store.Open(); err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("invalid type: %q", typ)
+	}
+}
+
+type LeaderElectionConfig struct {
+	Type     string        `yaml:"type"`
+	Interval time.Duration `yaml:"interval"`
+	URL      string        `yaml:"url"`  // Postgres & Redis
+	Key      string        `yaml:"key"`  // Postgres & Redis
+	TTL      time.Duration `yaml:"ttl"`  // Redis
+	Path     string        `yaml:"path"` // File
+}
+
+func (c *LeaderElectionConfig) Validate() error {
+	switch typ := c.Type; typ {
+	case "postgres", "redis":
+		if c.URL == "" {
+			return fmt.Errorf("%s url required", typ)
+		}
+		return nil
+	case "file":
+		if c.Path == "" {
+			return fmt.Errorf("file path required")
+		}
+		return nil
+	case "":
+		return fmt.Errorf("type required")
+	default:
+		return fmt.Errorf("invalid type: %q", typ)
+	}
+}
+
+// NewLeaderElector returns a leader elector based on the config.
+// The caller is responsible for closing it, if it implements io.Closer.
+func (c *LeaderElectionConfig) NewLeaderElector() (fas.LeaderElector, error) {
+	switch typ := c.Type; typ {
+	case "postgres":
+		e := postgres.NewLeaderElector(c.URL)
+		if c.Key != "" {
+			e.Key = c.Key
+		}
+		return e, nil
+
+	case "redis":
+		e := redis.NewLeaderElector(c.URL)
+		e.ID = os.Getenv("FLY_MACHINE_ID")
+		if c.Key != "" {
+			e.Key = c.Key
+		}
+		if c.TTL > 0 {
+			e.TTL = c.TTL
+		}
+		if err := e.Open(); err != nil {
+			return nil, err
+		}
+		return e, nil
+
+	case "file":
+		return filelock.NewLeaderElector(c.Path), nil
+
+	default:
+		return nil, fmt.Errorf("invalid type: %q", typ)
+	}
+}
+
+type ShardingConfig struct {
+	Type  string        `yaml:"type"`
+	Index int           `yaml:"index"` // Static
+	Count int           `yaml:"count"` // Static
+	URL   string        `yaml:"url"`   // Redis
+	Key   string        `yaml:"key"`   // Redis
+	TTL   time.Duration `yaml:"ttl"`   // Redis
+}
+
+func (c *ShardingConfig) Validate() error {
+	switch typ := c.Type; typ {
+	case "static":
+		if c.Count < 1 {
+			return fmt.Errorf("shard count must be at least 1")
+		}
+		if c.Index < 0 || c.Index >= c.Count {
+			return fmt.Errorf("shard index must be between 0 and %d", c.Count-1)
+		}
+		return nil
+	case "redis":
+		if c.URL == "" {
+			return fmt.Errorf("redis url required")
+		}
+		return nil
+	case "":
+		return fmt.Errorf("type required")
+	default:
+		return fmt.Errorf("invalid type: %q", typ)
+	}
+}
+
+// InstanceID returns the identifier of this instance within the membership.
+func (c *ShardingConfig) InstanceID() (string, error) {
+	if c.Type == "static" {
+		return strconv.Itoa(c.Index), nil
+	}
+	if id := os.Getenv("FLY_MACHINE_ID"); id != "" {
+		return id, nil
+	}
+	return os.Hostname()
+}
+
+// NewMembership returns a membership based on the config.
+// The caller is responsible for closing it, if it implements io.Closer.
+func (c *ShardingConfig) NewMembership() (fas.Membership, error) {
+	switch typ := c.Type; typ {
+	case "static":
+		return fas.NewStaticMembership(c.Count), nil
+
+	case "redis":
+		m := redis.NewMembership(c.URL)
+		if c.Key != "" {
+			m.Key = c.Key
+		}
+		if c.TTL > 0 {
+			m.TTL = c.TTL
+		}
+		if err := m.Open(); err != nil {
+			return nil, err
+		}
+		return m, nil
+
+	default:
+		return nil, fmt.Errorf("invalid type: %q", typ)
+	}
+}
+
 type MetricCollectorConfig struct {
 	Type       string `yaml:"type"`
 	MetricName string `yaml:"metric-name"`
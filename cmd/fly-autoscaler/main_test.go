@@ -1,10 +1,13 @@
 package main_test
 
 import (
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	main "github.com/superfly/fly-autoscaler/cmd/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/notify"
 )
 
 func TestConfig_Parse(t *testing.T) {
@@ -31,6 +34,84 @@ func TestConfig_Parse(t *testing.T) {
 	if got, want := config.ProcessGroup, "app"; got != want {
 		t.Fatalf("ProcessGroup=%v, want %v", got, want)
 	}
+	if got, want := len(config.Guardrails), 3; got != want {
+		t.Fatalf("len(Guardrails)=%v, want %v", got, want)
+	}
+	if got, want := config.Guardrails[0].MaxMachines, 50; got != want {
+		t.Fatalf("Guardrails[0].MaxMachines=%v, want %v", got, want)
+	}
+	if got, want := config.Guardrails[2].Region, "iad"; got != want {
+		t.Fatalf("Guardrails[2].Region=%v, want %v", got, want)
+	}
+	if got, want := config.AppMetrics.MaxApps, 100; got != want {
+		t.Fatalf("AppMetrics.MaxApps=%v, want %v", got, want)
+	}
+	if got, want := config.HTTPAddr, ":9090"; got != want {
+		t.Fatalf("HTTPAddr=%v, want %v", got, want)
+	}
+	if got, want := config.AuditLog.Retention, 168*time.Hour; got != want {
+		t.Fatalf("AuditLog.Retention=%v, want %v", got, want)
+	}
+	if got, want := config.Notifications.ConsecutiveFailures, 3; got != want {
+		t.Fatalf("Notifications.ConsecutiveFailures=%v, want %v", got, want)
+	}
+	if got, want := config.Notifications.Sinks[1].Types, []string{"failure", "recovered"}; !slices.Equal(got, want) {
+		t.Fatalf("Notifications.Sinks[1].Types=%v, want %v", got, want)
+	}
+	if got, want := len(config.Schedules), 2; got != want {
+		t.Fatalf("len(Schedules)=%v, want %v", got, want)
+	}
+	if got, want := *config.Schedules[0].MinStartedN, 20; got != want {
+		t.Fatalf("Schedules[0].MinStartedN=%v, want %v", got, want)
+	}
+	if got, want := config.Schedules[0].Duration, 12*time.Hour; got != want {
+		t.Fatalf("Schedules[0].Duration=%v, want %v", got, want)
+	}
+	if got, want := config.Schedules[1].Until.Sub(config.Schedules[1].From), 96*time.Hour; got != want {
+		t.Fatalf("Schedules[1] range=%v, want %v", got, want)
+	}
+	if got, want := config.Schedules[1].Pause, true; got != want {
+		t.Fatalf("Schedules[1].Pause=%v, want %v", got, want)
+	}
+	if got, want := len(config.Variables), 2; got != want {
+		t.Fatalf("len(Variables)=%v, want %v", got, want)
+	}
+	if got, want := config.Variables[1].Name, "demand"; got != want {
+		t.Fatalf("Variables[1].Name=%v, want %v", got, want)
+	}
+	if got, want := config.Variables[1].Expr, "ceil(queue_depth / per_machine)"; got != want {
+		t.Fatalf("Variables[1].Expr=%v, want %v", got, want)
+	}
+	if got, want := config.Jitter, 1*time.Second; got != want {
+		t.Fatalf("Jitter=%v, want %v", got, want)
+	}
+	if got, want := config.AppIntervals[0].App, "my-app-critical-*"; got != want {
+		t.Fatalf("AppIntervals[0].App=%v, want %v", got, want)
+	}
+	if got, want := config.AppIntervals[0].Interval, 5*time.Second; got != want {
+		t.Fatalf("AppIntervals[0].Interval=%v, want %v", got, want)
+	}
+	if got, want := config.Cooldown, 1*time.Minute; got != want {
+		t.Fatalf("Cooldown=%v, want %v", got, want)
+	}
+	if got, want := config.APIRateLimit, 10.0; got != want {
+		t.Fatalf("APIRateLimit=%v, want %v", got, want)
+	}
+	if got, want := config.APIRateBurst, 20; got != want {
+		t.Fatalf("APIRateBurst=%v, want %v", got, want)
+	}
+	if got, want := config.CircuitBreakerThreshold, 5; got != want {
+		t.Fatalf("CircuitBreakerThreshold=%v, want %v", got, want)
+	}
+	if got, want := config.CircuitBreakerMaxBackoff, 10*time.Minute; got != want {
+		t.Fatalf("CircuitBreakerMaxBackoff=%v, want %v", got, want)
+	}
+	if got, want := config.Store.Type, "bolt"; got != want {
+		t.Fatalf("Store.Type=%v, want %v", got, want)
+	}
+	if got, want := config.Store.Path, "/data/fly-autoscaler.db"; got != want {
+		t.Fatalf("Store.Path=%v, want %v", got, want)
+	}
 
 	mc := config.MetricCollectors[0]
 	if got, want := mc.Type, "prometheus"; got != want {
@@ -99,4 +180,233 @@ func TestConfig_Validate(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("Expr", func(t *testing.T) {
+		newConfig := func(expr string) *main.Config {
+			c := main.NewConfig()
+			c.AppName = "myapp"
+			c.InitialMachineState = "started"
+			c.StartedMachineN = expr
+			c.MetricCollectors = []*main.MetricCollectorConfig{
+				{Type: "prometheus", MetricName: "queue_depth", Address: "http://localhost:9090", Query: "sum(queue_depth)"},
+			}
+			return c
+		}
+
+		t.Run("OK", func(t *testing.T) {
+			if err := newConfig("ceil(queue_depth / 10)").Validate(); err != nil {
+				t.Fatal(err)
+			}
+		})
+		t.Run("UnknownIdentifier", func(t *testing.T) {
+			if err := newConfig("ceil(queue_dept / 10)").Validate(); err == nil || err.Error() != `started-machine-count: unknown identifier "queue_dept" at column 6 in expression "ceil(queue_dept / 10)" (available metrics: queue_depth)` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("Variables", func(t *testing.T) {
+			c := newConfig("demand")
+			c.Variables = []*main.VariableConfig{
+				{Name: "demand", Expr: "ceil(queue_depth / per_machine)"},
+				{Name: "per_machine", Expr: "10"},
+			}
+			if err := c.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		})
+		t.Run("VariableCycle", func(t *testing.T) {
+			c := newConfig("a")
+			c.Variables = []*main.VariableConfig{
+				{Name: "a", Expr: "b + 1"},
+				{Name: "b", Expr: "c + 1"},
+				{Name: "c", Expr: "a + queue_depth"},
+			}
+			if err := c.Validate(); err == nil || err.Error() != `variables: variable dependency cycle: a -> b -> c -> a` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("VariableConflictsWithMetric", func(t *testing.T) {
+			c := newConfig("queue_depth")
+			c.Variables = []*main.VariableConfig{{Name: "queue_depth", Expr: "1"}}
+			if err := c.Validate(); err == nil || err.Error() != `variables[0]: variable "queue_depth" conflicts with metric name` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("Policy", func(t *testing.T) {
+			c := newConfig("")
+			c.StartedMachinePolicy = &main.PolicyConfig{Type: "target-tracking", Metric: "queue_depth", Target: 10}
+			if err := c.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		})
+		t.Run("PolicyAndExpr", func(t *testing.T) {
+			c := newConfig("1")
+			c.StartedMachinePolicy = &main.PolicyConfig{Type: "target-tracking", Metric: "queue_depth", Target: 10}
+			if err := c.Validate(); err == nil || err.Error() != `cannot define started machine count and started machine policy` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("PolicyUnknownMetric", func(t *testing.T) {
+			c := newConfig("")
+			c.StartedMachinePolicy = &main.PolicyConfig{Type: "target-tracking", Metric: "cpu", Target: 10}
+			if err := c.Validate(); err == nil || err.Error() != `started-machine-policy: unknown metric "cpu" (available metrics: queue_depth)` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("PolicyInvalidTarget", func(t *testing.T) {
+			c := newConfig("")
+			c.StartedMachinePolicy = &main.PolicyConfig{Type: "target-tracking", Metric: "queue_depth"}
+			if err := c.Validate(); err == nil || err.Error() != `started-machine-policy: target tracking target must be greater than zero` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("PolicyPID", func(t *testing.T) {
+			c := newConfig("")
+			c.StartedMachinePolicy = &main.PolicyConfig{Type: "pid", Metric: "queue_depth", Setpoint: 10, Kp: 0.5, Ki: 0.01}
+			if err := c.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		})
+		t.Run("PolicyPIDNoGain", func(t *testing.T) {
+			c := newConfig("")
+			c.StartedMachinePolicy = &main.PolicyConfig{Type: "pid", Metric: "queue_depth", Setpoint: 10}
+			if err := c.Validate(); err == nil || err.Error() != `started-machine-policy: pid requires at least one non-zero gain` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("PolicyPredictive", func(t *testing.T) {
+			c := newConfig("")
+			c.StartedMachinePolicy = &main.PolicyConfig{Type: "predictive", Metric: "queue_depth", Target: 10, Horizon: 10 * time.Minute}
+			c.Forecasts = []*main.ForecastConfig{{Metric: "queue_depth", Season: 24 * time.Hour, Lookback: 7 * 24 * time.Hour}}
+			if err := c.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		})
+		t.Run("ForecastUnknownMetric", func(t *testing.T) {
+			c := newConfig("forecast(queue_depth, \"10m\")")
+			c.Forecasts = []*main.ForecastConfig{{Metric: "cpu"}}
+			if err := c.Validate(); err == nil || err.Error() != `forecasts[0]: unknown metric "cpu" (available metrics: queue_depth)` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("ForecastLookback", func(t *testing.T) {
+			c := newConfig("forecast(queue_depth, \"10m\")")
+			c.Forecasts = []*main.ForecastConfig{{Metric: "queue_depth", Season: 24 * time.Hour, Lookback: 24 * time.Hour}}
+			if err := c.Validate(); err == nil || err.Error() != `forecasts[0]: forecast lookback must cover at least two seasons` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("ForecastSeasonWithoutLookback", func(t *testing.T) {
+			c := newConfig("forecast(queue_depth, \"10m\")")
+			c.Forecasts = []*main.ForecastConfig{{Metric: "queue_depth", Season: 24 * time.Hour}}
+			if err := c.Validate(); err == nil || err.Error() != `forecasts[0]: forecast season requires a lookback period` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("HistoryWindowExceedsRetention", func(t *testing.T) {
+			if err := newConfig(`avg_over(queue_depth, "2h")`).Validate(); err == nil || err.Error() != `started-machine-count: window of 2h0m0s exceeds the 1h0m0s of metric history retained at a 15s interval` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// A longer interval for any app requires the window to fit the shortest.
+			c := newConfig(`avg_over(queue_depth, "2h")`)
+			c.Interval = time.Minute
+			if err := c.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			c.AppIntervals = []*main.AppIntervalConfig{{App: "myapp", Interval: 15 * time.Second}}
+			if err := c.Validate(); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+		t.Run("AppMetricsNegativeMaxApps", func(t *testing.T) {
+			c := newConfig("1")
+			c.AppMetrics = &main.AppMetricsConfig{MaxApps: -1}
+			if err := c.Validate(); err == nil || err.Error() != `app-metrics: max apps cannot be negative` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("TracingSampleRate", func(t *testing.T) {
+			c := newConfig("1")
+			sampleRate := 2.0
+			c.Tracing = &main.TracingConfig{Endpoint: "localhost:4318", SampleRate: &sampleRate}
+			if err := c.Validate(); err == nil || err.Error() != `tracing: sample rate must be between 0 and 1` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("InvalidHTTPAddr", func(t *testing.T) {
+			c := newConfig("1")
+			c.HTTPAddr = "9090"
+			if err := c.Validate(); err == nil || err.Error() != `invalid http addr: "9090"` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("AuditLogFilePathRequired", func(t *testing.T) {
+			c := newConfig("1")
+			c.AuditLog = &main.AuditLogConfig{Type: "file"}
+			if err := c.Validate(); err == nil || err.Error() != `audit-log: file path required` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("AuditLogStoreRequiresBolt", func(t *testing.T) {
+			c := newConfig("1")
+			c.AuditLog = &main.AuditLogConfig{Type: "store"}
+			if err := c.Validate(); err == nil || err.Error() != `audit-log: store type requires a bolt store` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("NotificationsNoFilter", func(t *testing.T) {
+			c := newConfig("1")
+			c.Notifications = &main.NotificationsConfig{Sinks: []*main.NotificationSinkConfig{{Type: "slack", URL: "http://localhost"}}}
+			if err := c.Validate(); err == nil || err.Error() != `notifications: must set min machines, consecutive failures or max bound` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("NotificationSinkInvalidType", func(t *testing.T) {
+			c := newConfig("1")
+			c.Notifications = &main.NotificationsConfig{MaxBound: true, Sinks: []*main.NotificationSinkConfig{{Type: "slack", URL: "http://localhost", Types: []string{"scaled"}}}}
+			if err := c.Validate(); err == nil || err.Error() != `notifications: sinks[0]: invalid notification type: "scaled"` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("GuardrailMaxLessThanMin", func(t *testing.T) {
+			c := newConfig("1")
+			c.Guardrails = []*main.GuardrailConfig{{MinMachines: 5, MaxMachines: 2}}
+			if err := c.Validate(); err == nil || err.Error() != `guardrails[0]: guardrail max machines cannot be less than min machines` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("ScheduleNoEffect", func(t *testing.T) {
+			c := newConfig("1")
+			c.Schedules = []*main.ScheduleConfig{{Name: "nightly", Cron: "0 2 * * *", Duration: time.Hour}}
+			if err := c.Validate(); err == nil || err.Error() != `schedules[0]: schedule must set a machine count or pause` {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("SyntaxError", func(t *testing.T) {
+			if err := newConfig("queue_depth +").Validate(); err == nil || !strings.HasPrefix(err.Error(), `started-machine-count: compile expression: unexpected token EOF`) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	})
+}
+
+func TestNotificationsConfig_NewNotifier(t *testing.T) {
+	c := &main.NotificationsConfig{Sinks: []*main.NotificationSinkConfig{
+		{Type: "pagerduty", RoutingKey: "KEY"},
+		{Type: "pagerduty", RoutingKey: "KEY", Types: []string{"scale"}},
+		{Type: "slack", URL: "http://localhost"},
+	}}
+	n, err := c.NewNotifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// PagerDuty only receives failures unless types are set.
+	if got, want := n.Sinks[0].Types, notify.DefaultPagerDutyTypes; !slices.Equal(got, want) {
+		t.Fatalf("Sinks[0].Types=%v, want %v", got, want)
+	} else if got, want := n.Sinks[1].Types, []string{"scale"}; !slices.Equal(got, want) {
+		t.Fatalf("Sinks[1].Types=%v, want %v", got, want)
+	} else if got := n.Sinks[2].Types; got != nil {
+		t.Fatalf("Sinks[2].Types=%v, want nil", got)
+	}
 }
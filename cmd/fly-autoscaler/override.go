@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	fashttp "github.com/superfly/fly-autoscaler/http"
+)
+
+// PauseCommand represents a command to pause scaling of an app on a running
+// autoscaler until the pause expires.
+type PauseCommand struct {
+	Client *fashttp.Client
+	App    string
+	TTL    time.Duration
+}
+
+func NewPauseCommand() *PauseCommand {
+	return &PauseCommand{}
+}
+
+func (c *PauseCommand) Run(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("fly-autoscaler-pause", flag.ContinueOnError)
+	u := registerURLFlag(fs)
+	fs.DurationVar(&c.TTL, "ttl", fas.DefaultOverrideTTL, "time until the app resumes scaling")
+	fs.Usage = func() {
+		fmt.Println(`
+The pause command stops the autoscaler from reconciling an app until the TTL
+expires or the app is resumed. Pauses are retained across restarts if a
+persistent store is configured.
+
+Usage:
+
+	fly-autoscaler pause <app> [arguments]
+
+Arguments:
+`[1:])
+		fs.PrintDefaults()
+		fmt.Println("")
+	}
+	if c.App, err = parseAppArgs(fs, args); err != nil {
+		return err
+	}
+	c.Client = newClient(*u)
+
+	o, err := c.Client.Pause(ctx, c.App, c.TTL)
+	if err != nil {
+		return fmt.Errorf("cannot pause app: %w", err)
+	}
+	fmt.Printf("%s paused until %s\n", c.App, o.ExpiresAt.Local().Format(time.RFC3339))
+	return nil
+}
+
+// ResumeCommand represents a command to remove any pause or override from an
+// app on a running autoscaler.
+type ResumeCommand struct {
+	Client *fashttp.Client
+	App    string
+}
+
+func NewResumeCommand() *ResumeCommand {
+	return &ResumeCommand{}
+}
+
+func (c *ResumeCommand) Run(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("fly-autoscaler-resume", flag.ContinueOnError)
+	u := registerURLFlag(fs)
+	fs.Usage = func() {
+		fmt.Println(`
+The resume command removes any pause or override from an app so that it is
+scaled on its expressions again.
+
+Usage:
+
+	fly-autoscaler resume <app> [arguments]
+
+Arguments:
+`[1:])
+		fs.PrintDefaults()
+		fmt.Println("")
+	}
+	if c.App, err = parseAppArgs(fs, args); err != nil {
+		return err
+	}
+	c.Client = newClient(*u)
+
+	if err := c.Client.Resume(ctx, c.App); err != nil {
+		return fmt.Errorf("cannot resume app: %w", err)
+	}
+	fmt.Printf("%s resumed\n", c.App)
+	return nil
+}
+
+// OverrideCommand represents a command to fix the machine counts of an app on
+// a running autoscaler until the override expires.
+type OverrideCommand struct {
+	Client   *fashttp.Client
+	App      string
+	CreatedN *int
+	StartedN *int
+	TTL      time.Duration
+}
+
+func NewOverrideCommand() *OverrideCommand {
+	return &OverrideCommand{}
+}
+
+func (c *OverrideCommand) Run(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("fly-autoscaler-override", flag.ContinueOnError)
+	u := registerURLFlag(fs)
+	createdN := fs.Int("created", -1, "fixed number of created machines")
+	startedN := fs.Int("started", -1, "fixed number of started machines")
+	fs.DurationVar(&c.TTL, "ttl", fas.DefaultOverrideTTL, "time until the override expires")
+	fs.Usage = func() {
+		fmt.Println(`
+The override command fixes the number of created and/or started machines for
+an app, ignoring its expressions & schedules, until the TTL expires or the app
+is resumed. Guardrails still apply. Overrides are retained across restarts if a
+persistent store is configured.
+
+Usage:
+
+	fly-autoscaler override <app> [arguments]
+
+Arguments:
+`[1:])
+		fs.PrintDefaults()
+		fmt.Println("")
+	}
+	if c.App, err = parseAppArgs(fs, args); err != nil {
+		return err
+	}
+	if *createdN >= 0 {
+		c.CreatedN = createdN
+	}
+	if *startedN >= 0 {
+		c.StartedN = startedN
+	}
+	if c.CreatedN == nil && c.StartedN == nil {
+		return fmt.Errorf("-created or -started required")
+	}
+	c.Client = newClient(*u)
+
+	o, err := c.Client.Override(ctx, c.App, c.CreatedN, c.StartedN, c.TTL)
+	if err != nil {
+		return fmt.Errorf("cannot override app: %w", err)
+	}
+
+	var counts []string
+	if o.CreatedN != nil {
+		counts = append(counts, fmt.Sprintf("%d created", *o.CreatedN))
+	}
+	if o.StartedN != nil {
+		counts = append(counts, fmt.Sprintf("%d started", *o.StartedN))
+	}
+	fmt.Printf("%s fixed at %s machines until %s\n", c.App, strings.Join(counts, " & "), o.ExpiresAt.Local().Format(time.RFC3339))
+	return nil
+}
+
+// parseAppArgs parses flags & returns the single app name argument. The app
+// name may be passed before or after the flags.
+func parseAppArgs(fs *flag.FlagSet, args []string) (app string, err error) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		app, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+
+	if app == "" && fs.NArg() > 0 {
+		app, args = fs.Arg(0), fs.Args()[1:]
+	} else {
+		args = fs.Args()
+	}
+
+	if app == "" {
+		return "", fmt.Errorf("app name required")
+	} else if len(args) > 0 {
+		return "", fmt.Errorf("too many arguments")
+	}
+	return app, nil
+}
@@ -4,20 +4,28 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
-	"net/http"
-	_ "net/http/pprof"
 	"os"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	fas "github.com/superfly/fly-autoscaler"
+	fashttp "github.com/superfly/fly-autoscaler/http"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 // ServeCommand represents a command run the autoscaler server process.
 type ServeCommand struct {
-	pool   *fas.ReconcilerPool
-	Config *Config
+	pool       *fas.ReconcilerPool
+	httpServer *fashttp.Server
+	store      fas.Store
+	auditLog   fas.AuditLog
+	notifier   *fas.Notifier
+	elector    fas.LeaderElector
+	membership fas.Membership
+	tracer     *sdktrace.TracerProvider
+	Config     *Config
 }
 
 func NewServeCommand() *ServeCommand {
@@ -25,11 +33,56 @@ func NewServeCommand() *ServeCommand {
 }
 
 func (c *ServeCommand) Close() (err error) {
+	if c.httpServer != nil {
+		if err := c.httpServer.Close(); err != nil {
+			slog.Warn("failed to close http server", slog.Any("err", err))
+		}
+	}
+
 	if c.pool != nil {
 		if err := c.pool.Close(); err != nil {
 			slog.Warn("failed to close reconciler pool", slog.Any("err", err))
 		}
 	}
+
+	// Send any pending notifications once reconciliation has stopped.
+	if c.notifier != nil {
+		if err := c.notifier.Close(); err != nil {
+			slog.Warn("failed to close notifier", slog.Any("err", err))
+		}
+	}
+
+	if closer, ok := c.membership.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			slog.Warn("failed to close membership", slog.Any("err", err))
+		}
+	}
+
+	if closer, ok := c.elector.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			slog.Warn("failed to close leader elector", slog.Any("err", err))
+		}
+	}
+
+	// The store is closed separately if it is also used as the audit log.
+	if l, ok := c.auditLog.(*fas.FileAuditLog); ok {
+		if err := l.Close(); err != nil {
+			slog.Warn("failed to close audit log", slog.Any("err", err))
+		}
+	}
+
+	if closer, ok := c.store.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			slog.Warn("failed to close store", slog.Any("err", err))
+		}
+	}
+
+	// Flush any remaining spans after reconciliation has stopped.
+	if c.tracer != nil {
+		if err := c.tracer.Shutdown(context.Background()); err != nil {
+			slog.Warn("failed to shut down tracer", slog.Any("err", err))
+		}
+	}
 	return nil
 }
 
@@ -45,8 +98,19 @@ func (c *ServeCommand) Run(ctx context.Context, args []string) (err error) {
 		return err
 	}
 
-	// Instantiate clients for access org/apps & for scaling machines.
-	flyClient, err := c.Config.NewFlyClient(ctx)
+	// Export traces of each reconciliation, if enabled.
+	if config := c.Config.Tracing; config != nil {
+		if c.tracer, err = config.NewTracerProvider(ctx); err != nil {
+			return fmt.Errorf("cannot initialize tracing: %w", err)
+		}
+		otel.SetTracerProvider(c.tracer)
+		slog.Info("tracing enabled", slog.String("endpoint", config.Endpoint))
+	}
+
+	// Instantiate clients for access org/apps & for scaling machines. All
+	// calls share a single rate limiter, if enabled.
+	limiter := c.Config.NewRateLimiter()
+	flyClient, err := c.Config.NewFlyClient(ctx, limiter)
 	if err != nil {
 		return fmt.Errorf("cannot create fly client: %w", err)
 	}
@@ -59,6 +123,44 @@ func (c *ServeCommand) Run(ctx context.Context, args []string) (err error) {
 	}
 	slog.Info("metrics collectors initialized", slog.Int("n", len(collectors)))
 
+	// Instantiate store to persist app state across restarts.
+	if c.store, err = c.Config.NewStore(); err != nil {
+		return fmt.Errorf("cannot open store: %w", err)
+	}
+
+	// Record each scaling decision, if enabled.
+	if config := c.Config.AuditLog; config != nil {
+		if c.auditLog, err = config.NewAuditLog(c.store); err != nil {
+			return fmt.Errorf("cannot open audit log: %w", err)
+		}
+		slog.Info("audit log enabled", slog.String("type", config.Type))
+	}
+
+	// Send notifications for notable scaling events, if enabled.
+	if config := c.Config.Notifications; config != nil {
+		if c.notifier, err = config.NewNotifier(); err != nil {
+			return fmt.Errorf("cannot initialize notifier: %w", err)
+		}
+		if err := c.notifier.Open(); err != nil {
+			return fmt.Errorf("cannot open notifier: %w", err)
+		}
+		slog.Info("notifications enabled", slog.Int("sinks", len(config.Sinks)))
+	}
+
+	// Instantiate policies used instead of expressions, if any.
+	createdMachinePolicy, err := c.Config.CreatedMachinePolicy.NewPolicy()
+	if err != nil {
+		return fmt.Errorf("cannot create created machine policy: %w", err)
+	}
+	startedMachinePolicy, err := c.Config.StartedMachinePolicy.NewPolicy()
+	if err != nil {
+		return fmt.Errorf("cannot create started machine policy: %w", err)
+	}
+
+	// Forecasters are shared by all reconcilers so range query results are
+	// cached across reconciliations.
+	forecasters := c.Config.NewForecasters()
+
 	minCreatedMachineN := c.Config.GetMinCreatedMachineN()
 	maxCreatedMachineN := c.Config.GetMaxCreatedMachineN()
 	minStartedMachineN := c.Config.GetMinStartedMachineN()
@@ -66,9 +168,13 @@ func (c *ServeCommand) Run(ctx context.Context, args []string) (err error) {
 
 	// Instantiate pool.
 	p := fas.NewReconcilerPool(flyClient, c.Config.Concurrency)
-	if p.NewFlapsClient, err = c.Config.NewFlapsClient(); err != nil {
+	if p.NewFlapsClient, err = c.Config.NewFlapsClient(limiter); err != nil {
 		return fmt.Errorf("cannot initialize flaps client constructor: %w", err)
 	}
+	p.RateLimiter = limiter
+	p.CircuitBreakerThreshold = c.Config.CircuitBreakerThreshold
+	p.CircuitBreakerBackoff = c.Config.CircuitBreakerBackoff
+	p.CircuitBreakerMaxBackoff = c.Config.CircuitBreakerMaxBackoff
 	p.NewReconciler = func() *fas.Reconciler {
 		r := fas.NewReconciler()
 		r.MinCreatedMachineN = minCreatedMachineN
@@ -76,26 +182,91 @@ func (c *ServeCommand) Run(ctx context.Context, args []string) (err error) {
 		r.MinStartedMachineN = minStartedMachineN
 		r.MaxStartedMachineN = maxStartedMachineN
 		r.InitialMachineState = c.Config.InitialMachineState
+		r.Cooldown = c.Config.Cooldown
 		r.Regions = c.Config.Regions
 		r.ProcessGroup = c.Config.ProcessGroup
 		r.Collectors = collectors
+		r.Variables = c.Config.GetVariables()
+		r.Forecasters = forecasters
+		r.Schedules = c.Config.GetSchedules()
+		r.Guardrails = c.Config.GetGuardrails()
+		r.GuardrailStrict = c.Config.GuardrailStrict
+		r.CreatedMachinePolicy = createdMachinePolicy
+		r.StartedMachinePolicy = startedMachinePolicy
 		return r
 	}
 	p.AppName = c.Config.AppName
 	p.OrganizationSlug = c.Config.Org
 	p.ReconcileInterval = c.Config.Interval
+	p.ReconcileJitter = c.Config.Jitter
+	p.AppReconcileIntervals = c.Config.AppReconcileIntervals()
 	p.ReconcileTimeout = c.Config.Timeout
 	p.AppListRefreshInterval = c.Config.AppListRefreshInterval
+	p.Store = c.store
+	p.AuditLog = c.auditLog
+	p.Notifier = c.notifier
+	if config := c.Config.AuditLog; config != nil {
+		p.AuditNoScale = config.IncludeNoScale
+	}
+
+	// Only run reconciliation on the leader if running multiple instances.
+	if config := c.Config.LeaderElection; config != nil {
+		if c.elector, err = config.NewLeaderElector(); err != nil {
+			return fmt.Errorf("cannot initialize leader elector: %w", err)
+		}
+		p.LeaderElector = c.elector
+		if config.Interval > 0 {
+			p.LeaderElectionInterval = config.Interval
+		}
+		slog.Info("leader election enabled", slog.String("type", config.Type))
+	}
+
+	// Shard apps across instances, if enabled.
+	if config := c.Config.Sharding; config != nil {
+		if p.InstanceID, err = config.InstanceID(); err != nil {
+			return fmt.Errorf("cannot determine instance id: %w", err)
+		}
+		if c.membership, err = config.NewMembership(); err != nil {
+			return fmt.Errorf("cannot initialize membership: %w", err)
+		}
+		p.Membership = c.membership
+
+		// Heartbeat several times per TTL so a single missed heartbeat does
+		// not expire this instance.
+		if config.TTL > 0 {
+			p.MembershipHeartbeatInterval = min(p.MembershipHeartbeatInterval, config.TTL/3)
+		}
+		slog.Info("sharding enabled",
+			slog.String("type", config.Type),
+			slog.String("id", p.InstanceID))
+	}
+
+	// Export labeled metrics for each app, if enabled.
+	if config := c.Config.AppMetrics; config != nil {
+		p.AppMetrics = true
+		p.AppMetricsApps = config.Apps
+		p.AppMetricsMaxApps = config.MaxApps
+		p.AppMetricsRegions = config.Regions
+	}
+
 	p.RegisterPromMetrics(prometheus.DefaultRegisterer)
 	c.pool = p
 
 	attrs := []any{
 		slog.String("interval", p.ReconcileInterval.String()),
+		slog.String("jitter", p.ReconcileJitter.String()),
 		slog.String("timeout", p.ReconcileTimeout.String()),
 		slog.String("appListRefreshInterval", p.AppListRefreshInterval.String()),
 		slog.Int("collectors", len(collectors)),
 	}
 
+	if c.Config.Cooldown > 0 {
+		attrs = append(attrs, slog.String("cooldown", c.Config.Cooldown.String()))
+	}
+	if limiter != nil {
+		attrs = append(attrs, slog.Float64("apiRateLimit", c.Config.APIRateLimit))
+	}
+
 	if regions := c.Config.Regions; len(regions) > 0 {
 		attrs = append(attrs, slog.Any("regions", regions))
 	}
@@ -123,20 +294,20 @@ func (c *ServeCommand) Run(ctx context.Context, args []string) (err error) {
 		return fmt.Errorf("cannot initialize reconciler pool: %w", err)
 	}
 
-	go c.serveMetricsServer(ctx)
+	// Serve metrics & API.
+	c.httpServer = fashttp.NewServer(p)
+	if c.Config.HTTPAddr != "" {
+		c.httpServer.Addr = c.Config.HTTPAddr
+	}
+	c.httpServer.Token = c.Config.AuthToken
+	if err := c.httpServer.Open(); err != nil {
+		return fmt.Errorf("cannot open http server: %w", err)
+	}
+	slog.Info("http server listening", slog.String("addr", c.httpServer.Addr))
 
 	return nil
 }
 
-func (c *ServeCommand) serveMetricsServer(ctx context.Context) {
-	addr := ":9090"
-	slog.Info("serving metrics", slog.String("addr", addr))
-	http.Handle("/metrics", promhttp.Handler())
-	if err := http.ListenAndServe(addr, nil); err != nil && ctx.Err() == nil {
-		slog.Error("cannot serve metrics", slog.Any("err", err))
-	}
-}
-
 func (c *ServeCommand) parseFlags(ctx context.Context, args []string) (err error) {
 	fs := flag.NewFlagSet("fly-autoscaler-serve", flag.ContinueOnError)
 	configPath := registerConfigPathFlag(fs)
@@ -0,0 +1,83 @@
+package fas
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// CollectorHealth represents the recent results of a metric collector across
+// all apps.
+type CollectorHealth struct {
+	Name string `json:"name"`
+
+	SuccessN int64 `json:"success_count"`
+	ErrorN   int64 `json:"error_count"`
+
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorApp  string     `json:"last_error_app,omitempty"`
+
+	// Duration of the most recent collection, in seconds.
+	LastDuration float64 `json:"last_duration_seconds"`
+}
+
+// Healthy returns true if the collector's most recent collection succeeded.
+func (h *CollectorHealth) Healthy() bool {
+	return h.LastErrorAt == nil || (h.LastSuccessAt != nil && h.LastSuccessAt.After(*h.LastErrorAt))
+}
+
+// collectorHealth tracks the health of each collector. It is shared by all
+// reconcilers in a pool.
+type collectorHealth struct {
+	mu sync.Mutex
+	m  map[string]*CollectorHealth
+}
+
+func newCollectorHealth() *collectorHealth {
+	return &collectorHealth{m: make(map[string]*CollectorHealth)}
+}
+
+// record updates the named collector's health after collecting for app.
+// This is a no-op if h is nil.
+func (h *collectorHealth) record(name, app string, start time.Time, err error) {
+	if h == nil {
+		return
+	}
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c := h.m[name]
+	if c == nil {
+		c = &CollectorHealth{Name: name}
+		h.m[name] = c
+	}
+	c.LastDuration = now.Sub(start).Seconds()
+
+	if err != nil {
+		c.ErrorN++
+		c.LastErrorAt, c.LastError, c.LastErrorApp = &now, err.Error(), app
+		return
+	}
+	c.SuccessN++
+	c.LastSuccessAt = &now
+}
+
+// CollectorHealth returns the health of each collector that has been used,
+// sorted by name.
+func (p *ReconcilerPool) CollectorHealth() []*CollectorHealth {
+	h := p.collectorHealth
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	a := make([]*CollectorHealth, 0, len(h.m))
+	for _, c := range h.m {
+		other := *c
+		a = append(a, &other)
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].Name < a[j].Name })
+	return a
+}
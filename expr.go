@@ -0,0 +1,238 @@
+package fas
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/file"
+	"github.com/expr-lang/expr/vm"
+)
+
+// Expr represents a compiled scaling expression. The program is type-checked
+// against a fixed set of metric names at compile time so it can be run on
+// each reconciliation without recompiling. Safe for concurrent use.
+type Expr struct {
+	src       string
+	names     []string
+	program   *vm.Program
+	maxWindow time.Duration
+}
+
+// CompileExpr compiles s against an environment containing the built-in
+// variables & the given metric names. Returns an error that identifies the
+// first unknown identifier, if any.
+func CompileExpr(s string, metricNames []string) (*Expr, error) {
+	if s == "" {
+		return nil, ErrExprRequired
+	}
+
+	names := make([]string, len(metricNames))
+	copy(names, metricNames)
+	sort.Strings(names)
+
+	env := (&ExprVars{}).env()
+	for _, name := range names {
+		if _, ok := env[name]; ok {
+			return nil, fmt.Errorf("metric name %q conflicts with built-in variable", name)
+		}
+		env[name] = float64(0)
+	}
+
+	program, err := expr.Compile(s,
+		expr.AsFloat64(),
+		expr.Env(env),
+		expr.Patch(&metricNamePatcher{}),
+	)
+	if err != nil {
+		return nil, formatCompileError(err, s, names)
+	}
+
+	// Validate literal arguments to built-in functions.
+	v := &funcArgValidator{src: s, names: names}
+	node := program.Node()
+	ast.Walk(&node, v)
+	if v.err != nil {
+		return nil, v.err
+	}
+
+	return &Expr{
+		src:       s,
+		names:     names,
+		program:   program,
+		maxWindow: v.maxWindow,
+	}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string { return e.src }
+
+// MaxWindow returns the longest window passed to a history function, such as
+// avg_over(), or zero if no history functions are used.
+func (e *Expr) MaxWindow() time.Duration { return e.maxWindow }
+
+// Eval runs the expression against a set of metric values & built-in variables.
+func (e *Expr) Eval(metrics map[string]float64, vars *ExprVars) (float64, error) {
+	env := vars.env()
+	for _, name := range e.names {
+		v, ok := metrics[name]
+		if !ok {
+			return 0, fmt.Errorf("no value for metric %q", name)
+		}
+		env[name] = v
+	}
+
+	v, err := expr.Run(e.program, env)
+	if err != nil {
+		return 0, fmt.Errorf("execute expression: %w", err)
+	}
+	return v.(float64), nil
+}
+
+// ExprVars holds the built-in variables that are available to all expressions
+// in addition to collected metrics. Machine counts only include reachable
+// machines in the reconciler's process group.
+type ExprVars struct {
+	AppName string
+
+	CreatedN int
+	StartedN int
+	StoppedN int
+
+	CreatedNByRegion map[string]int
+	StartedNByRegion map[string]int
+	StoppedNByRegion map[string]int
+
+	// Result of the same expression on the previous reconciliation.
+	// Zero if there is no previous result.
+	PreviousTarget int
+
+	// Time of the last scaling action. Zero if the app has not been scaled.
+	LastScaledAt time.Time
+
+	// Recent samples for each metric. Used by history functions.
+	History map[string][]MetricSample
+
+	// Returns the forecast value of a metric. If nil, forecasts are made from
+	// History using the default smoothing factors.
+	Forecast func(name string, horizon time.Duration) (float64, error)
+
+	// Current time. Used for computing durations & by time functions.
+	Now time.Time
+}
+
+// env returns the variables as an expression environment. Variable names and
+// types must remain stable as they are used to type-check expressions.
+func (v *ExprVars) env() map[string]any {
+	sinceLastScale := math.Inf(1)
+	if !v.LastScaledAt.IsZero() {
+		sinceLastScale = v.Now.Sub(v.LastScaledAt).Seconds()
+	}
+
+	env := timeFuncs(v.Now)
+	for k, fn := range historyFuncs(v.History, v.Now) {
+		env[k] = fn
+	}
+	env["forecast"] = forecastFunc(v)
+	for k, val := range map[string]any{
+		"app_name":                   v.AppName,
+		"created_machines":           v.CreatedN,
+		"started_machines":           v.StartedN,
+		"stopped_machines":           v.StoppedN,
+		"created_machines_by_region": nonNilMap(v.CreatedNByRegion),
+		"started_machines_by_region": nonNilMap(v.StartedNByRegion),
+		"stopped_machines_by_region": nonNilMap(v.StoppedNByRegion),
+		"previous_target":            v.PreviousTarget,
+		"seconds_since_scale":        sinceLastScale,
+	} {
+		env[k] = val
+	}
+	return env
+}
+
+func nonNilMap(m map[string]int) map[string]int {
+	if m == nil {
+		return map[string]int{}
+	}
+	return m
+}
+
+// funcArgValidator walks an expression & validates literal string arguments
+// passed to built-in functions. Only the first error is recorded. The longest
+// history function window is also recorded.
+type funcArgValidator struct {
+	src       string
+	names     []string
+	err       error
+	maxWindow time.Duration
+}
+
+func (v *funcArgValidator) Visit(node *ast.Node) {
+	call, ok := (*node).(*ast.CallNode)
+	if !ok || v.err != nil {
+		return
+	}
+	ident, ok := call.Callee.(*ast.IdentifierNode)
+	if !ok {
+		return
+	}
+
+	for i, arg := range call.Arguments {
+		str, ok := arg.(*ast.StringNode)
+		if !ok {
+			continue
+		}
+
+		// History functions must reference a collected metric.
+		if isHistoryFunc(ident.Value) && i == 0 && !slices.Contains(v.names, str.Value) {
+			v.err = unknownIdentifierError(str.Value, str.Location().Column, v.src, v.names)
+			return
+		}
+
+		err := validateTimeFuncArg(ident.Value, i, str.Value)
+		if err == nil {
+			err = validateHistoryFuncArg(ident.Value, i, str.Value)
+		}
+		if err != nil {
+			v.err = fmt.Errorf("invalid argument in expression %q: %s(): %w", v.src, ident.Value, err)
+			return
+		}
+
+		if d, ok := historyFuncWindow(ident.Value, i, str.Value); ok {
+			v.maxWindow = max(v.maxWindow, d)
+		}
+	}
+}
+
+// formatCompileError rewrites an unknown name error from the compiler so that
+// it names the identifier & lists the identifiers that are available.
+func formatCompileError(err error, s string, names []string) error {
+	var fileErr *file.Error
+	if !errors.As(err, &fileErr) {
+		return fmt.Errorf("compile expression: %w", err)
+	}
+
+	name, ok := strings.CutPrefix(fileErr.Message, "unknown name ")
+	if !ok {
+		return fmt.Errorf("compile expression: %w", err)
+	}
+
+	return unknownIdentifierError(name, fileErr.Column, s, names)
+}
+
+// unknownIdentifierError returns an error for an unknown identifier at a
+// 0-based column. Lists the available metric names to help fix typos.
+func unknownIdentifierError(name string, column int, s string, names []string) error {
+	available := "none"
+	if len(names) > 0 {
+		available = strings.Join(names, ", ")
+	}
+	return fmt.Errorf("unknown identifier %q at column %d in expression %q (available metrics: %s)",
+		name, column+1, s, available)
+}
@@ -0,0 +1,170 @@
+package fas
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/expr-lang/expr/ast"
+)
+
+// historyFuncs returns expression functions that operate on recent samples of
+// a metric instead of only its current value. The metric is passed by name,
+// e.g. avg_over(queue_depth, "5m"), and is rewritten to a string at compile
+// time by metricNamePatcher.
+//
+//	avg_over(metric, window)  average of samples within the window
+//	min_over(metric, window)  minimum of samples within the window
+//	max_over(metric, window)  maximum of samples within the window
+//	rate(metric, window)      per-second change across the window
+//	ewma(metric, alpha)       exponentially weighted moving average
+//
+// Samples are kept per app in AppState so history is limited to the most
+// recent MaxMetricSampleN collections.
+func historyFuncs(history map[string][]MetricSample, now time.Time) map[string]any {
+	return map[string]any{
+		"avg_over": func(name, window string) (float64, error) {
+			samples, err := samplesWithin(history, name, window, now)
+			if err != nil {
+				return 0, err
+			}
+
+			var sum float64
+			for _, sample := range samples {
+				sum += sample.Value
+			}
+			return sum / float64(len(samples)), nil
+		},
+
+		"min_over": func(name, window string) (float64, error) {
+			samples, err := samplesWithin(history, name, window, now)
+			if err != nil {
+				return 0, err
+			}
+
+			v := samples[0].Value
+			for _, sample := range samples[1:] {
+				v = min(v, sample.Value)
+			}
+			return v, nil
+		},
+
+		"max_over": func(name, window string) (float64, error) {
+			samples, err := samplesWithin(history, name, window, now)
+			if err != nil {
+				return 0, err
+			}
+
+			v := samples[0].Value
+			for _, sample := range samples[1:] {
+				v = max(v, sample.Value)
+			}
+			return v, nil
+		},
+
+		"rate": func(name, window string) (float64, error) {
+			samples, err := samplesWithin(history, name, window, now)
+			if err != nil {
+				return 0, err
+			} else if len(samples) < 2 {
+				return 0, nil // not enough samples to compute a change
+			}
+
+			first, last := samples[0], samples[len(samples)-1]
+			elapsed := last.Timestamp.Sub(first.Timestamp).Seconds()
+			if elapsed <= 0 {
+				return 0, nil
+			}
+			return (last.Value - first.Value) / elapsed, nil
+		},
+
+		"ewma": func(name string, alpha float64) (float64, error) {
+			if alpha <= 0 || alpha > 1 {
+				return 0, fmt.Errorf("ewma alpha must be within (0, 1], got %v", alpha)
+			}
+
+			samples := history[name]
+			if len(samples) == 0 {
+				return 0, fmt.Errorf("no samples for metric %q", name)
+			}
+
+			v := samples[0].Value
+			for _, sample := range samples[1:] {
+				v = alpha*sample.Value + (1-alpha)*v
+			}
+			return v, nil
+		},
+	}
+}
+
+// samplesWithin returns samples for a metric that were collected within the
+// window before now. Returns an error if there are no samples in the window.
+func samplesWithin(history map[string][]MetricSample, name, window string, now time.Time) ([]MetricSample, error) {
+	d, err := time.ParseDuration(window)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window %q", window)
+	}
+
+	// Samples are in collection order so find the first one inside the window.
+	samples := history[name]
+	since := now.Add(-d)
+	for i, sample := range samples {
+		if sample.Timestamp.After(since) {
+			return samples[i:], nil
+		}
+	}
+	return nil, fmt.Errorf("no samples for metric %q in the last %s", name, window)
+}
+
+// isHistoryFunc returns true if name is a function that takes a metric name.
+func isHistoryFunc(name string) bool {
+	switch name {
+	case "avg_over", "min_over", "max_over", "rate", "ewma", "forecast":
+		return true
+	default:
+		return false
+	}
+}
+
+// validateHistoryFuncArg checks a literal window passed to a history function.
+func validateHistoryFuncArg(name string, i int, s string) error {
+	if !isHistoryFunc(name) || name == "ewma" || i != 1 {
+		return nil
+	}
+
+	if _, err := time.ParseDuration(s); err != nil {
+		if name == "forecast" {
+			return fmt.Errorf("invalid horizon %q", s)
+		}
+		return fmt.Errorf("invalid window %q", s)
+	}
+	return nil
+}
+
+// historyFuncWindow returns the window of a history function argument, if the
+// argument is a window. Forecast horizons are not windows.
+func historyFuncWindow(name string, i int, s string) (time.Duration, bool) {
+	if !isHistoryFunc(name) || name == "ewma" || name == "forecast" || i != 1 {
+		return 0, false
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil
+}
+
+// metricNamePatcher rewrites a metric identifier passed as the first argument
+// to a history function into a string so the function receives the metric's
+// name rather than its current value.
+type metricNamePatcher struct{}
+
+func (p *metricNamePatcher) Visit(node *ast.Node) {
+	call, ok := (*node).(*ast.CallNode)
+	if !ok || len(call.Arguments) == 0 {
+		return
+	}
+	if ident, ok := call.Callee.(*ast.IdentifierNode); !ok || !isHistoryFunc(ident.Value) {
+		return
+	}
+
+	if ident, ok := call.Arguments[0].(*ast.IdentifierNode); ok {
+		ast.Patch(&call.Arguments[0], &ast.StringNode{Value: ident.Value})
+	}
+}
@@ -0,0 +1,95 @@
+package fas_test
+
+import (
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+func TestExpr_HistoryFuncs(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 10, 0, 0, time.UTC)
+	history := map[string][]fas.MetricSample{
+		"queue_depth": {
+			{Timestamp: now.Add(-8 * time.Minute), Value: 100},
+			{Timestamp: now.Add(-4 * time.Minute), Value: 10},
+			{Timestamp: now.Add(-2 * time.Minute), Value: 20},
+			{Timestamp: now, Value: 30},
+		},
+	}
+
+	for _, tt := range []struct {
+		expr string
+		want float64
+	}{
+		{`avg_over(queue_depth, "5m")`, 20},
+		{`avg_over(queue_depth, "10m")`, 40},
+		{`avg_over("queue_depth", "1s")`, 30},
+		{`min_over(queue_depth, "5m")`, 10},
+		{`max_over(queue_depth, "5m")`, 30},
+		{`max_over(queue_depth, "10m")`, 100},
+		{`rate(queue_depth, "5m") * 60`, 5},
+		{`rate(queue_depth, "1s")`, 0},
+		{`ewma(queue_depth, 0.5)`, 33.75},
+		{`ewma(queue_depth, 1)`, 30},
+		{`queue_depth - avg_over(queue_depth, "5m")`, 10},
+	} {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := fas.CompileExpr(tt.expr, []string{"queue_depth"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			vars := &fas.ExprVars{History: history, Now: now}
+			if v, err := e.Eval(map[string]float64{"queue_depth": 30}, vars); err != nil {
+				t.Fatal(err)
+			} else if got, want := v, tt.want; got != want {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		})
+	}
+
+	t.Run("ErrUnknownMetric", func(t *testing.T) {
+		_, err := fas.CompileExpr(`avg_over(queue_dept, "5m")`, []string{"queue_depth"})
+		if err == nil || err.Error() != `unknown identifier "queue_dept" at column 10 in expression "avg_over(queue_dept, \"5m\")" (available metrics: queue_depth)` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("MaxWindow", func(t *testing.T) {
+		e, err := fas.CompileExpr(`max(avg_over(queue_depth, "5m"), rate(queue_depth, "30m"), forecast(queue_depth, "2h"))`, []string{"queue_depth"})
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := e.MaxWindow(), 30*time.Minute; got != want {
+			t.Fatalf("MaxWindow()=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrInvalidWindow", func(t *testing.T) {
+		_, err := fas.CompileExpr(`max_over(queue_depth, "5")`, []string{"queue_depth"})
+		if err == nil || err.Error() != `invalid argument in expression "max_over(queue_depth, \"5\")": max_over(): invalid window "5"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrNoSamples", func(t *testing.T) {
+		e, err := fas.CompileExpr(`avg_over(queue_depth, "5m")`, []string{"queue_depth"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		vars := &fas.ExprVars{History: history, Now: now.Add(time.Hour)}
+		if _, err := e.Eval(map[string]float64{"queue_depth": 30}, vars); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("ErrInvalidAlpha", func(t *testing.T) {
+		e, err := fas.CompileExpr(`ewma(queue_depth, 2)`, []string{"queue_depth"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		vars := &fas.ExprVars{History: history, Now: now}
+		if _, err := e.Eval(map[string]float64{"queue_depth": 30}, vars); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package fas_test
+
+import (
+	"testing"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+func TestCompileExpr(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		e, err := fas.CompileExpr("ceil(queue_depth / 10)", []string{"queue_depth"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Ensure the same program can be evaluated multiple times.
+		for _, tt := range []struct {
+			value float64
+			want  float64
+		}{
+			{0, 0},
+			{15, 2},
+			{100, 10},
+		} {
+			if v, err := e.Eval(map[string]float64{"queue_depth": tt.value}, &fas.ExprVars{}); err != nil {
+				t.Fatal(err)
+			} else if got, want := v, tt.want; got != want {
+				t.Fatalf("Eval(%v)=%v, want %v", tt.value, got, want)
+			}
+		}
+	})
+
+	t.Run("ErrExprRequired", func(t *testing.T) {
+		if _, err := fas.CompileExpr("", nil); err != fas.ErrExprRequired {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrUnknownIdentifier", func(t *testing.T) {
+		_, err := fas.CompileExpr("max(1, queue_dept)", []string{"queue_depth", "cpu"})
+		if err == nil || err.Error() != `unknown identifier "queue_dept" at column 8 in expression "max(1, queue_dept)" (available metrics: cpu, queue_depth)` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrNoMetrics", func(t *testing.T) {
+		_, err := fas.CompileExpr("foo", nil)
+		if err == nil || err.Error() != `unknown identifier "foo" at column 1 in expression "foo" (available metrics: none)` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrMissingValue", func(t *testing.T) {
+		e, err := fas.CompileExpr("foo + bar", []string{"foo", "bar"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.Eval(map[string]float64{"foo": 1}, &fas.ExprVars{}); err == nil || err.Error() != `no value for metric "bar"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
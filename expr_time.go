@@ -0,0 +1,159 @@
+package fas
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// timeFuncs returns the time-based expression functions evaluated at now.
+//
+//	hour(tz)                 hour of the day (0-23) in the time zone
+//	weekday(tz)              day of the week (0=Sunday) in the time zone
+//	between(start, end, tz)  true if the time of day is in ["HH:MM", "HH:MM")
+//	cron_active(spec, dur)   true if the cron schedule fired within dur
+//
+// Time zones are IANA names such as "America/New_York". A blank time zone is
+// treated as UTC. Cron specs use the standard 5-field format.
+func timeFuncs(now time.Time) map[string]any {
+	return map[string]any{
+		"hour": func(tz string) (int, error) {
+			loc, err := loadLocation(tz)
+			if err != nil {
+				return 0, err
+			}
+			return now.In(loc).Hour(), nil
+		},
+
+		"weekday": func(tz string) (int, error) {
+			loc, err := loadLocation(tz)
+			if err != nil {
+				return 0, err
+			}
+			return int(now.In(loc).Weekday()), nil
+		},
+
+		"between": func(start, end, tz string) (bool, error) {
+			return between(now, start, end, tz)
+		},
+
+		"cron_active": func(spec, dur string) (bool, error) {
+			return cronActive(now, spec, dur)
+		},
+	}
+}
+
+// between returns true if the time of day of now is within [start, end) in
+// the given time zone. If end is before start then the range wraps midnight.
+func between(now time.Time, start, end, tz string) (bool, error) {
+	loc, err := loadLocation(tz)
+	if err != nil {
+		return false, err
+	}
+	startMin, err := parseTimeOfDay(start)
+	if err != nil {
+		return false, err
+	}
+	endMin, err := parseTimeOfDay(end)
+	if err != nil {
+		return false, err
+	}
+
+	t := now.In(loc)
+	minute := t.Hour()*60 + t.Minute()
+	if startMin <= endMin {
+		return minute >= startMin && minute < endMin, nil
+	}
+	return minute >= startMin || minute < endMin, nil
+}
+
+// cronActive returns true if the cron schedule fired within the duration
+// before now. For example, a spec of "0 2 * * *" with a duration of "2h" is
+// active every day from 02:00 until 04:00 UTC.
+func cronActive(now time.Time, spec, dur string) (bool, error) {
+	sched, err := parseCronSpec(spec)
+	if err != nil {
+		return false, err
+	}
+	d, err := time.ParseDuration(dur)
+	if err != nil {
+		return false, fmt.Errorf("invalid duration %q", dur)
+	}
+
+	next := sched.Next(now.Add(-d))
+	return !next.After(now), nil
+}
+
+// parseTimeOfDay parses an "HH:MM" string into minutes since midnight.
+func parseTimeOfDay(s string) (int, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+
+	h, err := strconv.Atoi(hh)
+	if err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	m, err := strconv.Atoi(mm)
+	if err != nil || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return h*60 + m, nil
+}
+
+var locations sync.Map // name -> *time.Location
+
+// loadLocation returns a cached time zone by IANA name.
+func loadLocation(name string) (*time.Location, error) {
+	if v, ok := locations.Load(name); ok {
+		return v.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q", name)
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+var cronSpecs sync.Map // spec -> cron.Schedule
+
+// parseCronSpec returns a cached, parsed standard cron schedule.
+func parseCronSpec(spec string) (cron.Schedule, error) {
+	if v, ok := cronSpecs.Load(spec); ok {
+		return v.(cron.Schedule), nil
+	}
+
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron spec %q: %w", spec, err)
+	}
+	cronSpecs.Store(spec, sched)
+	return sched, nil
+}
+
+// validateTimeFuncArg checks a literal string argument passed to a time
+// function so that invalid time zones, times & cron specs are reported at
+// compile time instead of on the first evaluation.
+func validateTimeFuncArg(name string, i int, s string) (err error) {
+	switch {
+	case (name == "hour" || name == "weekday") && i == 0,
+		name == "between" && i == 2:
+		_, err = loadLocation(s)
+	case name == "between":
+		_, err = parseTimeOfDay(s)
+	case name == "cron_active" && i == 0:
+		_, err = parseCronSpec(s)
+	case name == "cron_active" && i == 1:
+		if _, e := time.ParseDuration(s); e != nil {
+			err = fmt.Errorf("invalid duration %q", s)
+		}
+	}
+	return err
+}
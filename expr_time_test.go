@@ -0,0 +1,58 @@
+package fas_test
+
+import (
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+func TestExpr_TimeFuncs(t *testing.T) {
+	// Saturday, 2:30am UTC which is Friday, 9:30pm in New York.
+	now := time.Date(2000, 1, 1, 2, 30, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		expr string
+		want float64
+	}{
+		{`hour("")`, 2},
+		{`hour("America/New_York")`, 21},
+		{`weekday("UTC")`, 6},
+		{`weekday("America/New_York")`, 5},
+		{`between("02:00", "03:00", "") ? 1 : 0`, 1},
+		{`between("03:00", "04:00", "") ? 1 : 0`, 0},
+		{`between("21:00", "09:00", "America/New_York") ? 1 : 0`, 1},
+		{`between("09:00", "18:00", "America/New_York") ? 1 : 0`, 0},
+		{`cron_active("0 2 * * *", "1h") ? 1 : 0`, 1},
+		{`cron_active("0 2 * * *", "15m") ? 1 : 0`, 0},
+		{`cron_active("0 3 * * *", "2h") ? 1 : 0`, 0},
+	} {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := fas.CompileExpr(tt.expr, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v, err := e.Eval(nil, &fas.ExprVars{Now: now}); err != nil {
+				t.Fatal(err)
+			} else if got, want := v, tt.want; got != want {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		})
+	}
+
+	t.Run("ErrInvalidArgs", func(t *testing.T) {
+		for _, tt := range []struct {
+			expr string
+			err  string
+		}{
+			{`hour("Mars/Olympus")`, `invalid argument in expression "hour(\"Mars/Olympus\")": hour(): invalid time zone "Mars/Olympus"`},
+			{`between("9am", "18:00", "") ? 1 : 0`, `invalid argument in expression "between(\"9am\", \"18:00\", \"\") ? 1 : 0": between(): invalid time of day "9am", expected HH:MM`},
+			{`cron_active("0 2 * *", "1h") ? 1 : 0`, `invalid argument in expression "cron_active(\"0 2 * *\", \"1h\") ? 1 : 0": cron_active(): invalid cron spec "0 2 * *": expected exactly 5 fields, found 4: [0 2 * *]`},
+			{`cron_active("0 2 * * *", "1x") ? 1 : 0`, `invalid argument in expression "cron_active(\"0 2 * * *\", \"1x\") ? 1 : 0": cron_active(): invalid duration "1x"`},
+		} {
+			if _, err := fas.CompileExpr(tt.expr, nil); err == nil || err.Error() != tt.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+}
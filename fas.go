@@ -15,6 +15,19 @@ var (
 	ErrExprInf      = errors.New("expression returned Inf")
 )
 
+// Reconciler errors.
+var (
+	ErrGuardrailViolation = errors.New("guardrail violation")
+)
+
+// Reconciler pool errors.
+var (
+	ErrAppNotFound = errors.New("app not found")
+	ErrNotLeader   = errors.New("not leader")
+
+	ErrAuditLogDisabled = errors.New("audit log disabled")
+)
+
 var _ FlyClient = (*fly.Client)(nil)
 
 type FlyClient interface {
@@ -34,3 +47,25 @@ type FlapsClient interface {
 }
 
 type NewFlapsClientFunc func(ctx context.Context, appName string) (FlapsClient, error)
+
+// LeaderElector represents a mechanism for electing a single leader among
+// multiple autoscaler instances. Only the leader performs reconciliation.
+type LeaderElector interface {
+	// Acquire attempts to obtain leadership or renews it if already held.
+	// Returns true if this instance is currently the leader.
+	Acquire(ctx context.Context) (bool, error)
+
+	// Release gives up leadership, if held.
+	Release(ctx context.Context) error
+}
+
+// Membership tracks the set of live autoscaler instances so that apps can be
+// sharded across them.
+type Membership interface {
+	// Heartbeat registers the instance as alive and returns the IDs of all
+	// currently live instances, including itself.
+	Heartbeat(ctx context.Context, id string) ([]string, error)
+
+	// Leave removes the instance from the membership.
+	Leave(ctx context.Context, id string) error
+}
@@ -0,0 +1,77 @@
+package filelock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+var _ fas.LeaderElector = (*LeaderElector)(nil)
+
+// LeaderElector implements fas.LeaderElector using an exclusive flock() on a
+// local file. This only works for instances sharing a filesystem so it is
+// mainly useful for testing & local development.
+type LeaderElector struct {
+	mu sync.Mutex
+	f  *os.File
+
+	// Path to the lock file. Created if it does not exist.
+	Path string
+}
+
+// NewLeaderElector returns a new instance of LeaderElector.
+func NewLeaderElector(path string) *LeaderElector {
+	return &LeaderElector{Path: path}
+}
+
+// Acquire attempts to obtain an exclusive lock on the file without blocking.
+func (e *LeaderElector) Acquire(ctx context.Context) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.f != nil {
+		return true, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(e.Path), 0o700); err != nil {
+		return false, err
+	}
+
+	f, err := os.OpenFile(e.Path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return false, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); errors.Is(err, syscall.EWOULDBLOCK) {
+		_ = f.Close()
+		return false, nil
+	} else if err != nil {
+		_ = f.Close()
+		return false, err
+	}
+
+	e.f = f
+	return true, nil
+}
+
+// Release unlocks & closes the lock file, if held.
+func (e *LeaderElector) Release(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.f == nil {
+		return nil
+	}
+	defer func() { e.f = nil }()
+
+	if err := syscall.Flock(int(e.f.Fd()), syscall.LOCK_UN); err != nil {
+		_ = e.f.Close()
+		return err
+	}
+	return e.f.Close()
+}
@@ -0,0 +1,46 @@
+package filelock_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/superfly/fly-autoscaler/filelock"
+)
+
+func TestLeaderElector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock")
+	e0, e1 := filelock.NewLeaderElector(path), filelock.NewLeaderElector(path)
+
+	if ok, err := e0.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected first elector to acquire leadership")
+	}
+
+	if ok, err := e1.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("expected second elector to not acquire leadership")
+	}
+
+	// Renewing should be a no-op for the current leader.
+	if ok, err := e0.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected first elector to retain leadership")
+	}
+
+	// Once released, the other elector can take over.
+	if err := e0.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := e1.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected second elector to acquire leadership")
+	}
+	if err := e1.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
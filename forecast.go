@@ -0,0 +1,223 @@
+package fas
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Default forecast settings.
+const (
+	DefaultForecastAlpha   = 0.5
+	DefaultForecastBeta    = 0.1
+	DefaultForecastGamma   = 0.3
+	DefaultForecastStep    = 5 * time.Minute
+	DefaultForecastRefresh = 1 * time.Hour
+)
+
+// Forecaster predicts future values of a metric using Holt-Winters
+// exponential smoothing. History is either loaded with a range query from the
+// metric's collector or, if Lookback is zero, taken from the samples stored
+// locally for the app.
+//
+// Seasonality is only modeled once at least two full seasons of history are
+// available. Until then, only the level & trend are used. Local history is too
+// short to cover a season so seasonality requires a range query.
+type Forecaster struct {
+	mu    sync.Mutex
+	cache map[string]*forecastHistory // keyed by app name
+
+	// Name of the metric to forecast.
+	Metric string
+
+	// Smoothing factors for the level, trend & seasonal components. Each must
+	// be within (0, 1]. Zero values use the defaults.
+	Alpha float64
+	Beta  float64
+	Gamma float64
+
+	// Length of a season, such as 24h for daily traffic patterns.
+	// Zero disables seasonality. Requires a Lookback period.
+	Season time.Duration
+
+	// Amount of history to load with a range query. The metric's collector
+	// must support range queries. Zero uses locally stored history instead.
+	Lookback time.Duration
+
+	// Resolution of the range query. Defaults to DefaultForecastStep.
+	Step time.Duration
+
+	// Frequency that the range query is re-run for each app.
+	// Defaults to DefaultForecastRefresh.
+	Refresh time.Duration
+}
+
+type forecastHistory struct {
+	samples   []MetricSample
+	fetchedAt time.Time
+}
+
+// Validate returns an error if the forecaster is misconfigured.
+func (f *Forecaster) Validate() error {
+	if f.Metric == "" {
+		return fmt.Errorf("forecast metric required")
+	} else if f.Alpha < 0 || f.Alpha > 1 {
+		return fmt.Errorf("forecast alpha must be within [0, 1]")
+	} else if f.Beta < 0 || f.Beta > 1 {
+		return fmt.Errorf("forecast beta must be within [0, 1]")
+	} else if f.Gamma < 0 || f.Gamma > 1 {
+		return fmt.Errorf("forecast gamma must be within [0, 1]")
+	} else if f.Season < 0 {
+		return fmt.Errorf("forecast season cannot be negative")
+	} else if f.Lookback < 0 {
+		return fmt.Errorf("forecast lookback cannot be negative")
+	} else if f.Season > 0 && f.Lookback == 0 {
+		return fmt.Errorf("forecast season requires a lookback period")
+	} else if f.Lookback > 0 && f.Season > 0 && f.Lookback < 2*f.Season {
+		return fmt.Errorf("forecast lookback must cover at least two seasons")
+	} else if f.Step < 0 {
+		return fmt.Errorf("forecast step cannot be negative")
+	} else if f.Refresh < 0 {
+		return fmt.Errorf("forecast refresh cannot be negative")
+	}
+	return nil
+}
+
+// History returns samples from a range query over the lookback period. Results
+// are cached per app until the refresh period has passed.
+func (f *Forecaster) History(ctx context.Context, c RangeMetricCollector, app string, now time.Time) ([]MetricSample, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	refresh := f.Refresh
+	if refresh == 0 {
+		refresh = DefaultForecastRefresh
+	}
+	if h := f.cache[app]; h != nil && now.Sub(h.fetchedAt) < refresh {
+		return h.samples, nil
+	}
+
+	samples, err := c.CollectMetricRange(ctx, app, now.Add(-f.Lookback), now, f.step())
+	if err != nil {
+		return nil, err
+	}
+
+	if f.cache == nil {
+		f.cache = make(map[string]*forecastHistory)
+	}
+	f.cache[app] = &forecastHistory{samples: samples, fetchedAt: now}
+	return samples, nil
+}
+
+// Forecast returns the predicted value of the metric horizon after now.
+// Samples are assumed to be evenly spaced & in time order. The forecast is
+// extended past the last sample to cover any gap until now, such as when
+// range query history is served from the cache.
+func (f *Forecaster) Forecast(samples []MetricSample, now time.Time, horizon time.Duration) (float64, error) {
+	if len(samples) == 0 {
+		return 0, fmt.Errorf("no samples for metric %q", f.Metric)
+	}
+
+	// Determine the spacing of samples so the horizon & season can be
+	// converted into a number of steps.
+	step := f.Step
+	if f.Lookback == 0 && len(samples) > 1 {
+		step = samples[len(samples)-1].Timestamp.Sub(samples[0].Timestamp) / time.Duration(len(samples)-1)
+	}
+	if step <= 0 {
+		step = f.step()
+	}
+
+	values := make([]float64, len(samples))
+	for i, sample := range samples {
+		values[i] = sample.Value
+	}
+
+	ahead := now.Add(horizon).Sub(samples[len(samples)-1].Timestamp)
+	h := max(0, int(math.Ceil(float64(ahead)/float64(step))))
+	period := int(f.Season / step)
+	return holtWinters(values, period, h, f.alpha(), f.beta(), f.gamma()), nil
+}
+
+func (f *Forecaster) step() time.Duration {
+	if f.Step == 0 {
+		return DefaultForecastStep
+	}
+	return f.Step
+}
+
+func (f *Forecaster) alpha() float64 { return defaultFloat(f.Alpha, DefaultForecastAlpha) }
+func (f *Forecaster) beta() float64  { return defaultFloat(f.Beta, DefaultForecastBeta) }
+func (f *Forecaster) gamma() float64 { return defaultFloat(f.Gamma, DefaultForecastGamma) }
+
+func defaultFloat(v, def float64) float64 {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// holtWinters returns the additive Holt-Winters forecast h steps after the last
+// value. If there are fewer than two seasons of values, the seasonal component
+// is ignored & Holt's linear method is used.
+func holtWinters(values []float64, period, h int, alpha, beta, gamma float64) float64 {
+	if len(values) == 1 {
+		return values[0]
+	}
+
+	if period < 2 || len(values) < 2*period {
+		level, trend := values[0], values[1]-values[0]
+		for _, v := range values[1:] {
+			prev := level
+			level = alpha*v + (1-alpha)*(level+trend)
+			trend = beta*(level-prev) + (1-beta)*trend
+		}
+		return level + float64(h)*trend
+	}
+
+	// Initialize from the first two seasons. Seasonal indices are relative to
+	// the level of the first season.
+	level := mean(values[:period])
+	trend := (mean(values[period:2*period]) - level) / float64(period)
+	season := make([]float64, period)
+	for i := range season {
+		season[i] = values[i] - level
+	}
+
+	for t := period; t < len(values); t++ {
+		v, s := values[t], season[t%period]
+		prev := level
+		level = alpha*(v-s) + (1-alpha)*(level+trend)
+		trend = beta*(level-prev) + (1-beta)*trend
+		season[t%period] = gamma*(v-level) + (1-gamma)*s
+	}
+
+	return level + float64(h)*trend + season[(len(values)-1+h)%period]
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// forecastFunc returns the forecast(metric, horizon) expression function. The
+// metric is passed by name, as with the history functions.
+func forecastFunc(v *ExprVars) func(name, horizon string) (float64, error) {
+	return func(name, horizon string) (float64, error) {
+		d, err := time.ParseDuration(horizon)
+		if err != nil {
+			return 0, fmt.Errorf("invalid horizon %q", horizon)
+		}
+
+		if v.Forecast != nil {
+			return v.Forecast(name, d)
+		}
+		f := &Forecaster{Metric: name}
+		return f.Forecast(v.History[name], v.Now, d)
+	}
+}
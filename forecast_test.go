@@ -0,0 +1,174 @@
+package fas_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/mock"
+)
+
+func TestForecaster_Forecast(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// samples returns n samples, one minute apart, generated by fn.
+	samples := func(n int, fn func(i int) float64) []fas.MetricSample {
+		a := make([]fas.MetricSample, n)
+		for i := range a {
+			a[i] = fas.MetricSample{Timestamp: start.Add(time.Duration(i) * time.Minute), Value: fn(i)}
+		}
+		return a
+	}
+
+	t.Run("Trend", func(t *testing.T) {
+		f := &fas.Forecaster{Metric: "requests"}
+		v, err := f.Forecast(samples(20, func(i int) float64 { return float64(10 * i) }), start.Add(19*time.Minute), 10*time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := v, 290.0; math.Abs(got-want) > 1e-9 {
+			t.Fatalf("Forecast=%v, want %v", got, want)
+		}
+	})
+
+	// The horizon is measured from now, not the last sample, so stale cached
+	// history is forecast further ahead.
+	t.Run("StaleHistory", func(t *testing.T) {
+		f := &fas.Forecaster{Metric: "requests"}
+		v, err := f.Forecast(samples(20, func(i int) float64 { return float64(10 * i) }), start.Add(49*time.Minute), 10*time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := v, 590.0; math.Abs(got-want) > 1e-9 {
+			t.Fatalf("Forecast=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Seasonal", func(t *testing.T) {
+		// Repeats 0, 100, 200, 300 every four minutes.
+		f := &fas.Forecaster{Metric: "requests", Season: 4 * time.Minute}
+		values := samples(12, func(i int) float64 { return float64(100 * (i % 4)) })
+
+		// The last sample is at the end of a season so a two minute forecast
+		// should return the second value in the season.
+		v, err := f.Forecast(values, start.Add(11*time.Minute), 2*time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := v, 100.0; math.Abs(got-want) > 1e-9 {
+			t.Fatalf("Forecast=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("SeasonalInsufficientHistory", func(t *testing.T) {
+		f := &fas.Forecaster{Metric: "requests", Season: 1 * time.Hour}
+		v, err := f.Forecast(samples(3, func(i int) float64 { return 50 }), start.Add(2*time.Minute), 10*time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := v, 50.0; got != want {
+			t.Fatalf("Forecast=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrNoSamples", func(t *testing.T) {
+		f := &fas.Forecaster{Metric: "requests"}
+		if _, err := f.Forecast(nil, start, time.Minute); err == nil || err.Error() != `no samples for metric "requests"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestForecaster_History(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var queryN int
+	c := mock.NewRangeMetricCollector("requests")
+	c.CollectMetricRangeFunc = func(ctx context.Context, app string, start, end time.Time, step time.Duration) ([]fas.MetricSample, error) {
+		queryN++
+		if got, want := app, "my-app"; got != want {
+			t.Fatalf("app=%v, want %v", got, want)
+		} else if got, want := end.Sub(start), 48*time.Hour; got != want {
+			t.Fatalf("range=%v, want %v", got, want)
+		} else if got, want := step, fas.DefaultForecastStep; got != want {
+			t.Fatalf("step=%v, want %v", got, want)
+		}
+		return []fas.MetricSample{{Timestamp: end, Value: 1}}, nil
+	}
+
+	f := &fas.Forecaster{Metric: "requests", Lookback: 48 * time.Hour, Refresh: 10 * time.Minute}
+	for _, d := range []time.Duration{0, 5 * time.Minute, 15 * time.Minute} {
+		if _, err := f.History(context.Background(), c, "my-app", now.Add(d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second call should be served from the cache.
+	if got, want := queryN, 2; got != want {
+		t.Fatalf("queryN=%v, want %v", got, want)
+	}
+}
+
+func TestExpr_Forecast(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 10, 0, 0, time.UTC)
+
+	t.Run("LocalHistory", func(t *testing.T) {
+		e, err := fas.CompileExpr(`forecast(queue_depth, "2m")`, []string{"queue_depth"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		vars := &fas.ExprVars{
+			History: map[string][]fas.MetricSample{
+				"queue_depth": {
+					{Timestamp: now.Add(-2 * time.Minute), Value: 10},
+					{Timestamp: now.Add(-1 * time.Minute), Value: 20},
+					{Timestamp: now, Value: 30},
+				},
+			},
+			Now: now,
+		}
+		if v, err := e.Eval(map[string]float64{"queue_depth": 30}, vars); err != nil {
+			t.Fatal(err)
+		} else if got, want := v, 50.0; math.Abs(got-want) > 1e-9 {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrInvalidHorizon", func(t *testing.T) {
+		_, err := fas.CompileExpr(`forecast(queue_depth, "10")`, []string{"queue_depth"})
+		if err == nil || err.Error() != `invalid argument in expression "forecast(queue_depth, \"10\")": forecast(): invalid horizon "10"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestPredictivePolicy_Desired(t *testing.T) {
+	p := &fas.PredictivePolicy{Metric: "requests", Horizon: 10 * time.Minute, Target: 100, MinN: 1, MaxN: 10}
+	for _, tt := range []struct {
+		value    float64
+		forecast float64
+		want     int
+	}{
+		{150, 450, 5}, // scales ahead of forecast peak
+		{450, 150, 5}, // does not scale down before load drops
+		{0, 0, 1},     // min
+		{0, 5000, 10}, // max
+		{250, 250, 3}, // rounds up
+		{-10, -50, 1}, // negative forecast
+	} {
+		n, err := p.Desired(&fas.PolicyInput{
+			Metrics: map[string]float64{"requests": tt.value},
+			State:   fas.NewAppState("my-app"),
+			Forecast: func(name string, horizon time.Duration) (float64, error) {
+				if got, want := horizon, 10*time.Minute; got != want {
+					t.Fatalf("horizon=%v, want %v", got, want)
+				}
+				return tt.forecast, nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := n, tt.want; got != want {
+			t.Fatalf("Desired(%v, %v)=%v, want %v", tt.value, tt.forecast, got, want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package fas
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+)
+
+// Guardrail represents absolute bounds on the number of machines that are
+// enforced regardless of the results of expressions, policies & schedules.
+// This protects against acting on bad metric values, such as a counter reset
+// that causes an expression to request a huge number of machines.
+//
+// A guardrail without a region bounds the machine count targets for the app.
+// A guardrail with a region bounds the number of machines in that region and
+// is enforced as machines are created, destroyed, started & stopped.
+type Guardrail struct {
+	// Wildcard pattern of app names the guardrail applies to.
+	// A blank pattern matches all apps.
+	App string
+
+	// Region the guardrail applies to. Blank applies to the whole app.
+	Region string
+
+	// Bounds on the number of machines. Zero MaxN means no maximum.
+	MinN int
+	MaxN int
+
+	// Compiled App pattern. Set on first match.
+	appRe *regexp.Regexp
+}
+
+// Validate returns an error if the guardrail is misconfigured.
+func (g *Guardrail) Validate() error {
+	if g.MinN < 0 {
+		return fmt.Errorf("guardrail min machines cannot be negative")
+	} else if g.MaxN < 0 {
+		return fmt.Errorf("guardrail max machines cannot be negative")
+	} else if g.MaxN != 0 && g.MaxN < g.MinN {
+		return fmt.Errorf("guardrail max machines cannot be less than min machines")
+	} else if g.MinN == 0 && g.MaxN == 0 {
+		return fmt.Errorf("guardrail must set min or max machines")
+	} else if _, err := regexp.Compile(FormatWildcardAsRegexp(g.App)); err != nil {
+		return fmt.Errorf("invalid guardrail app pattern %q", g.App)
+	}
+	return nil
+}
+
+// Matches returns true if the guardrail applies to the given app. The app
+// pattern is compiled on the first call & reused afterward.
+func (g *Guardrail) Matches(appName string) bool {
+	if g.appRe == nil {
+		re, err := regexp.Compile(FormatWildcardAsRegexp(g.App))
+		if err != nil {
+			return false
+		}
+		g.appRe = re
+	}
+	return g.appRe.MatchString(appName)
+}
+
+// guardrailBounds returns the highest minimum & lowest maximum of all
+// guardrails for the current app & region. Zero maxN means no maximum.
+func (r *Reconciler) guardrailBounds(region string) (minN, maxN int) {
+	for i := range r.Guardrails {
+		g := &r.Guardrails[i]
+		if g.Region != region || !g.Matches(r.AppName) {
+			continue
+		}
+
+		minN = max(minN, g.MinN)
+		if g.MaxN > 0 && (maxN == 0 || g.MaxN < maxN) {
+			maxN = g.MaxN
+		}
+	}
+	return minN, maxN
+}
+
+// ApplyGuardrails clamps the targets on d to the app's guardrails. Each target
+// outside of the guardrails is logged & counted as a violation. If
+// GuardrailStrict is set, the first violation is returned as an error.
+func (r *Reconciler) ApplyGuardrails(d *Decision) error {
+	minN, maxN := r.guardrailBounds("")
+	for _, target := range []struct {
+		name string
+		v    *int
+	}{
+		{"min created", d.MinCreatedN},
+		{"max created", d.MaxCreatedN},
+		{"min started", d.MinStartedN},
+		{"max started", d.MaxStartedN},
+	} {
+		if target.v == nil {
+			continue
+		}
+
+		if *target.v < minN {
+			if err := r.guardrailViolation(fmt.Sprintf("%s machine count %d below min machines %d", target.name, *target.v, minN)); err != nil {
+				return err
+			}
+			*target.v = minN
+		}
+		if maxN > 0 && *target.v > maxN {
+			if err := r.guardrailViolation(fmt.Sprintf("%s machine count %d exceeds max machines %d", target.name, *target.v, maxN)); err != nil {
+				return err
+			}
+			*target.v = maxN
+		}
+	}
+	return nil
+}
+
+// guardrailViolation logs & counts a violation. Returns an error wrapping
+// ErrGuardrailViolation if guardrails are strict.
+func (r *Reconciler) guardrailViolation(msg string) error {
+	r.Stats.GuardrailViolation.Add(1)
+	slog.Warn("guardrail violation",
+		slog.String("app", r.AppName),
+		slog.String("reason", msg),
+		slog.Bool("strict", r.GuardrailStrict))
+
+	if r.GuardrailStrict {
+		return fmt.Errorf("%w: %s", ErrGuardrailViolation, msg)
+	}
+	return nil
+}
+
+// regionGuardrail tracks machine counts per region during a bulk action so
+// that machines are not added to or removed from a region beyond its bounds.
+type regionGuardrail struct {
+	r      *Reconciler
+	counts map[string]int
+}
+
+func (r *Reconciler) newRegionGuardrail(counts map[string]int) *regionGuardrail {
+	other := maps.Clone(counts)
+	if other == nil {
+		other = make(map[string]int)
+	}
+	return &regionGuardrail{r: r, counts: other}
+}
+
+// canAdd returns true if a machine can be added to the region.
+func (g *regionGuardrail) canAdd(region string) bool {
+	_, maxN := g.r.guardrailBounds(region)
+	return maxN == 0 || g.counts[region] < maxN
+}
+
+// canRemove returns true if a machine can be removed from the region.
+func (g *regionGuardrail) canRemove(region string) bool {
+	minN, _ := g.r.guardrailBounds(region)
+	return minN == 0 || g.counts[region] > minN
+}
+
+// violation records a violation if n machines could not be added or removed
+// because of region guardrails.
+func (g *regionGuardrail) violation(action string, n int) error {
+	return g.r.guardrailViolation(fmt.Sprintf("cannot %s %d machines without violating region guardrails", action, n))
+}
+
+func (g *regionGuardrail) add(region string)    { g.counts[region]++ }
+func (g *regionGuardrail) remove(region string) { g.counts[region]-- }
@@ -0,0 +1,113 @@
+package fas_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	fas "github.com/superfly/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/mock"
+	"github.com/superfly/fly-go"
+)
+
+func TestReconciler_ApplyGuardrails(t *testing.T) {
+	t.Run("Clamp", func(t *testing.T) {
+		r := fas.NewReconciler()
+		r.AppName = "my-app"
+		r.Guardrails = []fas.Guardrail{
+			{MaxN: 50},
+			{App: "my-*", MinN: 2},
+			{App: "other-app", MaxN: 1},
+			{Region: "iad", MaxN: 1}, // region guardrails do not bound targets
+		}
+
+		d := &fas.Decision{MinStartedN: ptr(0), MaxStartedN: ptr(1_000_000_000)}
+		if err := r.ApplyGuardrails(d); err != nil {
+			t.Fatal(err)
+		} else if got, want := *d.MinStartedN, 2; got != want {
+			t.Fatalf("MinStartedN=%v, want %v", got, want)
+		} else if got, want := *d.MaxStartedN, 50; got != want {
+			t.Fatalf("MaxStartedN=%v, want %v", got, want)
+		} else if d.MinCreatedN != nil || d.MaxCreatedN != nil {
+			t.Fatal("expected created machine counts to be unset")
+		} else if got, want := r.Stats.GuardrailViolation.Load(), int64(2); got != want {
+			t.Fatalf("GuardrailViolation=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		r := fas.NewReconciler()
+		r.Guardrails = []fas.Guardrail{{MaxN: 50}}
+		r.GuardrailStrict = true
+
+		d := &fas.Decision{MinStartedN: ptr(1_000_000_000), MaxStartedN: ptr(1_000_000_000)}
+		if err := r.ApplyGuardrails(d); !errors.Is(err, fas.ErrGuardrailViolation) {
+			t.Fatalf("unexpected error: %v", err)
+		} else if got, want := err.Error(), `guardrail violation: min started machine count 1000000000 exceeds max machines 50`; got != want {
+			t.Fatalf("error=%v, want %v", got, want)
+		}
+	})
+}
+
+func TestReconciler_Reconcile_Guardrails(t *testing.T) {
+	newClient := func(startN *int) *mock.FlapsClient {
+		var client mock.FlapsClient
+		client.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+			return []*fly.Machine{
+				{ID: "1", State: fly.MachineStateStarted, Region: "iad", HostStatus: fly.HostStatusOk},
+				{ID: "2", State: fly.MachineStateStopped, Region: "iad", HostStatus: fly.HostStatusOk},
+				{ID: "3", State: fly.MachineStateStopped, Region: "ord", HostStatus: fly.HostStatusOk},
+				{ID: "4", State: fly.MachineStateStopped, Region: "ord", HostStatus: fly.HostStatusOk},
+			}, nil
+		}
+		client.StartFunc = func(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+			*startN++
+			return &fly.MachineStartResponse{}, nil
+		}
+		return &client
+	}
+
+	t.Run("MaxMachines", func(t *testing.T) {
+		var startN int
+		r := fas.NewReconciler()
+		r.Client = newClient(&startN)
+		r.MinStartedMachineN, r.MaxStartedMachineN = "1e9", "1e9"
+		r.Guardrails = []fas.Guardrail{{MaxN: 2}}
+		if err := r.Reconcile(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := startN, 1; got != want {
+			t.Fatalf("startN=%v, want %v", got, want)
+		} else if got, want := *r.State.LastDecision.MaxStartedN, 2; got != want {
+			t.Fatalf("MaxStartedN=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		var startN int
+		r := fas.NewReconciler()
+		r.Client = newClient(&startN)
+		r.MinStartedMachineN, r.MaxStartedMachineN = "1e9", "1e9"
+		r.Guardrails = []fas.Guardrail{{MaxN: 2}}
+		r.GuardrailStrict = true
+		if err := r.Reconcile(context.Background()); !errors.Is(err, fas.ErrGuardrailViolation) {
+			t.Fatalf("unexpected error: %v", err)
+		} else if got, want := startN, 0; got != want {
+			t.Fatalf("startN=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("RegionMaxMachines", func(t *testing.T) {
+		var startN int
+		r := fas.NewReconciler()
+		r.Client = newClient(&startN)
+		r.MinStartedMachineN, r.MaxStartedMachineN = "4", "4"
+		r.Guardrails = []fas.Guardrail{{Region: "iad", MaxN: 1}}
+		if err := r.Reconcile(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := startN, 2; got != want { // only "ord" machines
+			t.Fatalf("startN=%v, want %v", got, want)
+		} else if got, want := r.Stats.GuardrailViolation.Load(), int64(1); got != want {
+			t.Fatalf("GuardrailViolation=%v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+// DefaultURL is the default base URL used by the client.
+const DefaultURL = "http://localhost" + DefaultAddr
+
+// Client represents a client for the autoscaler's HTTP API.
+type Client struct {
+	// Base URL of the autoscaler server.
+	URL string
+
+	// Bearer token sent with each request, if set.
+	Token string
+
+	HTTPClient *http.Client
+}
+
+// NewClient returns a new instance of Client.
+func NewClient(u string) *Client {
+	return &Client{
+		URL:        u,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// History returns audit events from the server that match filter.
+func (c *Client) History(ctx context.Context, filter fas.AuditFilter) ([]*fas.AuditEvent, error) {
+	q := make(url.Values)
+	if filter.App != "" {
+		q.Set("app", filter.App)
+	}
+	if filter.Action != "" {
+		q.Set("action", filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		q.Set("since", filter.Since.Format(time.RFC3339Nano))
+	}
+	if !filter.Until.IsZero() {
+		q.Set("until", filter.Until.Format(time.RFC3339Nano))
+	}
+	if filter.Limit > 0 {
+		q.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	var resp historyResponse
+	if err := c.do(ctx, "GET", "/v1/history?"+q.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+// Pause pauses scaling of an app until ttl has elapsed.
+func (c *Client) Pause(ctx context.Context, app string, ttl time.Duration) (*fas.Override, error) {
+	var resp overrideResponse
+	if err := c.do(ctx, "POST", "/v1/apps/"+url.PathEscape(app)+"/pause", &overrideRequest{TTL: ttl.String()}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Override, nil
+}
+
+// Resume removes any pause or override from an app.
+func (c *Client) Resume(ctx context.Context, app string) error {
+	var resp overrideResponse
+	return c.do(ctx, "POST", "/v1/apps/"+url.PathEscape(app)+"/resume", nil, &resp)
+}
+
+// Override fixes an app's created and/or started machine counts until ttl
+// has elapsed. Nil counts are computed as usual.
+func (c *Client) Override(ctx context.Context, app string, createdN, startedN *int, ttl time.Duration) (*fas.Override, error) {
+	req := &overrideRequest{CreatedN: createdN, StartedN: startedN, TTL: ttl.String()}
+
+	var resp overrideResponse
+	if err := c.do(ctx, "POST", "/v1/apps/"+url.PathEscape(app)+"/override", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Override, nil
+}
+
+// do sends a request to the server & decodes the JSON response into v. If
+// body is non-nil, it is encoded as the JSON request body.
+func (c *Client) do(ctx context.Context, method, path string, body, v any) error {
+	var r io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.URL, "/")+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp errorResponse
+		if buf, _ := io.ReadAll(resp.Body); json.Unmarshal(buf, &errResp) == nil && errResp.Error != "" {
+			return fmt.Errorf("%s (status=%d)", errResp.Error, resp.StatusCode)
+		}
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
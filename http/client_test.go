@@ -0,0 +1,98 @@
+package http_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	fashttp "github.com/superfly/fly-autoscaler/http"
+)
+
+func TestClient_History(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		l := fas.NewFileAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+		if err := l.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = l.Close() }()
+
+		timestamp := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+		for i, action := range []string{fas.ActionStart, fas.ActionStop, fas.ActionStart} {
+			e := &fas.AuditEvent{App: "my-app"}
+			e.Timestamp = timestamp.Add(time.Duration(i) * time.Hour)
+			e.Action = action
+			if err := l.WriteAuditEvent(context.Background(), e); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		p := newOpenReconcilerPool(t, "my-app")
+		p.AuditLog = l
+		server := httptest.NewServer(fashttp.NewServer(p))
+		defer server.Close()
+
+		client := fashttp.NewClient(server.URL)
+		events, err := client.History(context.Background(), fas.AuditFilter{
+			Action: fas.ActionStart,
+			Since:  timestamp.Add(time.Minute),
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := len(events), 1; got != want {
+			t.Fatalf("len=%v, want %v", got, want)
+		} else if got, want := events[0].Timestamp, timestamp.Add(2*time.Hour); !got.Equal(want) {
+			t.Fatalf("Timestamp=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrDisabled", func(t *testing.T) {
+		server := httptest.NewServer(fashttp.NewServer(newOpenReconcilerPool(t, "my-app")))
+		defer server.Close()
+
+		client := fashttp.NewClient(server.URL)
+		if _, err := client.History(context.Background(), fas.AuditFilter{}); err == nil || err.Error() != `audit log disabled (status=501)` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestClient_Override(t *testing.T) {
+	p := newOpenReconcilerPool(t, "my-app")
+	s := fashttp.NewServer(p)
+	s.Token = "secret"
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	client := fashttp.NewClient(server.URL)
+	client.Token = "secret"
+
+	if o, err := client.Pause(context.Background(), "my-app", 2*time.Hour); err != nil {
+		t.Fatal(err)
+	} else if !o.Paused {
+		t.Fatal("expected pause")
+	} else if got, want := o.ExpiresAt.Sub(o.CreatedAt), 2*time.Hour; got != want {
+		t.Fatalf("ttl=%v, want %v", got, want)
+	}
+
+	startedN := 2
+	if o, err := client.Override(context.Background(), "my-app", nil, &startedN, time.Hour); err != nil {
+		t.Fatal(err)
+	} else if o.CreatedN != nil || *o.StartedN != 2 {
+		t.Fatalf("unexpected override: %#v", o)
+	}
+
+	if err := client.Resume(context.Background(), "my-app"); err != nil {
+		t.Fatal(err)
+	} else if status, err := p.AppStatus("my-app"); err != nil {
+		t.Fatal(err)
+	} else if status.Override != nil {
+		t.Fatalf("unexpected override: %#v", status.Override)
+	}
+
+	if err := client.Resume(context.Background(), "other-app"); err == nil || err.Error() != `app not found (status=404)` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package http
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// dashboardHTML is a single page that renders the status API for people who
+// do not have access to Grafana. Data is fetched by the page from the API.
+//
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// handleGetIndex redirects to the dashboard.
+func (s *Server) handleGetIndex(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, "/dashboard", http.StatusFound)
+}
+
+// handleGetDashboard serves the embedded dashboard page. The page itself is
+// not authenticated. If a token is configured, the page prompts for it before
+// calling the API.
+func (s *Server) handleGetDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(dashboardHTML)
+}
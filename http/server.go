@@ -0,0 +1,383 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	_ "net/http/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+// DefaultAddr is the default address the server listens on.
+const DefaultAddr = ":9090"
+
+// Server represents an HTTP server that exposes metrics and the API for
+// interacting with the autoscaler.
+type Server struct {
+	ln         net.Listener
+	httpServer *http.Server
+
+	pool *fas.ReconcilerPool
+
+	// Address to listen on. Must be set before calling Open().
+	Addr string
+
+	// Bearer token required by authenticated endpoints. If blank, then
+	// endpoints that change state are disabled & read-only admin endpoints,
+	// such as status & history, are served without authentication.
+	Token string
+}
+
+// NewServer returns a new instance of Server.
+func NewServer(pool *fas.ReconcilerPool) *Server {
+	s := &Server{
+		pool: pool,
+		Addr: DefaultAddr,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.Handler())
+	mux.HandleFunc("GET /v1/apps", s.optionalAuth(s.handleGetApps))
+	mux.HandleFunc("GET /v1/apps/{app}", s.optionalAuth(s.handleGetApp))
+	mux.HandleFunc("GET /v1/apps/{app}/samples", s.optionalAuth(s.handleGetAppSamples))
+	mux.HandleFunc("POST /v1/apps/{app}/reconcile", s.requireAuth(s.handlePostReconcile))
+	mux.HandleFunc("POST /v1/apps/{app}/pause", s.requireAuth(s.handlePostPause))
+	mux.HandleFunc("POST /v1/apps/{app}/resume", s.requireAuth(s.handlePostResume))
+	mux.HandleFunc("POST /v1/apps/{app}/override", s.requireAuth(s.handlePostOverride))
+	mux.HandleFunc("GET /v1/collectors", s.optionalAuth(s.handleGetCollectors))
+	mux.HandleFunc("GET /v1/history", s.optionalAuth(s.handleGetHistory))
+	mux.HandleFunc("GET /{$}", s.handleGetIndex)
+	mux.HandleFunc("GET /dashboard", s.handleGetDashboard)
+	mux.Handle("/debug/", http.DefaultServeMux) // pprof
+	s.httpServer = &http.Server{Handler: mux}
+
+	return s
+}
+
+// Open begins listening on the server's address and serving requests in a
+// separate goroutine.
+func (s *Server) Open() (err error) {
+	if s.ln, err = net.Listen("tcp", s.Addr); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := s.httpServer.Serve(s.ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("http server error", slog.Any("err", err))
+		}
+	}()
+
+	return nil
+}
+
+// Close gracefully shuts down the server.
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.httpServer.Shutdown(ctx)
+}
+
+// URL returns the base URL of the server. Only valid after Open().
+func (s *Server) URL() string {
+	return "http://" + s.ln.Addr().String()
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.httpServer.Handler.ServeHTTP(w, r)
+}
+
+// handleGetApps returns the status of every app managed by this instance.
+func (s *Server) handleGetApps(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, appsResponse{
+		Leader:   s.pool.IsLeader(),
+		QueueLag: s.pool.QueueLag().Seconds(),
+		Apps:     s.pool.AppStatuses(),
+	})
+}
+
+type appsResponse struct {
+	Leader   bool             `json:"leader"`
+	QueueLag float64          `json:"queue_lag_seconds"`
+	Apps     []*fas.AppStatus `json:"apps"`
+}
+
+// handleGetApp returns the status of a single app.
+func (s *Server) handleGetApp(w http.ResponseWriter, r *http.Request) {
+	status, err := s.pool.AppStatus(r.PathValue("app"))
+	if errors.Is(err, fas.ErrAppNotFound) {
+		writeError(w, r, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, status)
+}
+
+// handleGetAppSamples returns an app's recent machine counts & targets.
+func (s *Server) handleGetAppSamples(w http.ResponseWriter, r *http.Request) {
+	samples, err := s.pool.AppSamples(r.PathValue("app"))
+	if errors.Is(err, fas.ErrAppNotFound) {
+		writeError(w, r, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, samplesResponse{Samples: samples})
+}
+
+type samplesResponse struct {
+	Samples []fas.AppSample `json:"samples"`
+}
+
+// handleGetCollectors returns the health of each metric collector.
+func (s *Server) handleGetCollectors(w http.ResponseWriter, r *http.Request) {
+	collectors := s.pool.CollectorHealth()
+
+	a := make([]collectorResponse, len(collectors))
+	for i, c := range collectors {
+		a[i] = collectorResponse{CollectorHealth: c, Healthy: c.Healthy()}
+	}
+	writeJSON(w, r, http.StatusOK, collectorsResponse{Collectors: a})
+}
+
+type collectorsResponse struct {
+	Collectors []collectorResponse `json:"collectors"`
+}
+
+type collectorResponse struct {
+	*fas.CollectorHealth
+	Healthy bool `json:"healthy"`
+}
+
+// handlePostReconcile enqueues an immediate reconciliation of a single app.
+func (s *Server) handlePostReconcile(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("app")
+
+	queued, err := s.pool.Trigger(name)
+	if errors.Is(err, fas.ErrAppNotFound) {
+		writeError(w, r, http.StatusNotFound, err)
+		return
+	} else if errors.Is(err, fas.ErrNotLeader) {
+		writeError(w, r, http.StatusServiceUnavailable, err)
+		return
+	} else if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusAccepted, reconcileResponse{
+		App:    name,
+		Queued: queued,
+	})
+}
+
+type reconcileResponse struct {
+	App    string `json:"app"`
+	Queued bool   `json:"queued"` // false if already pending
+}
+
+// handlePostPause pauses scaling of a single app until the TTL expires.
+func (s *Server) handlePostPause(w http.ResponseWriter, r *http.Request) {
+	_, ttl, err := readOverrideRequest(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	s.setOverride(w, r, fas.NewPauseOverride(time.Now(), ttl))
+}
+
+// handlePostOverride fixes the machine counts of a single app until the TTL
+// expires.
+func (s *Server) handlePostOverride(w http.ResponseWriter, r *http.Request) {
+	req, ttl, err := readOverrideRequest(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	s.setOverride(w, r, fas.NewMachineOverride(time.Now(), req.CreatedN, req.StartedN, ttl))
+}
+
+func (s *Server) setOverride(w http.ResponseWriter, r *http.Request, o *fas.Override) {
+	name := r.PathValue("app")
+	if err := o.Validate(); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := s.pool.SetOverride(r.Context(), name, o); err != nil {
+		writeOverrideError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, overrideResponse{App: name, Override: o})
+}
+
+// handlePostResume removes any pause or override from a single app.
+func (s *Server) handlePostResume(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("app")
+	if err := s.pool.ClearOverride(r.Context(), name); err != nil {
+		writeOverrideError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, overrideResponse{App: name})
+}
+
+func writeOverrideError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, fas.ErrAppNotFound) {
+		writeError(w, r, http.StatusNotFound, err)
+	} else if errors.Is(err, fas.ErrNotLeader) {
+		writeError(w, r, http.StatusServiceUnavailable, err)
+	} else {
+		writeError(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+type overrideRequest struct {
+	CreatedN *int   `json:"created,omitempty"`
+	StartedN *int   `json:"started,omitempty"`
+	TTL      string `json:"ttl,omitempty"` // defaults to fas.DefaultOverrideTTL
+}
+
+// readOverrideRequest decodes an optional override request body & its TTL.
+func readOverrideRequest(r *http.Request) (*overrideRequest, time.Duration, error) {
+	var req overrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return nil, 0, fmt.Errorf("invalid request body: %w", err)
+	}
+
+	if req.TTL == "" {
+		return &req, fas.DefaultOverrideTTL, nil
+	}
+	ttl, err := time.ParseDuration(req.TTL)
+	if err != nil || ttl <= 0 {
+		return nil, 0, fmt.Errorf("invalid ttl: %q", req.TTL)
+	}
+	return &req, ttl, nil
+}
+
+type overrideResponse struct {
+	App      string        `json:"app"`
+	Override *fas.Override `json:"override"` // nil after resuming
+}
+
+// handleGetHistory returns audit events matching the query parameters. The
+// "since" & "until" parameters accept an RFC 3339 time or a duration before now.
+func (s *Server) handleGetHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := fas.AuditFilter{
+		App:    q.Get("app"),
+		Action: q.Get("action"),
+	}
+
+	var err error
+	if filter.Since, err = parseTimeParam(q.Get("since")); err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("invalid since: %w", err))
+		return
+	}
+	if filter.Until, err = parseTimeParam(q.Get("until")); err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("invalid until: %w", err))
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 0 {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("invalid limit: %q", v))
+			return
+		}
+	}
+
+	events, err := s.pool.AuditEvents(r.Context(), filter)
+	if errors.Is(err, fas.ErrAuditLogDisabled) {
+		writeError(w, r, http.StatusNotImplemented, err)
+		return
+	} else if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if events == nil {
+		events = []*fas.AuditEvent{}
+	}
+	writeJSON(w, r, http.StatusOK, historyResponse{Events: events})
+}
+
+type historyResponse struct {
+	Events []*fas.AuditEvent `json:"events"`
+}
+
+// parseTimeParam parses s as an RFC 3339 time or as a duration before now.
+// Returns the zero time if s is blank.
+func parseTimeParam(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	} else if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// requireAuth wraps a handler to require a matching bearer token.
+func (s *Server) requireAuth(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Token == "" {
+			writeError(w, r, http.StatusForbidden, errors.New("endpoint disabled, no auth token configured"))
+			return
+		}
+
+		if !s.isAuthorized(r) {
+			writeError(w, r, http.StatusUnauthorized, errors.New("invalid auth token"))
+			return
+		}
+
+		fn(w, r)
+	}
+}
+
+// optionalAuth wraps a handler to require a matching bearer token only if a
+// token is configured.
+func (s *Server) optionalAuth(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Token != "" && !s.isAuthorized(r) {
+			writeError(w, r, http.StatusUnauthorized, errors.New("invalid auth token"))
+			return
+		}
+
+		fn(w, r)
+	}
+}
+
+// isAuthorized returns true if the request has a bearer token matching Token.
+func (s *Server) isAuthorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("cannot write http response",
+			slog.String("path", r.URL.Path),
+			slog.Any("err", err))
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, code int, err error) {
+	writeJSON(w, r, code, errorResponse{Error: err.Error()})
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
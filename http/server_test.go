@@ -0,0 +1,385 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	fashttp "github.com/superfly/fly-autoscaler/http"
+	"github.com/superfly/fly-autoscaler/mock"
+	fly "github.com/superfly/fly-go"
+)
+
+func TestServer_PostReconcile(t *testing.T) {
+	p := newOpenReconcilerPool(t, "my-app")
+	s := fashttp.NewServer(p)
+	s.Token = "secret"
+
+	t.Run("OK", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/v1/apps/my-app/reconcile", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusAccepted; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrNotFound", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/v1/apps/other-app/reconcile", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusNotFound; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrUnauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/v1/apps/my-app/reconcile", nil)
+		r.Header.Set("Authorization", "Bearer wrong")
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusUnauthorized; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrNoToken", func(t *testing.T) {
+		s := fashttp.NewServer(p)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/v1/apps/my-app/reconcile", nil)
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusForbidden; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+}
+
+func TestServer_PostOverride(t *testing.T) {
+	p := newOpenReconcilerPool(t, "my-app")
+	s := fashttp.NewServer(p)
+	s.Token = "secret"
+
+	t.Run("Pause", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/v1/apps/my-app/pause", strings.NewReader(`{"ttl":"30m"}`))
+		r.Header.Set("Authorization", "Bearer secret")
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+
+		status, err := p.AppStatus("my-app")
+		if err != nil {
+			t.Fatal(err)
+		} else if o := status.Override; o == nil || !o.Paused {
+			t.Fatalf("unexpected override: %#v", o)
+		} else if got, want := o.ExpiresAt.Sub(o.CreatedAt), 30*time.Minute; got != want {
+			t.Fatalf("ttl=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Override", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/v1/apps/my-app/override", strings.NewReader(`{"started":3}`))
+		r.Header.Set("Authorization", "Bearer secret")
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+
+		status, err := p.AppStatus("my-app")
+		if err != nil {
+			t.Fatal(err)
+		} else if o := status.Override; o == nil || o.Paused || *o.StartedN != 3 {
+			t.Fatalf("unexpected override: %#v", o)
+		} else if got, want := o.ExpiresAt.Sub(o.CreatedAt), fas.DefaultOverrideTTL; got != want {
+			t.Fatalf("ttl=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/v1/apps/my-app/resume", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+
+		if status, err := p.AppStatus("my-app"); err != nil {
+			t.Fatal(err)
+		} else if status.Override != nil {
+			t.Fatalf("unexpected override: %#v", status.Override)
+		}
+	})
+
+	t.Run("ErrNoCount", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/v1/apps/my-app/override", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusBadRequest; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrInvalidTTL", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/v1/apps/my-app/pause", strings.NewReader(`{"ttl":"-1h"}`))
+		r.Header.Set("Authorization", "Bearer secret")
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusBadRequest; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrNotFound", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/v1/apps/other-app/pause", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusNotFound; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrUnauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/v1/apps/my-app/pause", nil)
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusUnauthorized; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+}
+
+func TestServer_GetApps(t *testing.T) {
+	p := newOpenReconcilerPool(t, "my-app")
+
+	t.Run("OK", func(t *testing.T) {
+		s := fashttp.NewServer(p)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/v1/apps", nil)
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+
+		var resp struct {
+			Leader bool             `json:"leader"`
+			Apps   []*fas.AppStatus `json:"apps"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		} else if got, want := resp.Leader, true; got != want {
+			t.Fatalf("Leader=%v, want %v", got, want)
+		} else if got, want := len(resp.Apps), 1; got != want {
+			t.Fatalf("len=%v, want %v", got, want)
+		} else if got, want := resp.Apps[0].App, "my-app"; got != want {
+			t.Fatalf("App=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("App", func(t *testing.T) {
+		s := fashttp.NewServer(p)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/v1/apps/my-app", nil)
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrNotFound", func(t *testing.T) {
+		s := fashttp.NewServer(p)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/v1/apps/other-app", nil)
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusNotFound; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+
+	// Read-only endpoints require the token only if one is configured.
+	t.Run("Auth", func(t *testing.T) {
+		s := fashttp.NewServer(p)
+		s.Token = "secret"
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/v1/apps", nil)
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusUnauthorized; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest("GET", "/v1/apps", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+}
+
+func TestServer_GetAppSamples(t *testing.T) {
+	s := fashttp.NewServer(newOpenReconcilerPool(t, "my-app"))
+
+	t.Run("OK", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/v1/apps/my-app/samples", nil)
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		} else if got, want := w.Body.String(), "{\"samples\":[]}\n"; got != want {
+			t.Fatalf("body=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("ErrNotFound", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/v1/apps/other-app/samples", nil)
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusNotFound; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+}
+
+func TestServer_GetCollectors(t *testing.T) {
+	s := fashttp.NewServer(newOpenReconcilerPool(t, "my-app"))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/v1/collectors", nil)
+	s.ServeHTTP(w, r)
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("code=%v, want %v", got, want)
+	} else if got, want := w.Body.String(), "{\"collectors\":[]}\n"; got != want {
+		t.Fatalf("body=%q, want %q", got, want)
+	}
+}
+
+func TestServer_GetDashboard(t *testing.T) {
+	s := fashttp.NewServer(newOpenReconcilerPool(t, "my-app"))
+	s.Token = "secret"
+
+	t.Run("OK", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/dashboard", nil)
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		} else if got, want := w.Header().Get("Content-Type"), "text/html; charset=utf-8"; got != want {
+			t.Fatalf("Content-Type=%v, want %v", got, want)
+		} else if !strings.Contains(w.Body.String(), "<title>fly-autoscaler</title>") {
+			t.Fatalf("unexpected body: %s", w.Body.String())
+		}
+	})
+
+	t.Run("Index", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusFound; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		} else if got, want := w.Header().Get("Location"), "/dashboard"; got != want {
+			t.Fatalf("Location=%v, want %v", got, want)
+		}
+	})
+}
+
+func TestServer_GetHistory(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		l := fas.NewFileAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+		if err := l.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = l.Close() }()
+
+		for _, app := range []string{"my-app", "other-app", "my-app"} {
+			e := &fas.AuditEvent{App: app}
+			e.Timestamp = time.Now()
+			e.Action = fas.ActionStart
+			if err := l.WriteAuditEvent(context.Background(), e); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		p := newOpenReconcilerPool(t, "my-app")
+		p.AuditLog = l
+		s := fashttp.NewServer(p)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/v1/history?app=my-app&since=1h&limit=10", nil)
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+
+		var resp struct {
+			Events []*fas.AuditEvent `json:"events"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		} else if got, want := len(resp.Events), 2; got != want {
+			t.Fatalf("len=%v, want %v", got, want)
+		} else if got, want := resp.Events[0].App, "my-app"; got != want {
+			t.Fatalf("App=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrInvalidSince", func(t *testing.T) {
+		s := fashttp.NewServer(newOpenReconcilerPool(t, "my-app"))
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/v1/history?since=yesterday", nil)
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusBadRequest; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrDisabled", func(t *testing.T) {
+		s := fashttp.NewServer(newOpenReconcilerPool(t, "my-app"))
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/v1/history", nil)
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, http.StatusNotImplemented; got != want {
+			t.Fatalf("code=%v, want %v", got, want)
+		}
+	})
+}
+
+// newOpenReconcilerPool returns an open pool for a single app with no machines.
+func newOpenReconcilerPool(tb testing.TB, appName string) *fas.ReconcilerPool {
+	tb.Helper()
+
+	var flyClient mock.FlyClient
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return &fly.Release{Status: "completed"}, nil
+	}
+
+	var flapsClient mock.FlapsClient
+	flapsClient.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return nil, nil
+	}
+
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.AppName = appName
+	p.ReconcileInterval = time.Hour
+	p.NewReconciler = fas.NewReconciler
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &flapsClient, nil
+	}
+	if err := p.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = p.Close() })
+	return p
+}
@@ -0,0 +1,134 @@
+package fas
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/fly-go/flaps"
+)
+
+// Status label values for API call & reconciliation histograms.
+const (
+	statusOK     = "ok"
+	statusFailed = "failed"
+)
+
+func newReconcileDurationHistogram() *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "fas_reconcile_duration_seconds",
+		Help:    "Time to collect metrics & reconcile a single app.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 13),
+	}, []string{"status"})
+}
+
+func newCollectDurationHistogram() *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "fas_metric_collect_duration_seconds",
+		Help:    "Time to collect a metric value, by collector.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"collector", "status"})
+}
+
+func newCollectErrorCounter() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "fas_metric_collect_error_count",
+		Help: "Number of failed metric collections, by collector.",
+	}, []string{"collector"})
+}
+
+func newAPICallDurationHistogram() *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "fas_api_call_duration_seconds",
+		Help:    "Time to perform a Fly or Machines API call, by method & status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"api", "method", "status"})
+}
+
+// statusLabel returns "ok" for a nil error, the HTTP status code for a
+// Machines API error or "failed" for any other error.
+func statusLabel(err error) string {
+	var flapsErr *flaps.FlapsError
+	if err == nil {
+		return statusOK
+	} else if errors.As(err, &flapsErr) && flapsErr.ResponseStatusCode != 0 {
+		return strconv.Itoa(flapsErr.ResponseStatusCode)
+	}
+	return statusFailed
+}
+
+// observeDuration records the time since start on h with the given labels.
+// This is a no-op if h is nil.
+func observeDuration(h *prometheus.HistogramVec, start time.Time, labels ...string) {
+	if h == nil {
+		return
+	}
+	h.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+}
+
+var _ FlyClient = (*instrumentedFlyClient)(nil)
+
+// instrumentedFlyClient wraps a FlyClient to record the duration of each call.
+type instrumentedFlyClient struct {
+	client   FlyClient
+	duration *prometheus.HistogramVec
+}
+
+func (c *instrumentedFlyClient) GetOrganizationBySlug(ctx context.Context, slug string) (_ *fly.Organization, err error) {
+	defer func(start time.Time) { c.observe("GetOrganizationBySlug", start, err) }(time.Now())
+	return c.client.GetOrganizationBySlug(ctx, slug)
+}
+
+func (c *instrumentedFlyClient) GetAppsForOrganization(ctx context.Context, orgID string) (_ []fly.App, err error) {
+	defer func(start time.Time) { c.observe("GetAppsForOrganization", start, err) }(time.Now())
+	return c.client.GetAppsForOrganization(ctx, orgID)
+}
+
+func (c *instrumentedFlyClient) GetAppCurrentReleaseMachines(ctx context.Context, appName string) (_ *fly.Release, err error) {
+	defer func(start time.Time) { c.observe("GetAppCurrentReleaseMachines", start, err) }(time.Now())
+	return c.client.GetAppCurrentReleaseMachines(ctx, appName)
+}
+
+func (c *instrumentedFlyClient) observe(method string, start time.Time, err error) {
+	observeDuration(c.duration, start, "fly", method, statusLabel(err))
+}
+
+var _ FlapsClient = (*instrumentedFlapsClient)(nil)
+
+// instrumentedFlapsClient wraps a FlapsClient to record the duration of each call.
+type instrumentedFlapsClient struct {
+	client   FlapsClient
+	duration *prometheus.HistogramVec
+}
+
+func (c *instrumentedFlapsClient) List(ctx context.Context, state string) (_ []*fly.Machine, err error) {
+	defer func(start time.Time) { c.observe("List", start, err) }(time.Now())
+	return c.client.List(ctx, state)
+}
+
+func (c *instrumentedFlapsClient) Launch(ctx context.Context, input fly.LaunchMachineInput) (_ *fly.Machine, err error) {
+	defer func(start time.Time) { c.observe("Launch", start, err) }(time.Now())
+	return c.client.Launch(ctx, input)
+}
+
+func (c *instrumentedFlapsClient) Destroy(ctx context.Context, input fly.RemoveMachineInput, nonce string) (err error) {
+	defer func(start time.Time) { c.observe("Destroy", start, err) }(time.Now())
+	return c.client.Destroy(ctx, input, nonce)
+}
+
+func (c *instrumentedFlapsClient) Start(ctx context.Context, id, nonce string) (_ *fly.MachineStartResponse, err error) {
+	defer func(start time.Time) { c.observe("Start", start, err) }(time.Now())
+	return c.client.Start(ctx, id, nonce)
+}
+
+func (c *instrumentedFlapsClient) Stop(ctx context.Context, in fly.StopMachineInput, nonce string) (err error) {
+	defer func(start time.Time) { c.observe("Stop", start, err) }(time.Now())
+	return c.client.Stop(ctx, in, nonce)
+}
+
+func (c *instrumentedFlapsClient) observe(method string, start time.Time, err error) {
+	observeDuration(c.duration, start, "machines", method, statusLabel(err))
+}
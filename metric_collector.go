@@ -3,6 +3,7 @@ package fas
 import (
 	"context"
 	"os"
+	"time"
 )
 
 // MetricCollector represents a client for collecting metrics from an external source.
@@ -11,6 +12,13 @@ type MetricCollector interface {
 	CollectMetric(ctx context.Context, app string) (float64, error)
 }
 
+// RangeMetricCollector represents a metric collector that can also return
+// historical values of a metric. This is used for forecasting.
+type RangeMetricCollector interface {
+	MetricCollector
+	CollectMetricRange(ctx context.Context, app string, start, end time.Time, step time.Duration) ([]MetricSample, error)
+}
+
 // ExpandMetricQuery replaces variables in query with their values.
 func ExpandMetricQuery(ctx context.Context, query, app string) string {
 	return os.Expand(query, func(key string) string {
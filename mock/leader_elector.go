@@ -0,0 +1,22 @@
+package mock
+
+import (
+	"context"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+var _ fas.LeaderElector = (*LeaderElector)(nil)
+
+type LeaderElector struct {
+	AcquireFunc func(ctx context.Context) (bool, error)
+	ReleaseFunc func(ctx context.Context) error
+}
+
+func (e *LeaderElector) Acquire(ctx context.Context) (bool, error) {
+	return e.AcquireFunc(ctx)
+}
+
+func (e *LeaderElector) Release(ctx context.Context) error {
+	return e.ReleaseFunc(ctx)
+}
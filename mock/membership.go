@@ -0,0 +1,22 @@
+package mock
+
+import (
+	"context"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+var _ fas.Membership = (*Membership)(nil)
+
+type Membership struct {
+	HeartbeatFunc func(ctx context.Context, id string) ([]string, error)
+	LeaveFunc     func(ctx context.Context, id string) error
+}
+
+func (m *Membership) Heartbeat(ctx context.Context, id string) ([]string, error) {
+	return m.HeartbeatFunc(ctx, id)
+}
+
+func (m *Membership) Leave(ctx context.Context, id string) error {
+	return m.LeaveFunc(ctx, id)
+}
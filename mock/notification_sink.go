@@ -0,0 +1,17 @@
+package mock
+
+import (
+	"context"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+var _ fas.NotificationSink = (*NotificationSink)(nil)
+
+type NotificationSink struct {
+	SendNotificationsFunc func(ctx context.Context, a []*fas.Notification) error
+}
+
+func (s *NotificationSink) SendNotifications(ctx context.Context, a []*fas.Notification) error {
+	return s.SendNotificationsFunc(ctx, a)
+}
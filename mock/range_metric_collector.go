@@ -0,0 +1,23 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+var _ fas.RangeMetricCollector = (*RangeMetricCollector)(nil)
+
+type RangeMetricCollector struct {
+	MetricCollector
+	CollectMetricRangeFunc func(ctx context.Context, app string, start, end time.Time, step time.Duration) ([]fas.MetricSample, error)
+}
+
+func NewRangeMetricCollector(name string) *RangeMetricCollector {
+	return &RangeMetricCollector{MetricCollector: MetricCollector{name: name}}
+}
+
+func (c *RangeMetricCollector) CollectMetricRange(ctx context.Context, app string, start, end time.Time, step time.Duration) ([]fas.MetricSample, error) {
+	return c.CollectMetricRangeFunc(ctx, app, start, end, step)
+}
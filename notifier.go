@@ -0,0 +1,326 @@
+package fas
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Default notifier settings.
+const (
+	DefaultNotifyBatchInterval = 10 * time.Second
+	DefaultNotifyMaxBatchSize  = 20
+	DefaultNotifyRateLimit     = 5 * time.Minute
+	DefaultNotifySendTimeout   = 10 * time.Second
+)
+
+// Notification types.
+const (
+	// An action affected at least the configured number of machines.
+	NotificationTypeScale = "scale"
+
+	// An app failed to reconcile the configured number of times in a row.
+	NotificationTypeFailure = "failure"
+
+	// An app reconciled successfully after a failure notification was sent.
+	NotificationTypeRecovered = "recovered"
+
+	// An app's target reached its maximum bound.
+	NotificationTypeMaxBound = "max_bound"
+)
+
+// Notification represents a notable scaling event for an app.
+type Notification struct {
+	Type      string    `json:"type"`
+	App       string    `json:"app"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// Human readable description of the event.
+	Summary string `json:"summary"`
+
+	// Action & affected machines for scale events.
+	Action     string   `json:"action,omitempty"`
+	N          int      `json:"n,omitempty"`
+	MachineIDs []string `json:"machine_ids,omitempty"`
+
+	// Number of consecutive failures & the most recent error.
+	Failures int    `json:"failures,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// NotificationSink delivers batches of notifications to an external service.
+type NotificationSink interface {
+	SendNotifications(ctx context.Context, a []*Notification) error
+}
+
+// NotifierSink is a sink & the notification types that are sent to it.
+type NotifierSink struct {
+	Sink  NotificationSink
+	Types []string // empty sends all types
+}
+
+// Notifier generates notifications from reconciliation results & delivers
+// them to its sinks in batches. Repeated notifications of the same type for
+// the same app are suppressed within the rate limit interval.
+type Notifier struct {
+	mu       sync.Mutex
+	pending  []*Notification
+	failureN map[string]int       // consecutive failures, by app
+	failed   map[string]bool      // true if a failure notification was sent
+	atMax    map[string]bool      // true if the app is at its max bound
+	lastSent map[string]time.Time // last notification time, by app & type
+
+	flush  chan struct{}
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	Sinks []NotifierSink
+
+	// Notify when an action affects at least this many machines.
+	// Zero disables scale notifications.
+	MinMachines int
+
+	// Notify when an app fails to reconcile this many times in a row.
+	// Zero disables failure notifications.
+	ConsecutiveFailures int
+
+	// Notify when an app's target reaches its maximum bound.
+	MaxBound bool
+
+	// Frequency to send pending notifications & the max number of
+	// notifications in a batch. A full batch is sent immediately.
+	BatchInterval time.Duration
+	MaxBatchSize  int
+
+	// Minimum time between notifications of the same type for an app.
+	RateLimit time.Duration
+
+	// Returns the current time. Overridden for testing.
+	Now func() time.Time
+}
+
+// NewNotifier returns a new instance of Notifier.
+func NewNotifier() *Notifier {
+	n := &Notifier{
+		failureN: make(map[string]int),
+		failed:   make(map[string]bool),
+		atMax:    make(map[string]bool),
+		lastSent: make(map[string]time.Time),
+		flush:    make(chan struct{}, 1),
+
+		BatchInterval: DefaultNotifyBatchInterval,
+		MaxBatchSize:  DefaultNotifyMaxBatchSize,
+		RateLimit:     DefaultNotifyRateLimit,
+		Now:           time.Now,
+	}
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+	return n
+}
+
+// Open begins sending batches of notifications in a separate goroutine.
+func (n *Notifier) Open() error {
+	n.wg.Add(1)
+	go func() { defer n.wg.Done(); n.monitor(n.ctx) }()
+	return nil
+}
+
+// Close stops the notifier & sends any pending notifications.
+func (n *Notifier) Close() error {
+	n.cancel()
+	n.wg.Wait()
+	n.Flush(context.Background())
+	return nil
+}
+
+func (n *Notifier) monitor(ctx context.Context) {
+	ticker := time.NewTicker(n.BatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-n.flush:
+		}
+		n.Flush(ctx)
+	}
+}
+
+// Observe records the result of reconciling an app & queues notifications for
+// any notable events. The decision d is nil if no new decision was made. If
+// non-blank, maxBound describes how the app's target reached its max bound.
+func (n *Notifier) Observe(app string, d *Decision, maxBound string, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := n.Now()
+	if err != nil {
+		// Retry on later failures if the notification was rate limited.
+		n.failureN[app]++
+		if failureN := n.failureN[app]; n.ConsecutiveFailures > 0 && failureN >= n.ConsecutiveFailures && !n.failed[app] {
+			if n.enqueueLocked(&Notification{
+				Type:      NotificationTypeFailure,
+				App:       app,
+				Timestamp: now,
+				Summary:   fmt.Sprintf("reconciliation failed %d times in a row: %s", failureN, err),
+				Failures:  failureN,
+				Error:     err.Error(),
+			}) {
+				n.failed[app] = true
+			}
+		}
+	} else {
+		if n.failed[app] {
+			n.enqueueLocked(&Notification{
+				Type:      NotificationTypeRecovered,
+				App:       app,
+				Timestamp: now,
+				Summary:   fmt.Sprintf("reconciliation succeeded after %d failures", n.failureN[app]),
+				Failures:  n.failureN[app],
+			})
+		}
+		delete(n.failureN, app)
+		delete(n.failed, app)
+	}
+
+	if d == nil {
+		return
+	}
+
+	if n.MinMachines > 0 && d.Action != ActionNoScale && d.N >= n.MinMachines {
+		n.enqueueLocked(&Notification{
+			Type:       NotificationTypeScale,
+			App:        app,
+			Timestamp:  d.Timestamp,
+			Summary:    fmt.Sprintf("%s %d machines", d.Action, d.N),
+			Action:     d.Action,
+			N:          d.N,
+			MachineIDs: d.MachineIDs,
+		})
+	}
+
+	// Only notify when an app first reaches its max bound.
+	if n.MaxBound && maxBound != "" && !n.atMax[app] {
+		n.enqueueLocked(&Notification{
+			Type:      NotificationTypeMaxBound,
+			App:       app,
+			Timestamp: d.Timestamp,
+			Summary:   maxBound,
+		})
+	}
+	n.atMax[app] = maxBound != ""
+}
+
+// enqueueLocked adds a notification to the pending batch unless it is rate
+// limited. Recovery notifications are never rate limited so that they always
+// follow a failure notification. Returns true if the notification was queued.
+func (n *Notifier) enqueueLocked(notification *Notification) bool {
+	key := notification.App + "/" + notification.Type
+	if notification.Type != NotificationTypeRecovered && n.RateLimit > 0 {
+		if t, ok := n.lastSent[key]; ok && n.Now().Sub(t) < n.RateLimit {
+			slog.Debug("notification rate limited",
+				slog.String("app", notification.App),
+				slog.String("type", notification.Type))
+			return false
+		}
+	}
+	n.lastSent[key] = n.Now()
+
+	n.pending = append(n.pending, notification)
+	if n.MaxBatchSize > 0 && len(n.pending) >= n.MaxBatchSize {
+		select {
+		case n.flush <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// Flush sends all pending notifications to each sink. Delivery errors are
+// logged & the notifications are not retried.
+func (n *Notifier) Flush(ctx context.Context) {
+	n.mu.Lock()
+	pending := n.pending
+	n.pending = nil
+	n.mu.Unlock()
+
+	for len(pending) > 0 {
+		batch := pending
+		if n.MaxBatchSize > 0 && len(batch) > n.MaxBatchSize {
+			batch = batch[:n.MaxBatchSize]
+		}
+		pending = pending[len(batch):]
+
+		for _, s := range n.Sinks {
+			n.send(ctx, s, batch)
+		}
+	}
+}
+
+func (n *Notifier) send(ctx context.Context, s NotifierSink, batch []*Notification) {
+	var a []*Notification
+	for _, notification := range batch {
+		if len(s.Types) == 0 || slices.Contains(s.Types, notification.Type) {
+			a = append(a, notification)
+		}
+	}
+	if len(a) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultNotifySendTimeout)
+	defer cancel()
+
+	if err := s.Sink.SendNotifications(ctx, a); err != nil {
+		slog.Error("cannot send notifications",
+			slog.String("sink", fmt.Sprintf("%T", s.Sink)),
+			slog.Int("n", len(a)),
+			slog.Any("err", err))
+	}
+}
+
+// notify passes the result of reconciling r to the notifier, if set. The prev
+// decision is the app's last decision before reconciling.
+func (p *ReconcilerPool) notify(r *Reconciler, prev *Decision, err error) {
+	if p.Notifier == nil {
+		return
+	}
+
+	var maxBound string
+	d := r.State.LastDecision
+	if d == prev {
+		d = nil
+	} else if d != nil {
+		maxBound = r.maxBoundReason(d)
+	}
+	p.Notifier.Observe(r.AppName, d, maxBound, err)
+}
+
+// maxBoundReason returns a description of how d's targets reached the app's
+// maximum bound, if at all. The bound is either the app's guardrail max or,
+// when only starting machines, the number of created machines.
+func (r *Reconciler) maxBoundReason(d *Decision) string {
+	if _, maxN := r.guardrailBounds(""); maxN > 0 {
+		for _, target := range []struct {
+			name string
+			v    *int
+		}{
+			{"created", d.MinCreatedN},
+			{"started", d.MinStartedN},
+		} {
+			if target.v != nil && *target.v >= maxN {
+				return fmt.Sprintf("%s machine target reached guardrail max of %d machines", target.name, maxN)
+			}
+		}
+	}
+
+	if d.MinCreatedN == nil && d.MinStartedN != nil && *d.MinStartedN > r.fleet.CreatedN {
+		return fmt.Sprintf("started machine target of %d exceeds %d created machines", *d.MinStartedN, r.fleet.CreatedN)
+	}
+	return ""
+}
@@ -0,0 +1,288 @@
+package fas_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/mock"
+	fly "github.com/superfly/fly-go"
+)
+
+func TestNotifier_Observe(t *testing.T) {
+	t.Run("Scale", func(t *testing.T) {
+		n, sent := newTestNotifier(t, nil)
+		n.MinMachines = 5
+
+		n.Observe("my-app", &fas.Decision{Action: fas.ActionStart, N: 4}, "", nil)
+		n.Observe("my-app", &fas.Decision{Action: fas.ActionStart, N: 5, MachineIDs: []string{"1"}}, "", nil)
+		n.Flush(context.Background())
+
+		if got, want := len(*sent), 1; got != want {
+			t.Fatalf("len=%v, want %v", got, want)
+		} else if got, want := (*sent)[0].Type, fas.NotificationTypeScale; got != want {
+			t.Fatalf("Type=%v, want %v", got, want)
+		} else if got, want := (*sent)[0].Summary, "start 5 machines"; got != want {
+			t.Fatalf("Summary=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ConsecutiveFailures", func(t *testing.T) {
+		n, sent := newTestNotifier(t, nil)
+		n.ConsecutiveFailures = 2
+
+		// Only the second failure in a row is reported, followed by a recovery.
+		n.Observe("my-app", nil, "", errors.New("marker"))
+		n.Observe("my-app", nil, "", errors.New("marker"))
+		n.Observe("my-app", nil, "", errors.New("marker"))
+		n.Observe("my-app", nil, "", nil)
+		n.Flush(context.Background())
+
+		if got, want := len(*sent), 2; got != want {
+			t.Fatalf("len=%v, want %v", got, want)
+		} else if got, want := (*sent)[0].Summary, "reconciliation failed 2 times in a row: marker"; got != want {
+			t.Fatalf("Summary=%v, want %v", got, want)
+		} else if got, want := (*sent)[1].Type, fas.NotificationTypeRecovered; got != want {
+			t.Fatalf("Type=%v, want %v", got, want)
+		} else if got, want := (*sent)[1].Failures, 3; got != want {
+			t.Fatalf("Failures=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("MaxBound", func(t *testing.T) {
+		n, sent := newTestNotifier(t, nil)
+		n.MaxBound = true
+
+		// Only notify when first reaching the bound.
+		n.Observe("my-app", &fas.Decision{}, "at max", nil)
+		n.Observe("my-app", &fas.Decision{}, "at max", nil)
+		n.Observe("my-app", &fas.Decision{}, "", nil)
+		n.Observe("my-app", &fas.Decision{}, "at max", nil)
+		n.Flush(context.Background())
+
+		if got, want := len(*sent), 1; got != want {
+			t.Fatalf("len=%v, want %v", got, want)
+		} else if got, want := (*sent)[0].Summary, "at max"; got != want {
+			t.Fatalf("Summary=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("RateLimit", func(t *testing.T) {
+		now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		n, sent := newTestNotifier(t, nil)
+		n.MinMachines = 1
+		n.RateLimit = time.Minute
+		n.Now = func() time.Time { return now }
+
+		n.Observe("my-app", &fas.Decision{Action: fas.ActionStart, N: 1}, "", nil)
+		n.Observe("my-app", &fas.Decision{Action: fas.ActionStart, N: 1}, "", nil)
+		n.Observe("other-app", &fas.Decision{Action: fas.ActionStart, N: 1}, "", nil)
+		now = now.Add(time.Minute)
+		n.Observe("my-app", &fas.Decision{Action: fas.ActionStart, N: 1}, "", nil)
+		n.Flush(context.Background())
+
+		if got, want := len(*sent), 3; got != want {
+			t.Fatalf("len=%v, want %v", got, want)
+		}
+	})
+
+	// A rate limited failure notification is retried on later failures.
+	t.Run("FailureRateLimit", func(t *testing.T) {
+		now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		n, sent := newTestNotifier(t, nil)
+		n.ConsecutiveFailures = 1
+		n.RateLimit = time.Minute
+		n.Now = func() time.Time { return now }
+
+		n.Observe("my-app", nil, "", errors.New("marker"))
+		n.Observe("my-app", nil, "", nil)
+		n.Observe("my-app", nil, "", errors.New("marker")) // rate limited
+		now = now.Add(time.Minute)
+		n.Observe("my-app", nil, "", errors.New("marker"))
+		n.Observe("my-app", nil, "", errors.New("marker")) // already notified
+		n.Observe("my-app", nil, "", nil)
+		n.Flush(context.Background())
+
+		var types []string
+		for _, notification := range *sent {
+			types = append(types, notification.Type)
+		}
+		if got, want := types, []string{
+			fas.NotificationTypeFailure,
+			fas.NotificationTypeRecovered,
+			fas.NotificationTypeFailure,
+			fas.NotificationTypeRecovered,
+		}; !slices.Equal(got, want) {
+			t.Fatalf("types=%v, want %v", got, want)
+		} else if got, want := (*sent)[2].Failures, 2; got != want {
+			t.Fatalf("Failures=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("SinkTypes", func(t *testing.T) {
+		n, sent := newTestNotifier(t, []string{fas.NotificationTypeFailure})
+		n.MinMachines = 1
+		n.ConsecutiveFailures = 1
+
+		n.Observe("my-app", &fas.Decision{Action: fas.ActionStart, N: 1}, "", nil)
+		n.Observe("my-app", nil, "", errors.New("marker"))
+		n.Flush(context.Background())
+
+		if got, want := len(*sent), 1; got != want {
+			t.Fatalf("len=%v, want %v", got, want)
+		} else if got, want := (*sent)[0].Type, fas.NotificationTypeFailure; got != want {
+			t.Fatalf("Type=%v, want %v", got, want)
+		}
+	})
+}
+
+func TestNotifier_Batch(t *testing.T) {
+	var batches [][]*fas.Notification
+	sink := &mock.NotificationSink{
+		SendNotificationsFunc: func(ctx context.Context, a []*fas.Notification) error {
+			batches = append(batches, a)
+			return nil
+		},
+	}
+
+	n := fas.NewNotifier()
+	n.Sinks = []fas.NotifierSink{{Sink: sink}}
+	n.MinMachines = 1
+	n.MaxBatchSize = 2
+	n.RateLimit = 0
+	for i := 0; i < 5; i++ {
+		n.Observe("my-app", &fas.Decision{Action: fas.ActionStart, N: 1}, "", nil)
+	}
+	n.Flush(context.Background())
+
+	if got, want := len(batches), 3; got != want {
+		t.Fatalf("len=%v, want %v", got, want)
+	} else if got, want := len(batches[2]), 1; got != want {
+		t.Fatalf("len(batches[2])=%v, want %v", got, want)
+	}
+}
+
+// Ensure the pool notifies when a target reaches a guardrail's max bound.
+func TestReconcilerPool_Notifier(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return &fly.Release{Status: "completed"}, nil
+	}
+
+	var flapsClient mock.FlapsClient
+	flapsClient.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+			{ID: "2", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+	flapsClient.StartFunc = func(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+		return &fly.MachineStartResponse{}, nil
+	}
+
+	n, sent := newTestNotifier(t, nil)
+	n.MinMachines = 2
+	n.MaxBound = true
+
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.AppName = "my-app"
+	p.ReconcileInterval = time.Hour
+	p.Notifier = n
+	p.NewReconciler = func() *fas.Reconciler {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN = "10"
+		r.Guardrails = []fas.Guardrail{{MaxN: 2}}
+		return r
+	}
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &flapsClient, nil
+	}
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	if _, err := p.Trigger("my-app"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	n.Flush(context.Background())
+
+	if got, want := len(*sent), 2; got != want {
+		t.Fatalf("len=%v, want %v", got, want)
+	} else if got, want := (*sent)[0].Summary, "start 2 machines"; got != want {
+		t.Fatalf("Summary=%v, want %v", got, want)
+	} else if got, want := (*sent)[1].Summary, "started machine target reached guardrail max of 2 machines"; got != want {
+		t.Fatalf("Summary=%v, want %v", got, want)
+	}
+}
+
+// Ensure a release in progress does not reset an app's failure streak or send
+// a recovered notification.
+func TestReconcilerPool_Notifier_ReleaseInProgress(t *testing.T) {
+	var releaseN atomic.Int64
+	var flyClient mock.FlyClient
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		if releaseN.Add(1) == 2 {
+			return &fly.Release{Status: "running"}, nil
+		}
+		return nil, errors.New("marker")
+	}
+
+	n, sent := newTestNotifier(t, nil)
+	n.ConsecutiveFailures = 2
+
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.AppName = "my-app"
+	p.ReconcileInterval = time.Hour
+	p.Notifier = n
+	p.NewReconciler = fas.NewReconciler
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &mock.FlapsClient{}, nil
+	}
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	// Fail, skip for the release & fail again.
+	for i := 0; i < 3; i++ {
+		if _, err := p.Trigger("my-app"); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	n.Flush(context.Background())
+
+	if got, want := releaseN.Load(), int64(3); got != want {
+		t.Fatalf("releaseN=%v, want %v", got, want)
+	} else if got, want := len(*sent), 1; got != want {
+		t.Fatalf("len=%v, want %v", got, want)
+	} else if got, want := (*sent)[0].Type, fas.NotificationTypeFailure; got != want {
+		t.Fatalf("Type=%v, want %v", got, want)
+	} else if got, want := (*sent)[0].Failures, 2; got != want {
+		t.Fatalf("Failures=%v, want %v", got, want)
+	}
+}
+
+// newTestNotifier returns a notifier that records all notifications sent to
+// a single sink subscribed to the given types.
+func newTestNotifier(tb testing.TB, types []string) (*fas.Notifier, *[]*fas.Notification) {
+	tb.Helper()
+
+	var sent []*fas.Notification
+	sink := &mock.NotificationSink{
+		SendNotificationsFunc: func(ctx context.Context, a []*fas.Notification) error {
+			sent = append(sent, a...)
+			return nil
+		},
+	}
+
+	n := fas.NewNotifier()
+	n.Sinks = []fas.NotifierSink{{Sink: sink, Types: types}}
+	return n, &sent
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+// DefaultTemplate is the default template for the message text of each
+// notification. Templates are executed with a *fas.Notification.
+const DefaultTemplate = `[{{.Type}}] {{.App}}: {{.Summary}}`
+
+// ParseTemplate parses a notification message template. A blank string
+// returns the default template.
+func ParseTemplate(s string) (*template.Template, error) {
+	if s == "" {
+		s = DefaultTemplate
+	}
+	return template.New("notification").Option("missingkey=error").Parse(s)
+}
+
+// render executes tmpl against n. Uses the default template if tmpl is nil.
+func render(tmpl *template.Template, n *fas.Notification) (string, error) {
+	if tmpl == nil {
+		tmpl = defaultTemplate
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, n); err != nil {
+		return "", fmt.Errorf("execute notification template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+var defaultTemplate = template.Must(ParseTemplate(""))
+
+// postJSON encodes v as JSON & posts it to u. Returns an error on a non-2xx
+// response status.
+func postJSON(ctx context.Context, client *http.Client, u string, header http.Header, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, values := range header {
+		req.Header[k] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		buf, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(buf))
+	}
+	return nil
+}
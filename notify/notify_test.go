@@ -0,0 +1,166 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/notify"
+)
+
+func TestWebhookSink_SendNotifications(t *testing.T) {
+	var body map[string]any
+	server := newTestServer(t, &body, func(r *http.Request) {
+		if got, want := r.Header.Get("Authorization"), "Bearer secret"; got != want {
+			t.Errorf("Authorization=%v, want %v", got, want)
+		}
+	})
+
+	s := notify.NewWebhookSink(server.URL)
+	s.Header.Set("Authorization", "Bearer secret")
+	if err := s.SendNotifications(context.Background(), []*fas.Notification{newNotification()}); err != nil {
+		t.Fatal(err)
+	}
+
+	a := body["notifications"].([]any)
+	if got, want := len(a), 1; got != want {
+		t.Fatalf("len=%v, want %v", got, want)
+	}
+	n := a[0].(map[string]any)
+	if got, want := n["app"], "my-app"; got != want {
+		t.Fatalf("app=%v, want %v", got, want)
+	} else if got, want := n["text"], "[scale] my-app: start 5 machines"; got != want {
+		t.Fatalf("text=%v, want %v", got, want)
+	}
+}
+
+func TestSlackSink_SendNotifications(t *testing.T) {
+	var body map[string]any
+	server := newTestServer(t, &body, nil)
+
+	tmpl, err := notify.ParseTemplate(`{{.App}} {{.Action}} x{{.N}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := notify.NewSlackSink(server.URL)
+	s.Template = tmpl
+	if err := s.SendNotifications(context.Background(), []*fas.Notification{newNotification(), newNotification()}); err != nil {
+		t.Fatal(err)
+	} else if got, want := body["text"], "my-app start x5\nmy-app start x5"; got != want {
+		t.Fatalf("text=%q, want %q", got, want)
+	}
+}
+
+func TestPagerDutySink_SendNotifications(t *testing.T) {
+	t.Run("Trigger", func(t *testing.T) {
+		var body map[string]any
+		server := newTestServer(t, &body, nil)
+
+		s := notify.NewPagerDutySink("KEY")
+		s.URL = server.URL
+		if err := s.SendNotifications(context.Background(), []*fas.Notification{newNotification()}); err != nil {
+			t.Fatal(err)
+		}
+
+		payload := body["payload"].(map[string]any)
+		if got, want := body["routing_key"], "KEY"; got != want {
+			t.Fatalf("routing_key=%v, want %v", got, want)
+		} else if got, want := body["event_action"], "trigger"; got != want {
+			t.Fatalf("event_action=%v, want %v", got, want)
+		} else if got, want := body["dedup_key"], "fly-autoscaler/my-app/scale"; got != want {
+			t.Fatalf("dedup_key=%v, want %v", got, want)
+		} else if got, want := payload["summary"], "[scale] my-app: start 5 machines"; got != want {
+			t.Fatalf("summary=%v, want %v", got, want)
+		} else if got, want := payload["severity"], "info"; got != want {
+			t.Fatalf("severity=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		var body map[string]any
+		server := newTestServer(t, &body, nil)
+
+		s := notify.NewPagerDutySink("KEY")
+		s.URL = server.URL
+		s.Severity = "critical"
+		if err := s.SendNotifications(context.Background(), []*fas.Notification{{Type: fas.NotificationTypeFailure, App: "my-app"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		payload := body["payload"].(map[string]any)
+		if got, want := body["dedup_key"], "fly-autoscaler/my-app/failure"; got != want {
+			t.Fatalf("dedup_key=%v, want %v", got, want)
+		} else if got, want := payload["severity"], "critical"; got != want {
+			t.Fatalf("severity=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Resolve", func(t *testing.T) {
+		var body map[string]any
+		server := newTestServer(t, &body, nil)
+
+		s := notify.NewPagerDutySink("KEY")
+		s.URL = server.URL
+		if err := s.SendNotifications(context.Background(), []*fas.Notification{{Type: fas.NotificationTypeRecovered, App: "my-app"}}); err != nil {
+			t.Fatal(err)
+		} else if got, want := body["event_action"], "resolve"; got != want {
+			t.Fatalf("event_action=%v, want %v", got, want)
+		} else if got, want := body["dedup_key"], "fly-autoscaler/my-app/failure"; got != want {
+			t.Fatalf("dedup_key=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrStatus", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "invalid routing key", http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		s := notify.NewPagerDutySink("KEY")
+		s.URL = server.URL
+		if err := s.SendNotifications(context.Background(), []*fas.Notification{newNotification()}); err == nil || err.Error() != `unexpected status 400: invalid routing key` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestParseTemplate(t *testing.T) {
+	if _, err := notify.ParseTemplate(`{{.App`); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func newNotification() *fas.Notification {
+	return &fas.Notification{
+		Type:      fas.NotificationTypeScale,
+		App:       "my-app",
+		Timestamp: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		Summary:   "start 5 machines",
+		Action:    fas.ActionStart,
+		N:         5,
+	}
+}
+
+// newTestServer returns a server that decodes the JSON request body into body.
+func newTestServer(tb testing.TB, body *map[string]any, fn func(r *http.Request)) *httptest.Server {
+	tb.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fn != nil {
+			fn(r)
+		}
+		buf, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(buf, body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	tb.Cleanup(server.Close)
+	return server
+}
@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"text/template"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+// DefaultPagerDutyURL is the PagerDuty Events API v2 endpoint.
+const DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// DefaultPagerDutyTypes are the notification types sent to PagerDuty if a sink
+// does not list its types. Only failures are resolved so other types would
+// leave incidents open.
+var DefaultPagerDutyTypes = []string{fas.NotificationTypeFailure, fas.NotificationTypeRecovered}
+
+var _ fas.NotificationSink = (*PagerDutySink)(nil)
+
+// PagerDutySink sends each notification as an event to the PagerDuty Events
+// API v2. Events are deduplicated by app & type and a recovery notification
+// resolves the app's failure event. Other types are informational & are
+// always sent with an "info" severity.
+type PagerDutySink struct {
+	URL        string
+	RoutingKey string
+
+	// Severity of failure events: "critical", "error", "warning" or "info".
+	Severity string
+
+	// Template for the event summary. Uses DefaultTemplate if nil.
+	Template *template.Template
+
+	HTTPClient *http.Client
+}
+
+// NewPagerDutySink returns a new instance of PagerDutySink.
+func NewPagerDutySink(routingKey string) *PagerDutySink {
+	return &PagerDutySink{
+		URL:        DefaultPagerDutyURL,
+		RoutingKey: routingKey,
+		Severity:   "error",
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+func (s *PagerDutySink) SendNotifications(ctx context.Context, a []*fas.Notification) error {
+	for _, n := range a {
+		summary, err := render(s.Template, n)
+		if err != nil {
+			return err
+		}
+
+		severity := s.Severity
+		if n.Type != fas.NotificationTypeFailure {
+			severity = "info"
+		}
+
+		event := pagerDutyEvent{
+			RoutingKey:  s.RoutingKey,
+			EventAction: "trigger",
+			DedupKey:    "fly-autoscaler/" + n.App + "/" + n.Type,
+			Payload: &pagerDutyPayload{
+				Summary:       summary,
+				Source:        n.App,
+				Severity:      severity,
+				Timestamp:     n.Timestamp.UTC().Format(time.RFC3339),
+				Component:     "fly-autoscaler",
+				Class:         n.Type,
+				CustomDetails: n,
+			},
+		}
+		if n.Type == fas.NotificationTypeRecovered {
+			event.EventAction = "resolve"
+			event.DedupKey = "fly-autoscaler/" + n.App + "/" + fas.NotificationTypeFailure
+			event.Payload = nil
+		}
+
+		if err := postJSON(ctx, s.HTTPClient, s.URL, nil, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key,omitempty"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string `json:"summary"`
+	Source        string `json:"source"`
+	Severity      string `json:"severity"`
+	Timestamp     string `json:"timestamp,omitempty"`
+	Component     string `json:"component,omitempty"`
+	Class         string `json:"class,omitempty"`
+	CustomDetails any    `json:"custom_details,omitempty"`
+}
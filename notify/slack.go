@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"text/template"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+var _ fas.NotificationSink = (*SlackSink)(nil)
+
+// SlackSink posts each batch of notifications as a single message to a
+// Slack-compatible incoming webhook. Each notification is rendered on its
+// own line.
+type SlackSink struct {
+	URL string
+
+	// Template for each notification's line. Uses DefaultTemplate if nil.
+	Template *template.Template
+
+	HTTPClient *http.Client
+}
+
+// NewSlackSink returns a new instance of SlackSink.
+func NewSlackSink(u string) *SlackSink {
+	return &SlackSink{
+		URL:        u,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+func (s *SlackSink) SendNotifications(ctx context.Context, a []*fas.Notification) error {
+	lines := make([]string, 0, len(a))
+	for _, n := range a {
+		text, err := render(s.Template, n)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, text)
+	}
+	return postJSON(ctx, s.HTTPClient, s.URL, nil, slackPayload{Text: strings.Join(lines, "\n")})
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"text/template"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+var _ fas.NotificationSink = (*WebhookSink)(nil)
+
+// WebhookSink posts each batch of notifications as a JSON object to a URL.
+// Each notification includes its rendered message text.
+type WebhookSink struct {
+	URL string
+
+	// Additional headers sent with each request, such as authorization.
+	Header http.Header
+
+	// Template for the message text. Uses DefaultTemplate if nil.
+	Template *template.Template
+
+	HTTPClient *http.Client
+}
+
+// NewWebhookSink returns a new instance of WebhookSink.
+func NewWebhookSink(u string) *WebhookSink {
+	return &WebhookSink{
+		URL:        u,
+		Header:     make(http.Header),
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+func (s *WebhookSink) SendNotifications(ctx context.Context, a []*fas.Notification) error {
+	var payload webhookPayload
+	for _, n := range a {
+		text, err := render(s.Template, n)
+		if err != nil {
+			return err
+		}
+		payload.Notifications = append(payload.Notifications, webhookNotification{Notification: n, Text: text})
+	}
+	return postJSON(ctx, s.HTTPClient, s.URL, s.Header, payload)
+}
+
+type webhookPayload struct {
+	Notifications []webhookNotification `json:"notifications"`
+}
+
+type webhookNotification struct {
+	*fas.Notification
+	Text string `json:"text"`
+}
@@ -0,0 +1,185 @@
+package fas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DefaultOverrideTTL is the default duration of a pause or override.
+const DefaultOverrideTTL = 1 * time.Hour
+
+// Override represents a manual pause or fixed machine count for an app that
+// takes precedence over its expressions, policies & schedules. Overrides are
+// set through the admin API & expire automatically.
+type Override struct {
+	// If true, the app is not reconciled at all.
+	Paused bool `json:"paused,omitempty"`
+
+	// Fixed machine counts. Nil counts are computed as usual.
+	CreatedN *int `json:"created,omitempty"`
+	StartedN *int `json:"started,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewPauseOverride returns an override that pauses an app for ttl.
+func NewPauseOverride(now time.Time, ttl time.Duration) *Override {
+	return &Override{
+		Paused:    true,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+// NewMachineOverride returns an override that fixes an app's created and/or
+// started machine counts for ttl.
+func NewMachineOverride(now time.Time, createdN, startedN *int, ttl time.Duration) *Override {
+	return &Override{
+		CreatedN:  createdN,
+		StartedN:  startedN,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+// Validate returns an error if the override is invalid.
+func (o *Override) Validate() error {
+	if o.Paused && (o.CreatedN != nil || o.StartedN != nil) {
+		return errors.New("override cannot both pause and set machine counts")
+	} else if !o.Paused && o.CreatedN == nil && o.StartedN == nil {
+		return errors.New("override requires a created or started machine count")
+	} else if o.CreatedN != nil && *o.CreatedN < 0 {
+		return fmt.Errorf("created machine count cannot be negative: %d", *o.CreatedN)
+	} else if o.StartedN != nil && *o.StartedN < 0 {
+		return fmt.Errorf("started machine count cannot be negative: %d", *o.StartedN)
+	} else if !o.ExpiresAt.After(o.CreatedAt) {
+		return errors.New("override must expire after it is created")
+	}
+	return nil
+}
+
+// Active returns true if the override is in effect at t. Nil overrides are
+// never active.
+func (o *Override) Active(t time.Time) bool {
+	return o != nil && t.Before(o.ExpiresAt)
+}
+
+// Clone returns a copy of o.
+func (o *Override) Clone() *Override {
+	if o == nil {
+		return nil
+	}
+	other := *o
+	if o.CreatedN != nil {
+		other.CreatedN = ptr(*o.CreatedN)
+	}
+	if o.StartedN != nil {
+		other.StartedN = ptr(*o.StartedN)
+	}
+	return &other
+}
+
+// ApplyOverride fixes the decision's targets to the machine counts of the
+// app's override, if one is active. Returns true if an override was applied.
+func (r *Reconciler) ApplyOverride(d *Decision) bool {
+	o := r.State.Override
+	if !o.Active(d.Timestamp) || o.Paused {
+		return false
+	}
+
+	if o.CreatedN != nil {
+		d.MinCreatedN, d.MaxCreatedN = ptr(*o.CreatedN), ptr(*o.CreatedN)
+	}
+	if o.StartedN != nil {
+		d.MinStartedN, d.MaxStartedN = ptr(*o.StartedN), ptr(*o.StartedN)
+	}
+	d.Override = true
+	return true
+}
+
+// SetOverride pauses an app or fixes its machine counts until the override
+// expires, replacing any existing override. The override is saved to the
+// store & an immediate reconciliation is triggered so it takes effect.
+func (p *ReconcilerPool) SetOverride(ctx context.Context, name string, o *Override) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	if err := p.updateOverride(ctx, name, o); err != nil {
+		return err
+	}
+
+	slog.Info("override set",
+		slog.String("app", name),
+		slog.Bool("paused", o.Paused),
+		slog.Any("created", o.CreatedN),
+		slog.Any("started", o.StartedN),
+		slog.Time("expires_at", o.ExpiresAt))
+
+	// Apply the new targets immediately rather than on the next interval.
+	if _, err := p.Trigger(name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ClearOverride removes any pause or override for an app so it resumes
+// scaling on its expressions. Reconciliation is triggered immediately.
+func (p *ReconcilerPool) ClearOverride(ctx context.Context, name string) error {
+	if err := p.updateOverride(ctx, name, nil); err != nil {
+		return err
+	}
+
+	slog.Info("override cleared", slog.String("app", name))
+
+	if _, err := p.Trigger(name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// updateOverride replaces the override in an app's state & writes it to the
+// store. Only the leader may change overrides as it owns the stored state.
+func (p *ReconcilerPool) updateOverride(ctx context.Context, name string, o *Override) error {
+	if !p.IsLeader() {
+		return ErrNotLeader
+	}
+
+	p.apps.Lock()
+	_, ok := p.apps.m[name]
+	p.apps.Unlock()
+	if !ok {
+		return ErrAppNotFound
+	}
+
+	p.stateWriteMu.Lock()
+	defer p.stateWriteMu.Unlock()
+
+	p.states.Lock()
+	state := p.states.m[name].Clone()
+	if state == nil {
+		state = NewAppState(name)
+	}
+	state.Override = o.Clone()
+	p.states.m[name] = state.Clone()
+	p.states.Unlock()
+
+	if err := p.Store.PutAppState(ctx, state); err != nil {
+		return fmt.Errorf("save app state: %w", err)
+	}
+	return nil
+}
+
+// activeOverride returns a copy of the app's override if it is active at t.
+func (p *ReconcilerPool) activeOverride(name string, t time.Time) *Override {
+	p.states.Lock()
+	defer p.states.Unlock()
+
+	if state := p.states.m[name]; state != nil && state.Override.Active(t) {
+		return state.Override.Clone()
+	}
+	return nil
+}
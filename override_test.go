@@ -0,0 +1,246 @@
+package fas_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/mock"
+	fly "github.com/superfly/fly-go"
+)
+
+func TestOverride_Validate(t *testing.T) {
+	now := time.Now()
+	for _, tt := range []struct {
+		name     string
+		override *fas.Override
+		err      string
+	}{
+		{"Pause", fas.NewPauseOverride(now, time.Hour), ""},
+		{"Started", fas.NewMachineOverride(now, nil, ptr(2), time.Hour), ""},
+		{"Zero", fas.NewMachineOverride(now, ptr(0), ptr(0), time.Hour), ""},
+		{"ErrPauseWithCount", &fas.Override{Paused: true, StartedN: ptr(1), CreatedAt: now, ExpiresAt: now.Add(time.Hour)}, `override cannot both pause and set machine counts`},
+		{"ErrNoCount", fas.NewMachineOverride(now, nil, nil, time.Hour), `override requires a created or started machine count`},
+		{"ErrNegative", fas.NewMachineOverride(now, ptr(-1), nil, time.Hour), `created machine count cannot be negative: -1`},
+		{"ErrTTL", fas.NewPauseOverride(now, 0), `override must expire after it is created`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.override.Validate()
+			if tt.err == "" && err != nil {
+				t.Fatal(err)
+			} else if tt.err != "" && (err == nil || err.Error() != tt.err) {
+				t.Fatalf("err=%v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// Ensure an override is applied immediately during the cooldown period.
+func TestReconciler_ApplyOverride_Cooldown(t *testing.T) {
+	var startN int
+	var client mock.FlapsClient
+	client.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStarted, HostStatus: fly.HostStatusOk},
+			{ID: "2", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+			{ID: "3", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+	client.StartFunc = func(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+		startN++
+		return &fly.MachineStartResponse{}, nil
+	}
+
+	r := fas.NewReconciler()
+	r.Client = &client
+	r.MinStartedMachineN, r.MaxStartedMachineN = "1", "1"
+	r.Cooldown = time.Hour
+	r.State.CooldownUntil = time.Now().Add(time.Hour)
+	r.State.Override = fas.NewMachineOverride(time.Now(), nil, ptr(3), time.Hour)
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got, want := startN, 2; got != want {
+		t.Fatalf("startN=%v, want %v", got, want)
+	} else if d := r.State.LastDecision; !d.Override || d.Action != fas.ActionStart {
+		t.Fatalf("unexpected decision: %#v", d)
+	}
+}
+
+// Ensure pauses skip reconciliation, overrides fix the machine count & both
+// are persisted to the store until cleared.
+func TestReconcilerPool_Override(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return &fly.Release{Status: "completed"}, nil
+	}
+
+	var listN, startN atomic.Int64
+	var flapsClient mock.FlapsClient
+	flapsClient.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		listN.Add(1)
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+	flapsClient.StartFunc = func(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+		startN.Add(1)
+		return &fly.MachineStartResponse{}, nil
+	}
+
+	store := fas.NewMemoryStore()
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.AppName = "my-app"
+	p.Store = store
+	p.ReconcileInterval = 10 * time.Millisecond
+	p.NewReconciler = func() *fas.Reconciler {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN, r.MaxStartedMachineN = "0", "0"
+		return r
+	}
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &flapsClient, nil
+	}
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	storedOverride := func() *fas.Override {
+		states, err := store.AppStates(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		} else if len(states) == 0 {
+			return nil
+		}
+		return states[0].Override
+	}
+
+	t.Run("Pause", func(t *testing.T) {
+		if err := p.SetOverride(context.Background(), "my-app", fas.NewPauseOverride(time.Now(), time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * p.ReconcileInterval)
+
+		n := listN.Load()
+		time.Sleep(5 * p.ReconcileInterval)
+		if got := listN.Load(); got != n {
+			t.Fatalf("listN=%v, want %v", got, n)
+		}
+
+		if o := storedOverride(); o == nil || !o.Paused {
+			t.Fatalf("unexpected stored override: %#v", o)
+		}
+		if status, err := p.AppStatus("my-app"); err != nil {
+			t.Fatal(err)
+		} else if status.Override == nil || !status.Override.Paused {
+			t.Fatalf("unexpected status override: %#v", status.Override)
+		}
+	})
+
+	t.Run("Override", func(t *testing.T) {
+		if err := p.SetOverride(context.Background(), "my-app", fas.NewMachineOverride(time.Now(), nil, ptr(1), time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * p.ReconcileInterval)
+
+		if startN.Load() == 0 {
+			t.Fatal("expected machine to be started")
+		}
+		if o := storedOverride(); o == nil || o.Paused || *o.StartedN != 1 {
+			t.Fatalf("unexpected stored override: %#v", o)
+		}
+
+		d := p.AppState("my-app").LastDecision
+		if !d.Override {
+			t.Fatal("expected override decision")
+		} else if got, want := *d.MinStartedN, 1; got != want {
+			t.Fatalf("MinStartedN=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		if err := p.ClearOverride(context.Background(), "my-app"); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * p.ReconcileInterval)
+
+		if o := storedOverride(); o != nil {
+			t.Fatalf("unexpected stored override: %#v", o)
+		}
+		if d := p.AppState("my-app").LastDecision; d.Override || d.Action != fas.ActionNoScale {
+			t.Fatalf("unexpected decision: %#v", d)
+		}
+	})
+
+	t.Run("ErrAppNotFound", func(t *testing.T) {
+		if err := p.ClearOverride(context.Background(), "other-app"); err != fas.ErrAppNotFound {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+// Ensure an override loaded from the store remains in effect after a restart
+// & is removed from the store once it expires.
+func TestReconcilerPool_Override_Store(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return &fly.Release{Status: "completed"}, nil
+	}
+
+	var listN atomic.Int64
+	var flapsClient mock.FlapsClient
+	flapsClient.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		listN.Add(1)
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStarted, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+
+	// Pause the app for a short period before the pool starts.
+	store := fas.NewMemoryStore()
+	state := fas.NewAppState("my-app")
+	state.Override = fas.NewPauseOverride(time.Now(), 100*time.Millisecond)
+	if err := store.PutAppState(context.Background(), state); err != nil {
+		t.Fatal(err)
+	}
+
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.AppName = "my-app"
+	p.Store = store
+	p.ReconcileInterval = 10 * time.Millisecond
+	p.NewReconciler = func() *fas.Reconciler {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN, r.MaxStartedMachineN = "1", "1"
+		return r
+	}
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &flapsClient, nil
+	}
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	time.Sleep(50 * time.Millisecond)
+	if got, want := listN.Load(), int64(0); got != want {
+		t.Fatalf("listN=%v, want %v", got, want)
+	}
+
+	// Reconciliation resumes once the pause expires.
+	time.Sleep(150 * time.Millisecond)
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if listN.Load() == 0 {
+		t.Fatal("expected reconciliation after pause expired")
+	}
+
+	states, err := store.AppStates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if states[0].Override != nil {
+		t.Fatalf("expected expired override to be removed: %#v", states[0].Override)
+	}
+}
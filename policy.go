@@ -0,0 +1,163 @@
+package fas
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Policy types.
+const (
+	PolicyTypeTargetTracking = "target-tracking"
+	PolicyTypePID            = "pid"
+	PolicyTypePredictive     = "predictive"
+)
+
+// Names of the machine counts that a policy can compute.
+const (
+	PolicyNameCreated = "created"
+	PolicyNameStarted = "started"
+)
+
+// Policy computes a target number of machines from metrics. It is used as an
+// alternative to min/max expressions for a machine count.
+type Policy interface {
+	// MetricNames returns the names of the metrics required by the policy.
+	MetricNames() []string
+
+	// Desired returns the number of machines that should exist. Policies may
+	// record state on in.State so it is available on the next evaluation.
+	Desired(in *PolicyInput) (int, error)
+}
+
+// PolicyInput represents the inputs available to a policy on evaluation.
+type PolicyInput struct {
+	// Name of the machine count the policy is computing (e.g. "started").
+	// Used to key any state the policy records.
+	Name string
+
+	// Current number of machines counted toward this target.
+	Current int
+
+	// Current metric & variable values.
+	Metrics map[string]float64
+
+	// Per-app state. Policies may store their own state here.
+	State *AppState
+
+	// Time of evaluation.
+	Now time.Time
+
+	// Returns the forecast value of a metric. Used by predictive policies.
+	Forecast func(name string, horizon time.Duration) (float64, error)
+}
+
+var _ Policy = (*TargetTrackingPolicy)(nil)
+
+// TargetTrackingPolicy scales proportionally so that a per-machine utilization
+// metric (e.g. CPU or concurrency) stays near a target value:
+//
+//	desired = ceil(current * metric / target)
+//
+// No scaling is performed while the metric is within the tolerance band of the
+// target. Scale down is stabilized by using the highest recommendation made
+// within the stabilization window so short dips do not remove capacity.
+type TargetTrackingPolicy struct {
+	// Name of the per-machine utilization metric.
+	Metric string
+
+	// Target value of the metric.
+	Target float64
+
+	// Fraction of the target that the metric may differ by before scaling.
+	// For example, 0.1 means the metric may be within 10% of the target.
+	Tolerance float64
+
+	// Bounds on the desired number of machines. Zero MaxN means no maximum.
+	MinN int
+	MaxN int
+
+	// Time window over which scale down recommendations are considered.
+	StabilizationWindow time.Duration
+}
+
+// Validate returns an error if the policy is misconfigured.
+func (p *TargetTrackingPolicy) Validate() error {
+	if p.Metric == "" {
+		return fmt.Errorf("target tracking metric required")
+	} else if p.Target <= 0 {
+		return fmt.Errorf("target tracking target must be greater than zero")
+	} else if p.Tolerance < 0 || p.Tolerance >= 1 {
+		return fmt.Errorf("target tracking tolerance must be within [0, 1)")
+	} else if p.MinN < 0 {
+		return fmt.Errorf("target tracking min cannot be negative")
+	} else if p.MaxN != 0 && p.MaxN < p.MinN {
+		return fmt.Errorf("target tracking max cannot be less than min")
+	} else if p.StabilizationWindow < 0 {
+		return fmt.Errorf("target tracking stabilization cannot be negative")
+	}
+	return nil
+}
+
+// MetricNames returns the utilization metric name.
+func (p *TargetTrackingPolicy) MetricNames() []string {
+	return []string{p.Metric}
+}
+
+// Desired returns the number of machines needed to bring the metric to the
+// target. The unstabilized recommendation is recorded on the app state.
+func (p *TargetTrackingPolicy) Desired(in *PolicyInput) (int, error) {
+	value, ok := in.Metrics[p.Metric]
+	if !ok {
+		return 0, fmt.Errorf("no value for metric %q", p.Metric)
+	}
+
+	n := p.Recommend(in.Current, value)
+	history := in.State.Recommendations[in.Name]
+	in.State.AddRecommendation(in.Name, MetricSample{Timestamp: in.Now, Value: float64(n)})
+
+	n = p.stabilize(n, history, in.Now)
+	return clampN(n, p.MinN, p.MaxN), nil
+}
+
+// Recommend returns the unstabilized, unbounded number of machines needed for
+// the metric value, given the current number of machines.
+func (p *TargetTrackingPolicy) Recommend(current int, value float64) int {
+	ratio := value / p.Target
+	if math.IsNaN(ratio) || math.IsInf(ratio, 0) {
+		return current
+	}
+
+	// Avoid flapping when the metric is close to the target.
+	if math.Abs(ratio-1) <= p.Tolerance {
+		return current
+	}
+
+	// Utilization cannot be measured without machines so assume one.
+	return int(math.Ceil(float64(max(current, 1)) * ratio))
+}
+
+// stabilize returns the highest of n & the recommendations made within the
+// stabilization window. This only has an effect when scaling down.
+func (p *TargetTrackingPolicy) stabilize(n int, history []MetricSample, now time.Time) int {
+	if p.StabilizationWindow <= 0 {
+		return n
+	}
+
+	since := now.Add(-p.StabilizationWindow)
+	for _, sample := range history {
+		if sample.Timestamp.After(since) {
+			n = max(n, int(sample.Value))
+		}
+	}
+	return n
+}
+
+// clampN limits n to the range [minN, maxN]. A zero maxN is unbounded.
+func clampN(n, minN, maxN int) int {
+	n = max(n, minN)
+	if maxN > 0 {
+		n = min(n, maxN)
+	}
+	return n
+}
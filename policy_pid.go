@@ -0,0 +1,125 @@
+package fas
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+var _ Policy = (*PIDPolicy)(nil)
+
+// PIDPolicy computes the number of machines with a proportional-integral-
+// derivative controller. It is useful for metrics such as latency that do not
+// scale linearly with the number of machines, where a proportional-only
+// expression tends to oscillate around the target.
+//
+// The error is the metric value minus the setpoint so a metric above the
+// setpoint increases the number of machines:
+//
+//	output = Kp*error + Ki*integral(error) + Kd*d(error)/dt
+//
+// Time is measured in seconds. Controller state is stored per app so the
+// integral is retained across reconciliations.
+type PIDPolicy struct {
+	// Name of the metric to control.
+	Metric string
+
+	// Desired value of the metric.
+	Setpoint float64
+
+	// Proportional, integral & derivative gains.
+	Kp float64
+	Ki float64
+	Kd float64
+
+	// Maximum absolute number of machines contributed by the integral term.
+	// This prevents the integral from growing unbounded while the output is
+	// saturated. Zero means no limit.
+	WindupLimit float64
+
+	// Bounds on the desired number of machines. Zero MaxN means no maximum.
+	MinN int
+	MaxN int
+}
+
+// PIDState represents the state of a PID controller between evaluations.
+type PIDState struct {
+	Integral  float64   `json:"integral"`
+	PrevError float64   `json:"prev_error"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate returns an error if the policy is misconfigured.
+func (p *PIDPolicy) Validate() error {
+	if p.Metric == "" {
+		return fmt.Errorf("pid metric required")
+	} else if p.Kp == 0 && p.Ki == 0 && p.Kd == 0 {
+		return fmt.Errorf("pid requires at least one non-zero gain")
+	} else if p.WindupLimit < 0 {
+		return fmt.Errorf("pid windup limit cannot be negative")
+	} else if p.MinN < 0 {
+		return fmt.Errorf("pid min cannot be negative")
+	} else if p.MaxN != 0 && p.MaxN < p.MinN {
+		return fmt.Errorf("pid max cannot be less than min")
+	}
+	return nil
+}
+
+// MetricNames returns the controlled metric name.
+func (p *PIDPolicy) MetricNames() []string {
+	return []string{p.Metric}
+}
+
+// Desired returns the controller output as a number of machines. Controller
+// state is updated on the app state.
+func (p *PIDPolicy) Desired(in *PolicyInput) (int, error) {
+	value, ok := in.Metrics[p.Metric]
+	if !ok {
+		return 0, fmt.Errorf("no value for metric %q", p.Metric)
+	} else if math.IsNaN(value) || math.IsInf(value, 0) {
+		return clampN(in.Current, p.MinN, p.MaxN), nil
+	}
+
+	e := value - p.Setpoint
+	state, ok := in.State.Controllers[in.Name]
+	prevIntegral := state.Integral
+
+	var derivative float64
+	if !ok {
+		// On the first evaluation, start the integral from the current number
+		// of machines so enabling the controller does not cause a jump.
+		if p.Ki != 0 {
+			state.Integral = (float64(in.Current) - p.Kp*e) / p.Ki
+		}
+	} else if dt := in.Now.Sub(state.UpdatedAt).Seconds(); dt > 0 {
+		state.Integral += e * dt
+		derivative = (e - state.PrevError) / dt
+	}
+	state.Integral = p.limitIntegral(state.Integral)
+
+	output := p.Kp*e + p.Ki*state.Integral + p.Kd*derivative
+	n := int(math.Round(output))
+	clamped := clampN(n, p.MinN, p.MaxN)
+
+	// Stop integrating while the output is saturated in the direction of the
+	// error. Otherwise the integral winds up & overshoots once it recovers.
+	if ok && n != clamped && (n > clamped) == (e*p.Ki > 0) {
+		state.Integral = prevIntegral
+	}
+
+	state.PrevError = e
+	state.UpdatedAt = in.Now
+	in.State.SetController(in.Name, state)
+
+	return clamped, nil
+}
+
+// limitIntegral bounds the integral so that its contribution to the output
+// does not exceed the windup limit.
+func (p *PIDPolicy) limitIntegral(v float64) float64 {
+	if p.WindupLimit <= 0 || p.Ki == 0 {
+		return v
+	}
+	limit := p.WindupLimit / math.Abs(p.Ki)
+	return max(-limit, min(v, limit))
+}
@@ -0,0 +1,118 @@
+package fas_test
+
+import (
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+func TestPIDPolicy_Desired(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	desired := func(tb testing.TB, p *fas.PIDPolicy, state *fas.AppState, current int, value float64, now time.Time) int {
+		tb.Helper()
+		n, err := p.Desired(&fas.PolicyInput{
+			Name:    fas.PolicyNameStarted,
+			Current: current,
+			Metrics: map[string]float64{"latency": value},
+			State:   state,
+			Now:     now,
+		})
+		if err != nil {
+			tb.Fatal(err)
+		}
+		return n
+	}
+
+	t.Run("Proportional", func(t *testing.T) {
+		p := &fas.PIDPolicy{Metric: "latency", Setpoint: 100, Kp: 0.1}
+		state := fas.NewAppState("my-app")
+		if got, want := desired(t, p, state, 0, 150, now), 5; got != want {
+			t.Fatalf("Desired=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Integral", func(t *testing.T) {
+		p := &fas.PIDPolicy{Metric: "latency", Setpoint: 100, Ki: 0.01}
+		state := fas.NewAppState("my-app")
+
+		// The first evaluation starts from the current number of machines.
+		if got, want := desired(t, p, state, 4, 150, now), 4; got != want {
+			t.Fatalf("Desired=%v, want %v", got, want)
+		}
+
+		// 50ms over the setpoint for 10s adds 5 machines.
+		if got, want := desired(t, p, state, 4, 150, now.Add(10*time.Second)), 9; got != want {
+			t.Fatalf("Desired=%v, want %v", got, want)
+		}
+
+		// Holds steady once the metric reaches the setpoint.
+		if got, want := desired(t, p, state, 9, 100, now.Add(20*time.Second)), 9; got != want {
+			t.Fatalf("Desired=%v, want %v", got, want)
+		}
+
+		if got, want := state.Controllers[fas.PolicyNameStarted].PrevError, 0.0; got != want {
+			t.Fatalf("PrevError=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Derivative", func(t *testing.T) {
+		p := &fas.PIDPolicy{Metric: "latency", Setpoint: 100, Kd: 1}
+		state := fas.NewAppState("my-app")
+		desired(t, p, state, 0, 100, now)
+
+		// Error rising by 30ms over 10s.
+		if got, want := desired(t, p, state, 0, 130, now.Add(10*time.Second)), 3; got != want {
+			t.Fatalf("Desired=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("WindupLimit", func(t *testing.T) {
+		p := &fas.PIDPolicy{Metric: "latency", Setpoint: 100, Ki: 0.01, WindupLimit: 6}
+		state := fas.NewAppState("my-app")
+		desired(t, p, state, 4, 150, now)
+		for i := 1; i <= 10; i++ {
+			desired(t, p, state, 4, 150, now.Add(time.Duration(i)*10*time.Second))
+		}
+		if got, want := state.Controllers[fas.PolicyNameStarted].Integral, 600.0; got != want {
+			t.Fatalf("Integral=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Bounds", func(t *testing.T) {
+		p := &fas.PIDPolicy{Metric: "latency", Setpoint: 100, Ki: 0.01, MinN: 1, MaxN: 5}
+		state := fas.NewAppState("my-app")
+		desired(t, p, state, 4, 100, now)
+
+		// Saturated at the max, the integral should not continue to grow.
+		for i := 1; i <= 10; i++ {
+			if got, want := desired(t, p, state, 5, 200, now.Add(time.Duration(i)*10*time.Second)), 5; got != want {
+				t.Fatalf("Desired=%v, want %v", got, want)
+			}
+		}
+
+		// Recovers as soon as the metric drops below the setpoint.
+		if got, want := desired(t, p, state, 5, 90, now.Add(110*time.Second)), 3; got != want {
+			t.Fatalf("Desired=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrNoValue", func(t *testing.T) {
+		p := &fas.PIDPolicy{Metric: "latency", Kp: 1}
+		if _, err := p.Desired(&fas.PolicyInput{State: fas.NewAppState("")}); err == nil || err.Error() != `no value for metric "latency"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestAppState_Clone_Controllers(t *testing.T) {
+	state := fas.NewAppState("my-app")
+	state.SetController(fas.PolicyNameStarted, fas.PIDState{Integral: 1})
+
+	other := state.Clone()
+	other.SetController(fas.PolicyNameStarted, fas.PIDState{Integral: 2})
+	if got, want := state.Controllers[fas.PolicyNameStarted].Integral, 1.0; got != want {
+		t.Fatalf("Integral=%v, want %v", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package fas
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+var _ Policy = (*PredictivePolicy)(nil)
+
+// PredictivePolicy scales ahead of demand by forecasting a metric. The desired
+// number of machines is computed from the higher of the current & forecast
+// value so that machines are started before a predicted peak but are not
+// stopped before load actually drops:
+//
+//	desired = ceil(max(metric, forecast) / target)
+//
+// This is useful for apps with seasonal traffic & slow cold starts.
+type PredictivePolicy struct {
+	// Name of the metric to forecast, such as request rate or queue depth.
+	Metric string
+
+	// How far ahead to forecast. This should cover the time it takes for a
+	// machine to start & become ready.
+	Horizon time.Duration
+
+	// Value of the metric that a single machine can handle.
+	Target float64
+
+	// Bounds on the desired number of machines. Zero MaxN means no maximum.
+	MinN int
+	MaxN int
+}
+
+// Validate returns an error if the policy is misconfigured.
+func (p *PredictivePolicy) Validate() error {
+	if p.Metric == "" {
+		return fmt.Errorf("predictive metric required")
+	} else if p.Horizon <= 0 {
+		return fmt.Errorf("predictive horizon must be greater than zero")
+	} else if p.Target <= 0 {
+		return fmt.Errorf("predictive target must be greater than zero")
+	} else if p.MinN < 0 {
+		return fmt.Errorf("predictive min cannot be negative")
+	} else if p.MaxN != 0 && p.MaxN < p.MinN {
+		return fmt.Errorf("predictive max cannot be less than min")
+	}
+	return nil
+}
+
+// MetricNames returns the forecast metric name.
+func (p *PredictivePolicy) MetricNames() []string {
+	return []string{p.Metric}
+}
+
+// Desired returns the number of machines needed for the forecast demand.
+func (p *PredictivePolicy) Desired(in *PolicyInput) (int, error) {
+	value, ok := in.Metrics[p.Metric]
+	if !ok {
+		return 0, fmt.Errorf("no value for metric %q", p.Metric)
+	} else if in.Forecast == nil {
+		return 0, fmt.Errorf("forecast unavailable")
+	}
+
+	forecast, err := in.Forecast(p.Metric, p.Horizon)
+	if err != nil {
+		return 0, fmt.Errorf("forecast: %w", err)
+	}
+
+	demand := max(value, forecast)
+	if math.IsNaN(demand) || math.IsInf(demand, 0) {
+		return clampN(in.Current, p.MinN, p.MaxN), nil
+	}
+	return clampN(int(math.Ceil(demand/p.Target)), p.MinN, p.MaxN), nil
+}
@@ -0,0 +1,96 @@
+package fas_test
+
+import (
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+func TestTargetTrackingPolicy_Recommend(t *testing.T) {
+	p := &fas.TargetTrackingPolicy{Metric: "cpu", Target: 50, Tolerance: 0.1}
+	for _, tt := range []struct {
+		current int
+		value   float64
+		want    int
+	}{
+		{4, 50, 4},  // at target
+		{4, 54, 4},  // within tolerance
+		{4, 100, 8}, // double utilization
+		{4, 60, 5},  // rounds up
+		{4, 25, 2},  // half utilization
+		{4, 0, 0},   // idle
+		{0, 100, 2}, // assumes a single machine when none are running
+	} {
+		if got := p.Recommend(tt.current, tt.value); got != tt.want {
+			t.Errorf("Recommend(%d, %v)=%v, want %v", tt.current, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTargetTrackingPolicy_Desired(t *testing.T) {
+	t.Run("Bounds", func(t *testing.T) {
+		p := &fas.TargetTrackingPolicy{Metric: "cpu", Target: 50, MinN: 2, MaxN: 6}
+		state := fas.NewAppState("my-app")
+		for _, tt := range []struct {
+			value float64
+			want  int
+		}{
+			{0, 2},
+			{500, 6},
+		} {
+			if n, err := p.Desired(&fas.PolicyInput{
+				Name:    fas.PolicyNameStarted,
+				Current: 4,
+				Metrics: map[string]float64{"cpu": tt.value},
+				State:   state,
+			}); err != nil {
+				t.Fatal(err)
+			} else if got, want := n, tt.want; got != want {
+				t.Fatalf("Desired(%v)=%v, want %v", tt.value, got, want)
+			}
+		}
+	})
+
+	t.Run("Stabilization", func(t *testing.T) {
+		p := &fas.TargetTrackingPolicy{Metric: "cpu", Target: 50, StabilizationWindow: 5 * time.Minute}
+		state := fas.NewAppState("my-app")
+		now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		desired := func(current int, value float64, now time.Time) int {
+			n, err := p.Desired(&fas.PolicyInput{
+				Name:    fas.PolicyNameStarted,
+				Current: current,
+				Metrics: map[string]float64{"cpu": value},
+				State:   state,
+				Now:     now,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return n
+		}
+
+		// Scale up is immediate.
+		if got, want := desired(4, 100, now), 8; got != want {
+			t.Fatalf("Desired=%v, want %v", got, want)
+		}
+
+		// Scale down is held at the highest recent recommendation.
+		if got, want := desired(8, 25, now.Add(1*time.Minute)), 8; got != want {
+			t.Fatalf("Desired=%v, want %v", got, want)
+		}
+
+		// Once the window passes, the lower recommendations are used.
+		if got, want := desired(8, 25, now.Add(7*time.Minute)), 4; got != want {
+			t.Fatalf("Desired=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrNoValue", func(t *testing.T) {
+		p := &fas.TargetTrackingPolicy{Metric: "cpu", Target: 50}
+		if _, err := p.Desired(&fas.PolicyInput{State: fas.NewAppState("")}); err == nil || err.Error() != `no value for metric "cpu"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
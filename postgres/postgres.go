@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+// DefaultLockKey is the default name of the advisory lock.
+const DefaultLockKey = "fly-autoscaler"
+
+var _ fas.LeaderElector = (*LeaderElector)(nil)
+
+// LeaderElector implements fas.LeaderElector using a Postgres session-level
+// advisory lock. The lock is held for as long as the connection is alive so
+// leadership is released automatically if the leader process dies.
+type LeaderElector struct {
+	mu   sync.Mutex
+	conn *pgx.Conn
+	held bool
+
+	// Postgres connection string.
+	URL string
+
+	// Name of the advisory lock. Hashed to a 64-bit lock ID.
+	Key string
+}
+
+// NewLeaderElector returns a new instance of LeaderElector.
+func NewLeaderElector(url string) *LeaderElector {
+	return &LeaderElector{
+		URL: url,
+		Key: DefaultLockKey,
+	}
+}
+
+// Acquire attempts to obtain the advisory lock. If the lock is already held
+// then the connection is checked to ensure the session is still alive.
+func (e *LeaderElector) Acquire(ctx context.Context) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		conn, err := pgx.Connect(ctx, e.URL)
+		if err != nil {
+			return false, fmt.Errorf("connect: %w", err)
+		}
+		e.conn = conn
+	}
+
+	// The lock is tied to the session so if the connection is still healthy
+	// then we still hold the lock. Otherwise reset so we reconnect next time.
+	if e.held {
+		if err := e.conn.Ping(ctx); err != nil {
+			e.reset()
+			return false, fmt.Errorf("ping: %w", err)
+		}
+		return true, nil
+	}
+
+	if err := e.conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, e.lockID()).Scan(&e.held); err != nil {
+		e.reset()
+		return false, fmt.Errorf("try advisory lock: %w", err)
+	}
+	return e.held, nil
+}
+
+// Release releases the advisory lock and closes the connection.
+func (e *LeaderElector) Release(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		return nil
+	}
+	defer e.reset()
+
+	if !e.held {
+		return nil
+	}
+	if _, err := e.conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`, e.lockID()); err != nil {
+		return fmt.Errorf("advisory unlock: %w", err)
+	}
+	return nil
+}
+
+// reset closes the connection, which also releases any session locks.
+func (e *LeaderElector) reset() {
+	if e.conn != nil {
+		_ = e.conn.Close(context.Background())
+	}
+	e.conn, e.held = nil, false
+}
+
+func (e *LeaderElector) lockID() int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(e.Key))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,49 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/superfly/fly-autoscaler/postgres"
+)
+
+func TestLeaderElector(t *testing.T) {
+	url := os.Getenv("FAS_TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("FAS_TEST_POSTGRES_URL not set, skipping")
+	}
+
+	e0, e1 := postgres.NewLeaderElector(url), postgres.NewLeaderElector(url)
+	defer func() { _ = e0.Release(context.Background()) }()
+	defer func() { _ = e1.Release(context.Background()) }()
+
+	if ok, err := e0.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected first elector to acquire leadership")
+	}
+
+	if ok, err := e1.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("expected second elector to not acquire leadership")
+	}
+
+	if ok, err := e0.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected first elector to retain leadership")
+	}
+
+	if err := e0.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := e1.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected second elector to acquire leadership")
+	}
+}
@@ -15,7 +15,7 @@ import (
 	fas "github.com/superfly/fly-autoscaler"
 )
 
-var _ fas.MetricCollector = (*MetricCollector)(nil)
+var _ fas.RangeMetricCollector = (*MetricCollector)(nil)
 
 type MetricCollector struct {
 	name  string
@@ -70,6 +70,38 @@ func (c *MetricCollector) CollectMetric(ctx context.Context, app string) (float6
 	}
 }
 
+// CollectMetricRange evaluates the query over a time range. Only the first
+// series in the result is used.
+func (c *MetricCollector) CollectMetricRange(ctx context.Context, app string, start, end time.Time, step time.Duration) ([]fas.MetricSample, error) {
+	query := fas.ExpandMetricQuery(ctx, c.query, app)
+
+	result, warnings, err := c.api.QueryRange(ctx, query, v1.Range{Start: start, End: end, Step: step})
+	if err != nil {
+		return nil, err
+	} else if len(warnings) > 0 {
+		slog.Warn("prometheus", slog.Any("warnings", warnings))
+	}
+
+	switch result := result.(type) {
+	case model.Matrix:
+		if result.Len() < 1 {
+			return nil, fmt.Errorf("empty prometheus result")
+		}
+
+		samples := make([]fas.MetricSample, 0, len(result[0].Values))
+		for _, pair := range result[0].Values {
+			samples = append(samples, fas.MetricSample{
+				Timestamp: pair.Timestamp.Time(),
+				Value:     float64(pair.Value),
+			})
+		}
+		return samples, nil
+
+	default:
+		return nil, fmt.Errorf("unexpected prometheus result type: %T", result)
+	}
+}
+
 type httpClient struct {
 	api.Client
 	token string
@@ -0,0 +1,213 @@
+package fas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/fly-go/flaps"
+	"golang.org/x/time/rate"
+)
+
+// DefaultRetryAfter is the time to pause all API calls after receiving a
+// "429 Too Many Requests" response that does not specify a Retry-After.
+const DefaultRetryAfter = 1 * time.Second
+
+// RateLimiter is a token bucket limiter shared by all API calls made by the
+// pool. It also pauses all calls when the API responds with a 429 status.
+type RateLimiter struct {
+	limiter *rate.Limiter
+
+	mu         sync.Mutex
+	pauseUntil time.Time
+
+	// Returns the current time. Used for testing.
+	Now func() time.Time
+}
+
+// NewRateLimiter returns a limiter that allows limit calls per second with
+// bursts of up to burst calls.
+func NewRateLimiter(limit float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		limiter: rate.NewLimiter(rate.Limit(limit), burst),
+		Now:     time.Now,
+	}
+}
+
+// Wait blocks until a call is allowed or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if d := l.PauseUntil().Sub(l.Now()); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-timer.C:
+		}
+	}
+	return l.limiter.Wait(ctx)
+}
+
+// PauseUntil returns the time until which all calls are paused.
+func (l *RateLimiter) PauseUntil() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.pauseUntil
+}
+
+// Pause blocks all calls for at least d. Does not shorten an existing pause.
+func (l *RateLimiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if t := l.Now().Add(d); t.After(l.pauseUntil) {
+		l.pauseUntil = t
+	}
+}
+
+// Transport returns an HTTP transport that pauses the limiter when a response
+// has a 429 status code. The Retry-After header is used, if available.
+func (l *RateLimiter) Transport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &rateLimiterTransport{limiter: l, rt: rt}
+}
+
+type rateLimiterTransport struct {
+	limiter *RateLimiter
+	rt      http.RoundTripper
+}
+
+func (t *rateLimiterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.rt.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		t.limiter.Pause(parseRetryAfter(resp.Header.Get("Retry-After"), t.limiter.Now()))
+	}
+	return resp, err
+}
+
+// parseRetryAfter parses a Retry-After header value as either a number of
+// seconds or an HTTP date. Returns DefaultRetryAfter if it cannot be parsed.
+func parseRetryAfter(s string, now time.Time) time.Duration {
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return DefaultRetryAfter
+}
+
+// observe pauses the limiter if err is a rate limit error from the Fly or
+// Machines API. This is a fallback for clients that were not built with
+// Transport().
+func (l *RateLimiter) observe(err error) {
+	var apiErr *fly.ApiError
+	var flapsErr *flaps.FlapsError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusTooManyRequests {
+		l.Pause(DefaultRetryAfter)
+	} else if errors.As(err, &flapsErr) && flapsErr.ResponseStatusCode == http.StatusTooManyRequests {
+		l.Pause(DefaultRetryAfter)
+	}
+}
+
+var _ FlyClient = (*rateLimitedFlyClient)(nil)
+
+// rateLimitedFlyClient wraps a FlyClient so each call waits on the limiter.
+type rateLimitedFlyClient struct {
+	client  FlyClient
+	limiter *RateLimiter
+}
+
+func (c *rateLimitedFlyClient) GetOrganizationBySlug(ctx context.Context, slug string) (*fly.Organization, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	org, err := c.client.GetOrganizationBySlug(ctx, slug)
+	c.limiter.observe(err)
+	return org, err
+}
+
+func (c *rateLimitedFlyClient) GetAppsForOrganization(ctx context.Context, orgID string) ([]fly.App, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	apps, err := c.client.GetAppsForOrganization(ctx, orgID)
+	c.limiter.observe(err)
+	return apps, err
+}
+
+func (c *rateLimitedFlyClient) GetAppCurrentReleaseMachines(ctx context.Context, appName string) (*fly.Release, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	release, err := c.client.GetAppCurrentReleaseMachines(ctx, appName)
+	c.limiter.observe(err)
+	return release, err
+}
+
+var _ FlapsClient = (*rateLimitedFlapsClient)(nil)
+
+// rateLimitedFlapsClient wraps a FlapsClient so each call waits on the limiter.
+type rateLimitedFlapsClient struct {
+	client  FlapsClient
+	limiter *RateLimiter
+}
+
+func (c *rateLimitedFlapsClient) List(ctx context.Context, state string) ([]*fly.Machine, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	machines, err := c.client.List(ctx, state)
+	c.limiter.observe(err)
+	return machines, err
+}
+
+func (c *rateLimitedFlapsClient) Launch(ctx context.Context, input fly.LaunchMachineInput) (*fly.Machine, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	machine, err := c.client.Launch(ctx, input)
+	c.limiter.observe(err)
+	return machine, err
+}
+
+func (c *rateLimitedFlapsClient) Destroy(ctx context.Context, input fly.RemoveMachineInput, nonce string) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	err := c.client.Destroy(ctx, input, nonce)
+	c.limiter.observe(err)
+	return err
+}
+
+func (c *rateLimitedFlapsClient) Start(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Start(ctx, id, nonce)
+	c.limiter.observe(err)
+	return resp, err
+}
+
+func (c *rateLimitedFlapsClient) Stop(ctx context.Context, in fly.StopMachineInput, nonce string) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	err := c.client.Stop(ctx, in, nonce)
+	c.limiter.observe(err)
+	return err
+}
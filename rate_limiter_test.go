@@ -0,0 +1,129 @@
+package fas_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/mock"
+	fly "github.com/superfly/fly-go"
+)
+
+func TestRateLimiter_Transport(t *testing.T) {
+	t.Run("RetryAfterSeconds", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		l := fas.NewRateLimiter(10, 1)
+		l.Now = func() time.Time { return now }
+
+		client := &http.Client{Transport: l.Transport(nil)}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+
+		if got, want := l.PauseUntil(), now.Add(30*time.Second); !got.Equal(want) {
+			t.Fatalf("PauseUntil=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("RetryAfterDate", func(t *testing.T) {
+		now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", now.Add(5*time.Second).Format(http.TimeFormat))
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		l := fas.NewRateLimiter(10, 1)
+		l.Now = func() time.Time { return now }
+
+		client := &http.Client{Transport: l.Transport(nil)}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+
+		if got, want := l.PauseUntil(), now.Add(5*time.Second); !got.Equal(want) {
+			t.Fatalf("PauseUntil=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		l := fas.NewRateLimiter(10, 1)
+		client := &http.Client{Transport: l.Transport(nil)}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+
+		if got := l.PauseUntil(); !got.IsZero() {
+			t.Fatalf("unexpected pause: %v", got)
+		}
+	})
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	t.Run("Paused", func(t *testing.T) {
+		l := fas.NewRateLimiter(1000, 1)
+		l.Pause(50 * time.Millisecond)
+
+		start := time.Now()
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Fatalf("expected wait for pause, elapsed %v", elapsed)
+		}
+	})
+
+	t.Run("ContextCanceled", func(t *testing.T) {
+		l := fas.NewRateLimiter(1000, 1)
+		l.Pause(time.Hour)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := l.Wait(ctx); err != context.Canceled {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+// Ensure a rate limit error from the Fly API pauses all calls made by the pool.
+func TestReconcilerPool_RateLimiter_FlyClient(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return nil, &fly.ApiError{Message: "429 Too Many Requests", Status: http.StatusTooManyRequests}
+	}
+
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.AppName = "my-app"
+	p.ReconcileInterval = 10 * time.Millisecond
+	p.RateLimiter = fas.NewRateLimiter(1000, 1)
+	p.NewReconciler = fas.NewReconciler
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &mock.FlapsClient{}, nil
+	}
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	time.Sleep(5 * p.ReconcileInterval)
+	if p.RateLimiter.PauseUntil().IsZero() {
+		t.Fatal("expected rate limiter to be paused")
+	}
+}
@@ -5,19 +5,33 @@ import (
 	"fmt"
 	"log/slog"
 	"math"
+	"slices"
 	"sort"
 	"sync/atomic"
+	"time"
 
-	"github.com/expr-lang/expr"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/superfly/fly-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Reconciler represents the central part of the autoscaler that stores metrics,
 // computes the number of necessary machines, and performs scaling.
 type Reconciler struct {
 	metrics   map[string]float64
+	exprs     map[string]*Expr // compiled expressions, keyed by source
+	variables []compiledVariable
+	policyN   map[string]int            // policy results for the current reconciliation
+	history   map[string][]MetricSample // forecast history from range queries
+	fleet     ExprVars                  // machine counts from the last listing
 	regionSeq atomic.Int64
 
+	// Set by the pool, if instrumented.
+	collectDuration *prometheus.HistogramVec
+	collectErrors   *prometheus.CounterVec
+	collectorHealth *collectorHealth
+
 	// Client to connect to Machines API to scale app. Required.
 	Client FlapsClient
 
@@ -43,20 +57,57 @@ type Reconciler struct {
 	MinStartedMachineN string
 	MaxStartedMachineN string
 
+	// Policies used instead of the min/max expressions, if set.
+	CreatedMachinePolicy Policy
+	StartedMachinePolicy Policy
+
+	// Forecast models for individual metrics. Metrics without a forecaster
+	// are forecast from local history using the default smoothing factors.
+	Forecasters []*Forecaster
+
+	// Absolute bounds on machine counts, applied after expressions, policies
+	// & schedules. If GuardrailStrict is set, violations are returned as an
+	// error & the reconciliation is skipped.
+	Guardrails      []Guardrail
+	GuardrailStrict bool
+
+	// Calendar-based overrides applied on top of the computed machine counts.
+	Schedules []Schedule
+
+	// Named expressions evaluated before the machine count expressions.
+	// Values are available to expressions as if they were metrics.
+	Variables []Variable
+
 	// Initial machine state (started or stopped)
 	InitialMachineState string
 
+	// Minimum time to wait after a scaling action before performing another.
+	// Zero disables the cooldown period.
+	Cooldown time.Duration
+
 	// List of collectors to fetch metric values from.
 	Collectors []MetricCollector
 
+	// State tracked across reconciliations for the current app.
+	// The pool replaces this for each app before reconciling.
+	State *AppState
+
 	// Must also be registered in RegisterPromMetrics() for visibility.
 	Stats *ReconcilerStats
+
+	// Returns the current time. Used by time-based expression functions
+	// & overridden for testing and by "eval --at".
+	Now func() time.Time
 }
 
 func NewReconciler() *Reconciler {
 	return &Reconciler{
 		metrics: make(map[string]float64),
+		exprs:   make(map[string]*Expr),
+		policyN: make(map[string]int),
+		State:   NewAppState(""),
 		Stats:   &ReconcilerStats{},
+		Now:     time.Now,
 	}
 }
 
@@ -82,21 +133,248 @@ func (r *Reconciler) SetValue(name string, value float64) {
 	r.metrics[name] = value
 }
 
+// MetricNames returns the sorted names of all metrics available to expressions.
+// This includes the names of all collectors, variables & any values set with
+// SetValue().
+func (r *Reconciler) MetricNames() []string {
+	names := r.collectedMetricNames()
+	for _, v := range r.Variables {
+		if !slices.Contains(names, v.Name) {
+			names = append(names, v.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// collectedMetricNames returns the names of all metrics except variables.
+func (r *Reconciler) collectedMetricNames() []string {
+	m := make(map[string]struct{})
+	for _, c := range r.Collectors {
+		m[c.Name()] = struct{}{}
+	}
+	for name := range r.metrics {
+		m[name] = struct{}{}
+	}
+	for _, v := range r.Variables {
+		delete(m, v.Name)
+	}
+
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Compile compiles all variables & machine count expressions so that invalid
+// expressions are reported before the first reconciliation. Compiled programs
+// are cached & reused by each subsequent evaluation.
+func (r *Reconciler) Compile() error {
+	if err := r.compileVariables(); err != nil {
+		return err
+	}
+
+	for _, e := range []struct {
+		name string
+		src  string
+	}{
+		{"min created machine count", r.MinCreatedMachineN},
+		{"max created machine count", r.MaxCreatedMachineN},
+		{"min started machine count", r.MinStartedMachineN},
+		{"max started machine count", r.MaxStartedMachineN},
+	} {
+		if e.src == "" {
+			continue
+		}
+		if _, err := r.compile(e.src); err != nil {
+			return fmt.Errorf("%s: %w", e.name, err)
+		}
+	}
+
+	// Ensure policies only reference available metrics.
+	names := r.MetricNames()
+	for _, p := range []struct {
+		name   string
+		policy Policy
+	}{
+		{PolicyNameCreated, r.CreatedMachinePolicy},
+		{PolicyNameStarted, r.StartedMachinePolicy},
+	} {
+		if p.policy == nil {
+			continue
+		}
+		for _, metricName := range p.policy.MetricNames() {
+			if !slices.Contains(names, metricName) {
+				return fmt.Errorf("%s machine policy: unknown metric %q", p.name, metricName)
+			}
+		}
+	}
+
+	// Range queries can only be performed by collectors that support them.
+	for _, f := range r.Forecasters {
+		c := r.collector(f.Metric)
+		if c == nil {
+			return fmt.Errorf("forecast: unknown metric %q", f.Metric)
+		} else if _, ok := c.(RangeMetricCollector); f.Lookback > 0 && !ok {
+			return fmt.Errorf("forecast: metric %q does not support range queries", f.Metric)
+		}
+	}
+	return nil
+}
+
+// collector returns the collector for the named metric, if any.
+func (r *Reconciler) collector(name string) MetricCollector {
+	for _, c := range r.Collectors {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// forecaster returns the forecaster for the named metric, if any.
+func (r *Reconciler) forecaster(name string) *Forecaster {
+	for _, f := range r.Forecasters {
+		if f.Metric == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// compileVariables sorts variables by dependency & compiles each one against
+// the collected metrics & the variables evaluated before it.
+func (r *Reconciler) compileVariables() error {
+	sorted, err := SortVariables(r.Variables)
+	if err != nil {
+		return err
+	}
+
+	names := r.collectedMetricNames()
+	variables := make([]compiledVariable, 0, len(sorted))
+	for _, v := range sorted {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+
+		e, err := CompileExpr(v.Expr, names)
+		if err != nil {
+			return fmt.Errorf("variable %q: %w", v.Name, err)
+		}
+		variables = append(variables, compiledVariable{name: v.Name, expr: e})
+		names = append(names, v.Name)
+	}
+	r.variables = variables
+	return nil
+}
+
+// EvalVariables evaluates all variables in dependency order & sets each result
+// as a metric value. Results are also added to the metric history so history
+// functions can be used on variables.
+func (r *Reconciler) EvalVariables() error {
+	if len(r.variables) != len(r.Variables) {
+		if err := r.compileVariables(); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range r.variables {
+		vars := r.exprVars(0)
+		value, err := v.expr.Eval(r.metrics, &vars)
+		if err != nil {
+			return fmt.Errorf("variable %q: %w", v.name, err)
+		}
+		r.SetValue(v.name, value)
+		r.State.AddMetricSample(v.name, MetricSample{Timestamp: vars.Now, Value: value})
+	}
+	return nil
+}
+
+// compile returns the cached program for s or compiles it if not cached.
+func (r *Reconciler) compile(s string) (*Expr, error) {
+	if e := r.exprs[s]; e != nil {
+		return e, nil
+	}
+
+	e, err := CompileExpr(s, r.MetricNames())
+	if err != nil {
+		return nil, err
+	}
+	r.exprs[s] = e
+	return e, nil
+}
+
 // CollectMetrics fetches metrics from all collectors.
 func (r *Reconciler) CollectMetrics(ctx context.Context) error {
 	// Clear all metrics before each collection as the reconciler can be shared.
 	r.metrics = make(map[string]float64)
+	r.fleet = ExprVars{}
+	clear(r.policyN)
 
 	for _, c := range r.Collectors {
-		value, err := c.CollectMetric(ctx, r.AppName)
+		value, err := r.collectMetric(ctx, c)
 		if err != nil {
 			return fmt.Errorf("collect metric (%q): %w", c.Name(), err)
 		}
 		r.SetValue(c.Name(), value)
+		r.State.AddMetricSample(c.Name(), MetricSample{Timestamp: r.Now(), Value: value})
+	}
+
+	// Load long-term history for forecasts that use range queries.
+	r.history = make(map[string][]MetricSample)
+	for _, f := range r.Forecasters {
+		c, ok := r.collector(f.Metric).(RangeMetricCollector)
+		if f.Lookback == 0 || !ok {
+			continue
+		}
+
+		samples, err := f.History(ctx, c, r.AppName, r.Now())
+		if err != nil {
+			return fmt.Errorf("collect metric range (%q): %w", f.Metric, err)
+		}
+		r.history[f.Metric] = samples
 	}
 	return nil
 }
 
+// collectMetric fetches the current value from a single collector.
+func (r *Reconciler) collectMetric(ctx context.Context, c MetricCollector) (value float64, err error) {
+	ctx, span := tracer.Start(ctx, "collect_metric", trace.WithAttributes(attribute.String("fas.metric", c.Name())))
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	value, err = c.CollectMetric(ctx, r.AppName)
+	observeDuration(r.collectDuration, start, c.Name(), statusLabel(err))
+	r.collectorHealth.record(c.Name(), r.AppName, start, err)
+	if err != nil {
+		if r.collectErrors != nil {
+			r.collectErrors.WithLabelValues(c.Name()).Inc()
+		}
+		return 0, err
+	}
+
+	span.SetAttributes(attribute.Float64("fas.value", value))
+	return value, nil
+}
+
+// Forecast returns the predicted value of a metric after horizon has passed.
+// History is loaded by a range query if the metric's forecaster has a lookback
+// period. Otherwise, locally stored samples are used.
+func (r *Reconciler) Forecast(name string, horizon time.Duration) (float64, error) {
+	f := r.forecaster(name)
+	if f == nil {
+		f = &Forecaster{Metric: name}
+	}
+
+	samples, ok := r.history[name]
+	if !ok {
+		samples = r.State.Metrics[name]
+	}
+	return f.Forecast(samples, r.Now(), horizon)
+}
+
 func reachbleMachines(machines []*fly.Machine) []*fly.Machine {
 	var reachable []*fly.Machine
 	for _, m := range machines {
@@ -110,25 +388,6 @@ func reachbleMachines(machines []*fly.Machine) []*fly.Machine {
 // Reconcile scales the number of machines up, if needed. Machines should shut
 // themselves down to scale down. Returns the number of started machines, if any.
 func (r *Reconciler) Reconcile(ctx context.Context) error {
-	// Compute number of machines based on expr & metrics
-	minCreatedN, hasMinCreatedN, err := r.CalcMinCreatedMachineN()
-	if err != nil {
-		return fmt.Errorf("compute minimum created machine count: %w", err)
-	}
-	maxCreatedN, hasMaxCreatedN, err := r.CalcMaxCreatedMachineN()
-	if err != nil {
-		return fmt.Errorf("compute minimum created machine count: %w", err)
-	}
-
-	minStartedN, hasMinStartedN, err := r.CalcMinStartedMachineN()
-	if err != nil {
-		return fmt.Errorf("compute minimum started machine count: %w", err)
-	}
-	maxStartedN, hasMaxStartedN, err := r.CalcMaxStartedMachineN()
-	if err != nil {
-		return fmt.Errorf("compute minimum started machine count: %w", err)
-	}
-
 	// Fetch list of running machines.
 	all, err := r.listMachines(ctx)
 	if err != nil {
@@ -139,6 +398,18 @@ func (r *Reconciler) Reconcile(ctx context.Context) error {
 	filtered := machinesInGroup(machines, r.ProcessGroup)
 	m := machinesByState(filtered)
 
+	// Expose current machine counts to expressions before evaluating them.
+	r.SetMachines(filtered)
+	decision, paused, err := r.decide(ctx)
+	if err != nil {
+		return err
+	}
+
+	minCreatedN, hasMinCreatedN := deref(decision.MinCreatedN, 0), decision.MinCreatedN != nil
+	maxCreatedN, hasMaxCreatedN := deref(decision.MaxCreatedN, 0), decision.MaxCreatedN != nil
+	minStartedN, hasMinStartedN := deref(decision.MinStartedN, 0), decision.MinStartedN != nil
+	maxStartedN, hasMaxStartedN := deref(decision.MaxStartedN, 0), decision.MaxStartedN != nil
+
 	// Log out stats so we know exactly what the state of the world is.
 	slog.Info("reconciling",
 		slog.String("app", r.AppName),
@@ -157,6 +428,37 @@ func (r *Reconciler) Reconcile(ctx context.Context) error {
 			),
 		),
 	)
+	if len(decision.Schedules) > 0 {
+		slog.Info("schedules active",
+			slog.String("app", r.AppName),
+			slog.Any("schedules", decision.Schedules))
+	}
+	if decision.Override {
+		slog.Info("override active",
+			slog.String("app", r.AppName),
+			slog.Time("expires_at", r.State.Override.ExpiresAt))
+	}
+
+	// Skip scaling entirely while a schedule pauses scaling for the app.
+	if paused {
+		slog.Debug("scaling paused by schedule, skipping scaling",
+			slog.String("app", r.AppName),
+			slog.Any("schedules", decision.Schedules))
+		r.recordDecision(decision)
+		r.Stats.NoScale.Add(1)
+		return nil
+	}
+
+	// Skip scaling entirely if we recently performed a scaling action. Manual
+	// overrides are applied immediately, regardless of the cooldown.
+	if until := r.State.CooldownUntil; decision.Timestamp.Before(until) && !decision.Override {
+		slog.Debug("cooldown in effect, skipping scaling",
+			slog.String("app", r.AppName),
+			slog.Time("until", until))
+		r.recordDecision(decision)
+		r.Stats.NoScale.Add(1)
+		return nil
+	}
 
 	// Determine if we need to create or destroy machines.
 	createdN := len(filtered)
@@ -165,28 +467,162 @@ func (r *Reconciler) Reconcile(ctx context.Context) error {
 			return fmt.Errorf("no machine available to clone for scale up")
 		}
 
+		decision.Action, decision.N = ActionCreate, minCreatedN-createdN
+		r.recordDecision(decision)
+
 		machine := filtered[0]
 		config := machine.Config
 		config.Image = machine.FullImageRef()
-		return r.createN(ctx, filtered[0].Config, machine.Region, minCreatedN-createdN)
+		return r.recordScaled(decision, r.createN(ctx, filtered[0].Config, machine.Region, minCreatedN-createdN))
 	}
 	if hasMaxCreatedN && createdN > maxCreatedN {
-		return r.destroyN(ctx, m, createdN-maxCreatedN)
+		decision.Action, decision.N = ActionDestroy, createdN-maxCreatedN
+		r.recordDecision(decision)
+		return r.recordScaled(decision, r.destroyN(ctx, m, createdN-maxCreatedN))
 	}
 
 	// Determine if we need to start/stop machines.
 	startedN := len(m[fly.MachineStateStarted])
 	if hasMinStartedN && startedN < minStartedN {
-		return r.startN(ctx, m[fly.MachineStateStopped], minStartedN-startedN)
+		decision.Action, decision.N = ActionStart, minStartedN-startedN
+		r.recordDecision(decision)
+		return r.recordScaled(decision, r.startN(ctx, m[fly.MachineStateStopped], minStartedN-startedN))
 	}
 	if hasMaxStartedN && startedN > maxStartedN {
-		return r.stopN(ctx, m[fly.MachineStateStarted], startedN-maxStartedN)
+		decision.Action, decision.N = ActionStop, startedN-maxStartedN
+		r.recordDecision(decision)
+		return r.recordScaled(decision, r.stopN(ctx, m[fly.MachineStateStarted], startedN-maxStartedN))
 	}
 
+	r.recordDecision(decision)
 	r.Stats.NoScale.Add(1)
 	return nil
 }
 
+// decide evaluates variables & the machine count expressions or policies and
+// then applies schedules, overrides & guardrails to compute the app's targets.
+// Returns true if scaling is paused by a schedule.
+func (r *Reconciler) decide(ctx context.Context) (_ *Decision, paused bool, err error) {
+	_, span := tracer.Start(ctx, "evaluate")
+	defer func() { endSpan(span, err) }()
+
+	if err := r.EvalVariables(); err != nil {
+		return nil, false, fmt.Errorf("evaluate variables: %w", err)
+	}
+
+	// Compute number of machines based on expr & metrics
+	minCreatedN, hasMinCreatedN, err := r.CalcMinCreatedMachineN()
+	if err != nil {
+		return nil, false, fmt.Errorf("compute minimum created machine count: %w", err)
+	}
+	maxCreatedN, hasMaxCreatedN, err := r.CalcMaxCreatedMachineN()
+	if err != nil {
+		return nil, false, fmt.Errorf("compute minimum created machine count: %w", err)
+	}
+
+	minStartedN, hasMinStartedN, err := r.CalcMinStartedMachineN()
+	if err != nil {
+		return nil, false, fmt.Errorf("compute minimum started machine count: %w", err)
+	}
+	maxStartedN, hasMaxStartedN, err := r.CalcMaxStartedMachineN()
+	if err != nil {
+		return nil, false, fmt.Errorf("compute minimum started machine count: %w", err)
+	}
+
+	decision := &Decision{Timestamp: r.Now(), Action: ActionNoScale}
+	if hasMinCreatedN {
+		decision.MinCreatedN = &minCreatedN
+	}
+	if hasMaxCreatedN {
+		decision.MaxCreatedN = &maxCreatedN
+	}
+	if hasMinStartedN {
+		decision.MinStartedN = &minStartedN
+	}
+	if hasMaxStartedN {
+		decision.MaxStartedN = &maxStartedN
+	}
+
+	// Apply floors & ceilings from any active schedules.
+	if paused, err = r.ApplySchedules(decision); err != nil {
+		return nil, false, fmt.Errorf("apply schedules: %w", err)
+	}
+
+	// Manual overrides take precedence over schedules, including pauses.
+	if r.ApplyOverride(decision) {
+		paused = false
+	}
+
+	// Enforce absolute bounds last so nothing can exceed them.
+	if err := r.ApplyGuardrails(decision); err != nil {
+		return nil, false, err
+	}
+
+	span.SetAttributes(r.exprAttributes()...)
+	span.SetAttributes(decisionAttributes(decision)...)
+	span.SetAttributes(attribute.Bool("fas.paused", paused))
+	return decision, paused, nil
+}
+
+// recordMachineID adds a machine that was acted upon to the current decision.
+func (r *Reconciler) recordMachineID(id string) {
+	if d := r.State.LastDecision; d != nil {
+		d.MachineIDs = append(d.MachineIDs, id)
+	}
+}
+
+// MachineCounts returns the machine counts from the most recent listing.
+func (r *Reconciler) MachineCounts() ExprVars {
+	return r.fleet
+}
+
+// SetMachines updates the machine counts available to expressions. Machines
+// should already be filtered to reachable machines in the process group.
+func (r *Reconciler) SetMachines(machines []*fly.Machine) {
+	fleet := ExprVars{
+		CreatedNByRegion: make(map[string]int),
+		StartedNByRegion: make(map[string]int),
+		StoppedNByRegion: make(map[string]int),
+	}
+	for _, m := range machines {
+		fleet.CreatedN++
+		fleet.CreatedNByRegion[m.Region]++
+
+		switch m.State {
+		case fly.MachineStateStarted:
+			fleet.StartedN++
+			fleet.StartedNByRegion[m.Region]++
+		case fly.MachineStateStopped:
+			fleet.StoppedN++
+			fleet.StoppedNByRegion[m.Region]++
+		}
+	}
+	r.fleet = fleet
+	clear(r.policyN)
+}
+
+// recordDecision stores the decision on the app state. Scaling actions are
+// recorded before they run so that affected machine IDs can be added.
+func (r *Reconciler) recordDecision(decision *Decision) {
+	r.State.LastDecision = decision
+}
+
+// recordScaled updates the app state after a scaling action completes with
+// err. The scale time is only updated if a machine was acted upon & the
+// cooldown period is only restarted if the action succeeded so that failed
+// actions are retried on the next reconciliation. Returns err.
+func (r *Reconciler) recordScaled(decision *Decision, err error) error {
+	if len(decision.MachineIDs) == 0 {
+		return err
+	}
+
+	r.State.LastScaledAt = decision.Timestamp
+	if err == nil && r.Cooldown > 0 {
+		r.State.CooldownUntil = decision.Timestamp.Add(r.Cooldown)
+	}
+	return err
+}
+
 func (r *Reconciler) createN(ctx context.Context, config *fly.MachineConfig, defaultRegion string, n int) error {
 	r.Stats.BulkCreate.Add(1)
 
@@ -194,13 +630,12 @@ func (r *Reconciler) createN(ctx context.Context, config *fly.MachineConfig, def
 	logger.Info("begin bulk create")
 
 	// Attempt to start as many machines as needed.
+	guard := r.newRegionGuardrail(r.fleet.CreatedNByRegion)
 	remaining := n
 	for remaining > 0 {
-		// Cycle through possible regions, if set.
-		// Otherwise use the region of the source machine we're cloning.
-		region := r.NextRegion()
-		if region == "" {
-			region = defaultRegion
+		region, ok := r.nextRegionWithin(guard, defaultRegion)
+		if !ok {
+			break
 		}
 
 		machine, err := r.createMachine(ctx, config, region)
@@ -208,6 +643,7 @@ func (r *Reconciler) createN(ctx context.Context, config *fly.MachineConfig, def
 			logger.Error("cannot create machine, skipping", slog.Any("err", err))
 			continue
 		}
+		guard.add(region)
 
 		logger.Info("machine created",
 			slog.String("id", machine.ID),
@@ -219,9 +655,28 @@ func (r *Reconciler) createN(ctx context.Context, config *fly.MachineConfig, def
 	newlyCreatedN := n - remaining
 	logger.Info("bulk create completed", slog.Int("n", newlyCreatedN))
 
+	if remaining > 0 {
+		return guard.violation("create", remaining)
+	}
 	return nil
 }
 
+// nextRegionWithin returns the next region that a machine can be created in
+// without exceeding its guardrail. Regions are cycled through, if set.
+// Otherwise the region of the source machine we're cloning is used.
+func (r *Reconciler) nextRegionWithin(guard *regionGuardrail, defaultRegion string) (string, bool) {
+	for i := 0; i < max(len(r.Regions), 1); i++ {
+		region := r.NextRegion()
+		if region == "" {
+			region = defaultRegion
+		}
+		if guard.canAdd(region) {
+			return region, true
+		}
+	}
+	return "", false
+}
+
 func (r *Reconciler) destroyN(ctx context.Context, machinesByState map[string][]*fly.Machine, n int) error {
 	r.Stats.BulkDestroy.Add(1)
 
@@ -229,11 +684,15 @@ func (r *Reconciler) destroyN(ctx context.Context, machinesByState map[string][]
 	logger.Info("begin bulk destroy")
 
 	// Attempt to destroy as many machines as needed.
-	remaining := n
+	guard := r.newRegionGuardrail(r.fleet.CreatedNByRegion)
+	remaining, blocked := n, 0
 	for remaining > 0 {
 		machine := chooseNextDestroyCandidate(machinesByState)
 		if machine == nil {
 			break
+		} else if !guard.canRemove(machine.Region) {
+			blocked++
+			continue
 		}
 
 		if err := r.destroyMachine(ctx, machine.ID); err != nil {
@@ -241,6 +700,7 @@ func (r *Reconciler) destroyN(ctx context.Context, machinesByState map[string][]
 			remaining-- // don't retry so we don't kill too many machines
 			continue
 		}
+		guard.remove(machine.Region)
 
 		logger.Info("machine destroyed",
 			slog.String("id", machine.ID),
@@ -252,6 +712,9 @@ func (r *Reconciler) destroyN(ctx context.Context, machinesByState map[string][]
 	newlyDestroyedN := n - remaining
 	logger.Info("bulk destroy completed", slog.Int("n", newlyDestroyedN))
 
+	if remaining > 0 && blocked > 0 {
+		return guard.violation("destroy", remaining)
+	}
 	return nil
 }
 
@@ -288,10 +751,14 @@ func (r *Reconciler) startN(ctx context.Context, stoppedMachines []*fly.Machine,
 	sort.Slice(stoppedMachines, func(i, j int) bool { return stoppedMachines[i].ID < stoppedMachines[j].ID })
 
 	// Attempt to start as many machines as needed.
-	remaining := n
+	guard := r.newRegionGuardrail(r.fleet.StartedNByRegion)
+	remaining, blocked := n, 0
 	for _, machine := range stoppedMachines {
 		if remaining <= 0 {
 			break
+		} else if !guard.canAdd(machine.Region) {
+			blocked++
+			continue
 		}
 
 		if err := r.startMachine(ctx, machine.ID); err != nil {
@@ -300,6 +767,7 @@ func (r *Reconciler) startN(ctx context.Context, stoppedMachines []*fly.Machine,
 				slog.Any("err", err))
 			continue
 		}
+		guard.add(machine.Region)
 
 		logger.Info("machine started", slog.String("id", machine.ID))
 		remaining--
@@ -308,6 +776,9 @@ func (r *Reconciler) startN(ctx context.Context, stoppedMachines []*fly.Machine,
 	newlyStartedN := n - remaining
 	logger.Info("bulk start completed", slog.Int("n", newlyStartedN))
 
+	if remaining > 0 && blocked > 0 {
+		return guard.violation("start", remaining)
+	}
 	return nil
 }
 
@@ -321,10 +792,14 @@ func (r *Reconciler) stopN(ctx context.Context, startedMachines []*fly.Machine,
 	sort.Slice(startedMachines, func(i, j int) bool { return startedMachines[i].ID < startedMachines[j].ID })
 
 	// Attempt to stop as many machines as needed.
-	remaining := n
+	guard := r.newRegionGuardrail(r.fleet.StartedNByRegion)
+	remaining, blocked := n, 0
 	for _, machine := range startedMachines {
 		if remaining <= 0 {
 			break
+		} else if !guard.canRemove(machine.Region) {
+			blocked++
+			continue
 		}
 
 		if err := r.stopMachine(ctx, machine.ID); err != nil {
@@ -333,6 +808,7 @@ func (r *Reconciler) stopN(ctx context.Context, startedMachines []*fly.Machine,
 				slog.Any("err", err))
 			continue
 		}
+		guard.remove(machine.Region)
 
 		logger.Info("machine stopped", slog.String("id", machine.ID))
 		remaining--
@@ -341,18 +817,28 @@ func (r *Reconciler) stopN(ctx context.Context, startedMachines []*fly.Machine,
 	newlyStoppedN := n - remaining
 	logger.Info("bulk stop completed", slog.Int("n", newlyStoppedN))
 
+	if remaining > 0 && blocked > 0 {
+		return guard.violation("stop", remaining)
+	}
 	return nil
 }
 
-func (r *Reconciler) listMachines(ctx context.Context) ([]*fly.Machine, error) {
+func (r *Reconciler) listMachines(ctx context.Context) (_ []*fly.Machine, err error) {
+	ctx, span := tracer.Start(ctx, "list_machines")
+	defer func() { endSpan(span, err) }()
+
 	machines, err := r.Client.List(ctx, "")
 	if err != nil {
 		return nil, err
 	}
+	span.SetAttributes(attribute.Int("fas.machines", len(machines)))
 	return machines, nil
 }
 
-func (r *Reconciler) createMachine(ctx context.Context, config *fly.MachineConfig, region string) (*fly.Machine, error) {
+func (r *Reconciler) createMachine(ctx context.Context, config *fly.MachineConfig, region string) (_ *fly.Machine, err error) {
+	ctx, span := tracer.Start(ctx, "create_machine", trace.WithAttributes(attribute.String("fas.region", region)))
+	defer func() { endSpan(span, err) }()
+
 	machine, err := r.Client.Launch(ctx, fly.LaunchMachineInput{
 		Config:     config,
 		Region:     region,
@@ -362,40 +848,58 @@ func (r *Reconciler) createMachine(ctx context.Context, config *fly.MachineConfi
 		r.Stats.MachineCreateFailed.Add(1)
 		return nil, err
 	}
+	span.SetAttributes(attribute.String("fas.machine_id", machine.ID))
+	r.recordMachineID(machine.ID)
 	r.Stats.MachineCreated.Add(1)
 	return machine, nil
 }
 
-func (r *Reconciler) destroyMachine(ctx context.Context, id string) error {
+func (r *Reconciler) destroyMachine(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "destroy_machine", trace.WithAttributes(attribute.String("fas.machine_id", id)))
+	defer func() { endSpan(span, err) }()
+
 	if err := r.Client.Destroy(ctx, fly.RemoveMachineInput{ID: id, Kill: true}, ""); err != nil {
 		r.Stats.MachineDestroyFailed.Add(1)
 		return err
 	}
+	r.recordMachineID(id)
 	r.Stats.MachineDestroyed.Add(1)
 	return nil
 }
 
-func (r *Reconciler) startMachine(ctx context.Context, id string) error {
+func (r *Reconciler) startMachine(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "start_machine", trace.WithAttributes(attribute.String("fas.machine_id", id)))
+	defer func() { endSpan(span, err) }()
+
 	if _, err := r.Client.Start(ctx, id, ""); err != nil {
 		r.Stats.MachineStartFailed.Add(1)
 		return err
 	}
+	r.recordMachineID(id)
 	r.Stats.MachineStarted.Add(1)
 	return nil
 }
 
-func (r *Reconciler) stopMachine(ctx context.Context, id string) error {
+func (r *Reconciler) stopMachine(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "stop_machine", trace.WithAttributes(attribute.String("fas.machine_id", id)))
+	defer func() { endSpan(span, err) }()
+
 	if err := r.Client.Stop(ctx, fly.StopMachineInput{ID: id}, ""); err != nil {
 		r.Stats.MachineStopFailed.Add(1)
 		return err
 	}
+	r.recordMachineID(id)
 	r.Stats.MachineStopped.Add(1)
 	return nil
 }
 
 // CalcMinCreatedMachineN returns the minimum number of created machines.
 func (r *Reconciler) CalcMinCreatedMachineN() (int, bool, error) {
-	v, ok, err := r.evalInt(r.MinCreatedMachineN)
+	if r.CreatedMachinePolicy != nil {
+		return r.calcCreatedPolicy()
+	}
+
+	v, ok, err := r.evalInt(r.MinCreatedMachineN, r.previousTarget(func(d *Decision) *int { return d.MinCreatedN }))
 	if err != nil || !ok {
 		return v, ok, err
 	}
@@ -410,7 +914,11 @@ func (r *Reconciler) CalcMinCreatedMachineN() (int, bool, error) {
 
 // CalcMaxCreatedMachineN returns the maximum number of created machines.
 func (r *Reconciler) CalcMaxCreatedMachineN() (int, bool, error) {
-	v, ok, err := r.evalInt(r.MaxCreatedMachineN)
+	if r.CreatedMachinePolicy != nil {
+		return r.calcCreatedPolicy()
+	}
+
+	v, ok, err := r.evalInt(r.MaxCreatedMachineN, r.previousTarget(func(d *Decision) *int { return d.MaxCreatedN }))
 	if err != nil || !ok {
 		return v, ok, err
 	}
@@ -425,37 +933,94 @@ func (r *Reconciler) CalcMaxCreatedMachineN() (int, bool, error) {
 
 // CalcMinStartedMachineN returns the minimum number of started machines.
 func (r *Reconciler) CalcMinStartedMachineN() (int, bool, error) {
-	return r.evalInt(r.MinStartedMachineN)
+	if r.StartedMachinePolicy != nil {
+		return r.calcPolicy(PolicyNameStarted, r.StartedMachinePolicy, r.fleet.StartedN)
+	}
+	return r.evalInt(r.MinStartedMachineN, r.previousTarget(func(d *Decision) *int { return d.MinStartedN }))
 }
 
 // CalcMaxStartedMachineN returns the maximum number of started machines.
 func (r *Reconciler) CalcMaxStartedMachineN() (int, bool, error) {
-	return r.evalInt(r.MaxStartedMachineN)
+	if r.StartedMachinePolicy != nil {
+		return r.calcPolicy(PolicyNameStarted, r.StartedMachinePolicy, r.fleet.StartedN)
+	}
+	return r.evalInt(r.MaxStartedMachineN, r.previousTarget(func(d *Decision) *int { return d.MaxStartedN }))
 }
 
-// evalInt compiles & runs an expression. Returns a rounded integer.
-// Returns a true if the second argument if s is not blank. Otherwise returns false.
-func (r *Reconciler) evalInt(s string) (int, bool, error) {
-	if s == "" {
-		return 0, false, nil
+// calcCreatedPolicy returns the created machine count from the policy. As
+// with expressions, the count never drops below one machine.
+func (r *Reconciler) calcCreatedPolicy() (int, bool, error) {
+	v, ok, err := r.calcPolicy(PolicyNameCreated, r.CreatedMachinePolicy, r.fleet.CreatedN)
+	if err != nil {
+		return v, ok, err
 	}
+	return max(v, 1), true, nil
+}
 
-	env := map[string]any{}
-	for k, v := range r.metrics {
-		env[k] = v
+// calcPolicy evaluates a policy once per reconciliation. The policy is used as
+// both the min & max so its result is cached until the next collection.
+func (r *Reconciler) calcPolicy(name string, policy Policy, current int) (int, bool, error) {
+	if v, ok := r.policyN[name]; ok {
+		return v, true, nil
 	}
 
-	program, err := expr.Compile(s, expr.AsFloat64(), expr.Env(env))
+	v, err := policy.Desired(&PolicyInput{
+		Name:     name,
+		Current:  current,
+		Metrics:  r.metrics,
+		State:    r.State,
+		Now:      r.Now(),
+		Forecast: r.Forecast,
+	})
 	if err != nil {
-		return 0, true, fmt.Errorf("compile expression: %w", err)
+		return 0, true, fmt.Errorf("%s machine policy: %w", name, err)
 	}
+	r.policyN[name] = v
+	return v, true, nil
+}
+
+// exprVars returns the built-in variables for evaluating an expression.
+func (r *Reconciler) exprVars(previousTarget int) ExprVars {
+	vars := r.fleet
+	vars.AppName = r.AppName
+	vars.PreviousTarget = previousTarget
+	vars.LastScaledAt = r.State.LastScaledAt
+	vars.History = r.State.Metrics
+	vars.Forecast = r.Forecast
+	vars.Now = r.Now()
+	return vars
+}
 
-	v, err := expr.Run(program, env)
+// previousTarget returns a target from the last decision or zero if unset.
+func (r *Reconciler) previousTarget(fn func(*Decision) *int) int {
+	if r.State.LastDecision == nil {
+		return 0
+	} else if v := fn(r.State.LastDecision); v != nil {
+		return *v
+	}
+	return 0
+}
+
+// evalInt runs a cached expression, compiling it on first use. Returns a
+// rounded integer. Returns a true if the second argument if s is not blank.
+// Otherwise returns false.
+func (r *Reconciler) evalInt(s string, previousTarget int) (int, bool, error) {
+	if s == "" {
+		return 0, false, nil
+	}
+
+	e, err := r.compile(s)
 	if err != nil {
-		return 0, true, fmt.Errorf("execute expression: %w", err)
+		return 0, true, err
 	}
 
-	f := math.Round(v.(float64))
+	vars := r.exprVars(previousTarget)
+	v, err := e.Eval(r.metrics, &vars)
+	if err != nil {
+		return 0, true, err
+	}
+
+	f := math.Round(v)
 	if math.IsNaN(f) {
 		return 0, true, ErrExprNaN
 	} else if math.IsInf(f, 0) {
@@ -487,6 +1052,11 @@ func machinesInGroup(machines []*fly.Machine, group string) []*fly.Machine {
 	return m
 }
 
+type compiledVariable struct {
+	name string
+	expr *Expr
+}
+
 type ReconcilerStats struct {
 	// Outcomes, incremented for each reconciliation.
 	BulkCreate  atomic.Int64
@@ -504,4 +1074,15 @@ type ReconcilerStats struct {
 	MachineStartFailed   atomic.Int64
 	MachineStopped       atomic.Int64
 	MachineStopFailed    atomic.Int64
+
+	// Number of targets or actions that exceeded a guardrail.
+	GuardrailViolation atomic.Int64
+
+	// Reconciliations that were skipped or failed before or during scaling.
+	// These are incremented by the pool.
+	Paused            atomic.Int64
+	ReleaseInProgress atomic.Int64
+	ReleaseFailed     atomic.Int64
+	CollectFailed     atomic.Int64
+	ReconcileFailed   atomic.Int64
 }
@@ -5,20 +5,32 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	DefaultConcurrency            = 1
-	DefaultReconcileTimeout       = 30 * time.Second
-	DefaultReconcileInterval      = 15 * time.Second
-	DefaultAppListRefreshInterval = 60 * time.Second
-	DefaultProcessGroup           = "app"
+	DefaultConcurrency                 = 1
+	DefaultReconcileTimeout            = 30 * time.Second
+	DefaultReconcileInterval           = 15 * time.Second
+	DefaultAppListRefreshInterval      = 60 * time.Second
+	DefaultLeaderElectionInterval      = 5 * time.Second
+	DefaultMembershipHeartbeatInterval = 30 * time.Second
+	DefaultProcessGroup                = "app"
+
+	DefaultCircuitBreakerThreshold  = 5
+	DefaultCircuitBreakerBackoff    = 30 * time.Second
+	DefaultCircuitBreakerMaxBackoff = 10 * time.Minute
 )
 
 // ReconcilerPool represents a set of reconcilers that act as a worker pool.
@@ -33,22 +45,109 @@ type ReconcilerPool struct {
 	ctx    context.Context
 	cancel context.CancelCauseFunc
 
-	ch    chan appInfo // work queue
-	orgID string       // cached organization id
-	apps  struct {
+	ch       chan reconcileJob // work queue
+	wake     chan struct{}     // notifies the scheduler of changes
+	refresh  chan struct{}     // notifies the app list monitor of changes
+	orgID    string            // cached organization id
+	leader   atomic.Bool       // true if this instance may reconcile
+	queueLag atomic.Int64      // most recent queue lag, in nanoseconds
+	apps     struct {
 		sync.Mutex
 		m map[string]appInfo
 	}
 
+	// Consistent hash ring of live instances, if sharding.
+	shard struct {
+		sync.Mutex
+		members []string
+		ring    *HashRing
+	}
+
+	// Compiled patterns of AppReconcileIntervals, in the same order.
+	appIntervalRes []*regexp.Regexp
+
+	// Per-app schedule of the next reconciliation.
+	schedules struct {
+		sync.Mutex
+		m map[string]*appSchedule
+	}
+
+	// Per-app state, loaded from the store on Open().
+	states struct {
+		sync.Mutex
+		m map[string]*AppState
+	}
+
+	// Serializes writes of app state to the store so an override set while
+	// an app is being reconciled is not overwritten by older state.
+	stateWriteMu sync.Mutex
+
+	// Per-app metrics from the most recent reconciliation.
+	appMetrics struct {
+		sync.Mutex
+		m map[string]*appMetrics
+	}
+
+	// Latency histograms, registered in RegisterPromMetrics().
+	reconcileDuration *prometheus.HistogramVec
+	collectDuration   *prometheus.HistogramVec
+	apiCallDuration   *prometheus.HistogramVec
+
+	// Number of failed metric collections, by collector.
+	collectErrors *prometheus.CounterVec
+
+	// Most recent results of each metric collector.
+	collectorHealth *collectorHealth
+
 	// Time allowed to perform reconciliation for a single app.
 	ReconcileTimeout time.Duration
 
 	// Frequency to run the reconciliation loop for each app.
 	ReconcileInterval time.Duration
 
+	// Overrides the reconcile interval for apps matching a wildcard pattern.
+	// The first matching pattern is used.
+	AppReconcileIntervals []AppReconcileInterval
+
+	// Maximum random delay added to each app's reconcile interval. This
+	// spreads out reconciliation so apps do not run in lockstep.
+	ReconcileJitter time.Duration
+
 	// Frequency to update the list of matching apps when using wildcards.
 	AppListRefreshInterval time.Duration
 
+	// Limits the rate of all Fly & Machines API calls made by the pool.
+	// If nil, calls are not limited.
+	RateLimiter *RateLimiter
+
+	// Number of consecutive failed reconciliations before an app's circuit
+	// breaker opens and the app is backed off. Zero disables the breaker.
+	CircuitBreakerThreshold int
+
+	// Initial time an open circuit breaker waits before allowing another
+	// attempt. Doubles on each subsequent failure, up to the max backoff.
+	CircuitBreakerBackoff    time.Duration
+	CircuitBreakerMaxBackoff time.Duration
+
+	// Elects a single leader when running multiple autoscaler instances.
+	// Only the leader reconciles. If nil, this instance is always the leader.
+	LeaderElector LeaderElector
+
+	// Frequency to acquire or renew leadership.
+	LeaderElectionInterval time.Duration
+
+	// Tracks live autoscaler instances so that apps matching the wildcard are
+	// sharded across them. Each instance only reconciles apps it owns on a
+	// consistent hash ring. If nil, this instance reconciles all apps.
+	Membership Membership
+
+	// Unique identifier of this instance within the membership.
+	InstanceID string
+
+	// Frequency to send membership heartbeats. This must be shorter than the
+	// membership TTL so the instance is not expired between heartbeats.
+	MembershipHeartbeatInterval time.Duration
+
 	// Name of application to scale. Supports wildcards for multiple apps.
 	// All applications must be in the same org.
 	AppName string
@@ -59,10 +158,30 @@ type ReconcilerPool struct {
 	// NewFlapsClient is a constructor for building a FLAPS client for a given app.
 	NewFlapsClient NewFlapsClientFunc
 
+	// Store persists per-app state across restarts. Defaults to an in-memory store.
+	Store Store
+
 	// NewReconciler is a constructor for building reconcilers.
 	// Called one or more times on Open().
 	NewReconciler func() *Reconciler
 
+	// If true, labeled metrics are exported for each app. Each app adds a set
+	// of series so large orgs can limit exported apps to those matching
+	// AppMetricsApps & to the first AppMetricsMaxApps apps, sorted by name.
+	// Machine counts are only broken down by region if AppMetricsRegions is set.
+	AppMetrics        bool
+	AppMetricsApps    []string // wildcard patterns; empty matches all apps
+	AppMetricsMaxApps int      // zero means no limit
+	AppMetricsRegions bool
+
+	// Records each scaling decision & failed reconciliation, if set.
+	// No-op decisions are only recorded if AuditNoScale is true.
+	AuditLog     AuditLog
+	AuditNoScale bool
+
+	// Sends notifications for notable scaling events & failures, if set.
+	Notifier *Notifier
+
 	// Shared stats for all reconcilers.
 	Stats ReconcilerStats
 }
@@ -76,14 +195,34 @@ func NewReconcilerPool(flyClient FlyClient, concurrency int) *ReconcilerPool {
 	p := &ReconcilerPool{
 		flyClient:   flyClient,
 		reconcilers: make([]*Reconciler, concurrency),
-		ch:          make(chan appInfo),
+		ch:          make(chan reconcileJob),
+		wake:        make(chan struct{}, 1),
+		refresh:     make(chan struct{}, 1),
 
 		ReconcileTimeout:       DefaultReconcileTimeout,
 		ReconcileInterval:      DefaultReconcileInterval,
 		AppListRefreshInterval: DefaultAppListRefreshInterval,
+		LeaderElectionInterval: DefaultLeaderElectionInterval,
+
+		MembershipHeartbeatInterval: DefaultMembershipHeartbeatInterval,
+
+		CircuitBreakerThreshold:  DefaultCircuitBreakerThreshold,
+		CircuitBreakerBackoff:    DefaultCircuitBreakerBackoff,
+		CircuitBreakerMaxBackoff: DefaultCircuitBreakerMaxBackoff,
+
+		Store: NewMemoryStore(),
+
+		reconcileDuration: newReconcileDurationHistogram(),
+		collectDuration:   newCollectDurationHistogram(),
+		apiCallDuration:   newAPICallDurationHistogram(),
+		collectErrors:     newCollectErrorCounter(),
+		collectorHealth:   newCollectorHealth(),
 	}
 	p.ctx, p.cancel = context.WithCancelCause(context.Background())
 	p.apps.m = make(map[string]appInfo)
+	p.states.m = make(map[string]*AppState)
+	p.schedules.m = make(map[string]*appSchedule)
+	p.appMetrics.m = make(map[string]*appMetrics)
 
 	return p
 }
@@ -96,10 +235,40 @@ func (p *ReconcilerPool) Open() error {
 		return fmt.Errorf("flaps client constructor required")
 	}
 
+	// Compile interval patterns once as they are matched on every reschedule.
+	p.appIntervalRes = make([]*regexp.Regexp, len(p.AppReconcileIntervals))
+	for i, item := range p.AppReconcileIntervals {
+		re, err := regexp.Compile(FormatWildcardAsRegexp(item.Pattern))
+		if err != nil {
+			return fmt.Errorf("invalid app reconcile interval pattern %q: %w", item.Pattern, err)
+		}
+		p.appIntervalRes[i] = re
+	}
+
+	// Record the duration of each API call, excluding time spent waiting on
+	// the rate limiter.
+	p.flyClient = &instrumentedFlyClient{client: p.flyClient, duration: p.apiCallDuration}
+
+	// Share a single rate limit across all API calls.
+	if p.RateLimiter != nil {
+		p.flyClient = &rateLimitedFlyClient{client: p.flyClient, limiter: p.RateLimiter}
+	}
+
+	// Restore previous app state so we retain knowledge of recent scaling.
+	if err := p.loadAppStates(p.ctx); err != nil {
+		return fmt.Errorf("load app states: %w", err)
+	}
+
 	// Instantiate reconcilers.
 	for i := range p.reconcilers {
 		r := p.NewReconciler()
 		r.Stats = &p.Stats // share the same stats object
+		r.collectDuration = p.collectDuration
+		r.collectErrors = p.collectErrors
+		r.collectorHealth = p.collectorHealth
+		if err := r.Compile(); err != nil {
+			return fmt.Errorf("compile expressions: %w", err)
+		}
 		p.reconcilers[i] = r
 	}
 
@@ -115,6 +284,27 @@ func (p *ReconcilerPool) Open() error {
 		return fmt.Errorf("organization required if app name uses a wildcard")
 	}
 
+	// Sharding only makes sense when managing multiple apps.
+	if p.Membership != nil && !appNameHasWildcard {
+		return fmt.Errorf("sharding requires an app name with a wildcard")
+	}
+	if p.Membership != nil && p.InstanceID == "" {
+		return fmt.Errorf("instance id required for sharding")
+	}
+
+	// Followers stay warm by tracking apps & state but do not reconcile until
+	// they acquire leadership. Without an elector, this instance always leads.
+	if p.LeaderElector == nil {
+		p.leader.Store(true)
+	} else {
+		p.wg.Add(1)
+		go func() { defer p.wg.Done(); p.monitorLeaderElection(p.ctx) }()
+	}
+
+	// Push apps into the work queue as they become due.
+	p.wg.Add(1)
+	go func() { defer p.wg.Done(); p.monitorScheduler(p.ctx) }()
+
 	// Start each reconciler in a separate goroutine and wait for work.
 	p.wg.Add(len(p.reconcilers))
 	for _, r := range p.reconcilers {
@@ -123,63 +313,398 @@ func (p *ReconcilerPool) Open() error {
 	}
 
 	// If the app name does not contain a wildcard, set it as the value list
-	// and have it push
+	// and notify the scheduler. Otherwise kick off the app list monitor which
+	// will notify the scheduler each time the list is updated.
 	if !appNameHasWildcard {
-		client, err := p.NewFlapsClient(context.Background(), p.AppName)
+		client, err := p.newFlapsClient(context.Background(), p.AppName)
 		if err != nil {
 			return fmt.Errorf("cannot initialize flaps client: %w", err)
 		}
+		p.apps.Lock()
 		p.apps.m[p.AppName] = appInfo{
 			name:   p.AppName,
 			client: client,
 		}
-
-		p.wg.Add(1)
-		go func() { defer p.wg.Done(); p.monitorWorkQueueGenerator(p.ctx) }()
+		p.apps.Unlock()
+		p.wakeScheduler()
 	} else {
-		// If there is wildcard then we need to kick off the app list monitor
-		// first. Once we have a set of app names then we can kick off the
-		// work queue.
 		p.wg.Add(1)
 		go func() { defer p.wg.Done(); p.monitorAppNameRefresh(p.ctx) }()
 	}
 
+	// Heartbeat separately from the app list refresh so that the instance
+	// does not expire from the membership between refreshes.
+	if p.Membership != nil {
+		p.wg.Add(1)
+		go func() { defer p.wg.Done(); p.monitorMembership(p.ctx) }()
+	}
+
 	return nil
 }
 
+// newFlapsClient returns an instrumented client for an app that is rate
+// limited, if enabled.
+func (p *ReconcilerPool) newFlapsClient(ctx context.Context, name string) (FlapsClient, error) {
+	client, err := p.NewFlapsClient(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	client = &instrumentedFlapsClient{client: client, duration: p.apiCallDuration}
+
+	if p.RateLimiter != nil {
+		client = &rateLimitedFlapsClient{client: client, limiter: p.RateLimiter}
+	}
+	return client, nil
+}
+
 // Close stops all processing of the pool and underlying reconcilers.
 // Only returns once all reconcilers have finished processing.
 func (p *ReconcilerPool) Close() error {
 	p.cancel(errReconcilerPoolClosing)
 	p.wg.Wait()
+
+	// Leave the membership so other instances can rebalance immediately.
+	if p.Membership != nil {
+		if err := p.Membership.Leave(context.Background(), p.InstanceID); err != nil {
+			return fmt.Errorf("leave membership: %w", err)
+		}
+	}
+
+	// Give up leadership once reconciliation has stopped so that a follower
+	// can take over without waiting for the lock to expire.
+	if p.LeaderElector != nil && p.leader.Swap(false) {
+		if err := p.LeaderElector.Release(context.Background()); err != nil {
+			return fmt.Errorf("release leadership: %w", err)
+		}
+	}
 	return nil
 }
 
-// monitorWorkQueueGenerator pushes all apps into the work queue on an interval.
-func (p *ReconcilerPool) monitorWorkQueueGenerator(ctx context.Context) {
-	ticker := time.NewTicker(p.ReconcileInterval)
+// IsLeader returns true if this instance is currently allowed to reconcile.
+func (p *ReconcilerPool) IsLeader() bool {
+	return p.leader.Load()
+}
+
+// monitorLeaderElection periodically acquires or renews leadership.
+func (p *ReconcilerPool) monitorLeaderElection(ctx context.Context) {
+	ticker := time.NewTicker(p.LeaderElectionInterval)
 	defer ticker.Stop()
 
 	for {
+		p.updateLeadership(ctx)
+
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			// Fetch the app list under lock.
-			p.apps.Lock()
-			m := p.apps.m
-			p.apps.Unlock()
-
-			// Push all app names into the work queue.
-			for _, info := range m {
-				select {
-				case <-ctx.Done():
-					return
-				case p.ch <- info:
+		}
+	}
+}
+
+func (p *ReconcilerPool) updateLeadership(ctx context.Context) {
+	isLeader, err := p.LeaderElector.Acquire(ctx)
+	if err != nil && ctx.Err() == nil {
+		slog.Error("leader election failed, stepping down", slog.Any("err", err))
+	}
+	isLeader = isLeader && err == nil
+
+	if isLeader == p.leader.Load() {
+		return
+	}
+
+	if !isLeader {
+		p.leader.Store(false)
+		slog.Warn("leadership lost")
+		return
+	}
+
+	// Reload state as the previous leader may have written to a shared store.
+	if err := p.loadAppStates(ctx); err != nil {
+		slog.Error("cannot reload app states", slog.Any("err", err))
+	}
+	p.leader.Store(true)
+	p.wakeScheduler()
+	slog.Info("leadership acquired")
+}
+
+// loadAppStates reads the state for all apps from the store.
+func (p *ReconcilerPool) loadAppStates(ctx context.Context) error {
+	states, err := p.Store.AppStates(ctx)
+	if err != nil {
+		return err
+	}
+
+	p.states.Lock()
+	defer p.states.Unlock()
+	for _, state := range states {
+		p.states.m[state.AppName] = state
+	}
+
+	slog.Info("app states loaded", slog.Int("n", len(states)))
+	return nil
+}
+
+// AppState returns a copy of the current state for an app.
+// Returns nil if the app has no state.
+func (p *ReconcilerPool) AppState(name string) *AppState {
+	p.states.Lock()
+	defer p.states.Unlock()
+	return p.states.m[name].Clone()
+}
+
+// saveAppState updates the in-memory state for an app and writes it to the store.
+// The app's current override is retained, unless expired, as overrides are only
+// changed through SetOverride() & ClearOverride().
+func (p *ReconcilerPool) saveAppState(ctx context.Context, state *AppState) {
+	p.stateWriteMu.Lock()
+	defer p.stateWriteMu.Unlock()
+
+	p.states.Lock()
+	state.Override = nil
+	if cur := p.states.m[state.AppName]; cur != nil && cur.Override.Active(time.Now()) {
+		state.Override = cur.Override.Clone()
+	}
+	p.states.m[state.AppName] = state.Clone()
+	p.states.Unlock()
+
+	if err := p.Store.PutAppState(ctx, state); err != nil {
+		slog.Error("cannot save app state",
+			slog.String("app", state.AppName),
+			slog.Any("err", err))
+	}
+}
+
+// monitorScheduler pushes apps into the work queue as they become due.
+func (p *ReconcilerPool) monitorScheduler(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		jobs, next := p.nextJobs(time.Now())
+
+		// Push due apps into the work queue. This blocks until a reconciler is
+		// available so the queue lag grows if the pool cannot keep up.
+		for _, job := range jobs {
+			select {
+			case <-ctx.Done():
+				return
+			case p.ch <- job:
+			}
+		}
+
+		// Wait until the next app is due or the schedule changes.
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-p.wake:
+		}
+	}
+}
+
+// nextJobs returns a list of apps that are due for reconciliation, sorted by
+// due time. Also returns the time that the next app will become due.
+func (p *ReconcilerPool) nextJobs(now time.Time) (jobs []reconcileJob, next time.Time) {
+	p.apps.Lock()
+	apps := p.apps.m
+	p.apps.Unlock()
+
+	p.schedules.Lock()
+	defer p.schedules.Unlock()
+
+	// Remove schedules for apps that are no longer managed.
+	for name := range p.schedules.m {
+		if _, ok := apps[name]; !ok {
+			delete(p.schedules.m, name)
+		}
+	}
+	p.pruneAppMetrics(apps)
+
+	isLeader := p.IsLeader()
+	next = now.Add(p.ReconcileInterval)
+	for _, info := range apps {
+		sched := p.appScheduleLocked(info, now)
+
+		if sched.isDue(now) {
+			// Skip if the previous reconciliation has not finished. Triggers
+			// are kept so they run as soon as the reconciliation completes.
+			if sched.inFlight {
+				if sched.triggeredAt.IsZero() {
+					slog.Debug("reconciliation still in flight, skipping",
+						slog.String("app", info.name))
+					sched.nextRunAt = now.Add(p.nextInterval(sched.interval))
 				}
+			} else if isLeader {
+				jobs = append(jobs, reconcileJob{appInfo: info, dueAt: sched.dueAt()})
+				sched.inFlight = true
+				sched.triggeredAt = time.Time{}
+				sched.nextRunAt = now.Add(p.nextInterval(sched.interval))
+			} else {
+				// Only the leader performs reconciliation.
+				sched.triggeredAt = time.Time{}
+				sched.nextRunAt = now.Add(p.nextInterval(sched.interval))
 			}
 		}
+
+		if !sched.inFlight && sched.nextRunAt.Before(next) {
+			next = sched.nextRunAt
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].dueAt.Before(jobs[j].dueAt) })
+	return jobs, next
+}
+
+// appScheduleLocked returns the schedule for an app or creates it if it does
+// not exist. New apps are given a random initial offset within their interval
+// so that apps discovered at the same time do not reconcile in lockstep.
+func (p *ReconcilerPool) appScheduleLocked(info appInfo, now time.Time) *appSchedule {
+	if sched, ok := p.schedules.m[info.name]; ok {
+		sched.info = info
+		return sched
+	}
+
+	interval := p.appReconcileInterval(info.name)
+	sched := &appSchedule{
+		info:      info,
+		interval:  interval,
+		nextRunAt: now.Add(randDuration(interval)),
+	}
+	p.schedules.m[info.name] = sched
+	return sched
+}
+
+// appReconcileInterval returns the reconcile interval for an app.
+func (p *ReconcilerPool) appReconcileInterval(name string) time.Duration {
+	for i, re := range p.appIntervalRes {
+		if re.MatchString(name) {
+			return p.AppReconcileIntervals[i].Interval
+		}
+	}
+	return p.ReconcileInterval
+}
+
+// nextInterval returns interval with a random amount of jitter added.
+func (p *ReconcilerPool) nextInterval(interval time.Duration) time.Duration {
+	return interval + randDuration(p.ReconcileJitter)
+}
+
+// completeJob marks an app as no longer in flight & reschedules. If the
+// reconciliation failed too many times in a row then the app's circuit breaker
+// is opened and the app is backed off exponentially.
+func (p *ReconcilerPool) completeJob(name string, err error) {
+	defer p.wakeScheduler()
+
+	p.schedules.Lock()
+	defer p.schedules.Unlock()
+
+	sched, ok := p.schedules.m[name]
+	if !ok {
+		return
+	}
+	sched.inFlight = false
+
+	if err == nil {
+		if p.CircuitBreakerThreshold > 0 && sched.failureN >= p.CircuitBreakerThreshold {
+			slog.Info("circuit breaker closed", slog.String("app", name))
+		}
+		sched.failureN, sched.openUntil = 0, time.Time{}
+		return
+	}
+
+	sched.failureN++
+	if p.CircuitBreakerThreshold <= 0 || sched.failureN < p.CircuitBreakerThreshold {
+		return
+	}
+
+	backoff := p.CircuitBreakerBackoff
+	for i := p.CircuitBreakerThreshold; i < sched.failureN && backoff < p.CircuitBreakerMaxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, p.CircuitBreakerMaxBackoff)
+
+	sched.openUntil = time.Now().Add(backoff)
+	if sched.nextRunAt.Before(sched.openUntil) {
+		sched.nextRunAt = sched.openUntil
+	}
+
+	slog.Warn("circuit breaker open, backing off app",
+		slog.String("app", name),
+		slog.Int("failures", sched.failureN),
+		slog.String("backoff", backoff.String()))
+}
+
+// CircuitBreakerState returns the state of an app's circuit breaker and the
+// number of consecutive failures. Returns blank if the app is not scheduled.
+func (p *ReconcilerPool) CircuitBreakerState(name string) (state string, failureN int) {
+	p.schedules.Lock()
+	defer p.schedules.Unlock()
+
+	sched, ok := p.schedules.m[name]
+	if !ok {
+		return "", 0
+	}
+	return p.circuitBreakerStateLocked(sched, time.Now()), sched.failureN
+}
+
+func (p *ReconcilerPool) circuitBreakerStateLocked(sched *appSchedule, now time.Time) string {
+	if p.CircuitBreakerThreshold <= 0 || sched.failureN < p.CircuitBreakerThreshold {
+		return CircuitBreakerClosed
+	} else if now.Before(sched.openUntil) {
+		return CircuitBreakerOpen
+	}
+	return CircuitBreakerHalfOpen
+}
+
+// wakeScheduler notifies the scheduler to recompute due apps. This does not
+// block if a notification is already pending.
+func (p *ReconcilerPool) wakeScheduler() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// QueueLag returns the delay between when the most recently dequeued app was
+// due for reconciliation and when a reconciler picked it up.
+func (p *ReconcilerPool) QueueLag() time.Duration {
+	return time.Duration(p.queueLag.Load())
+}
+
+// Trigger requests an immediate out-of-band reconciliation of an app. Returns
+// false if a reconciliation is already pending for the app as bursts of
+// triggers are collapsed into a single reconciliation.
+func (p *ReconcilerPool) Trigger(name string) (bool, error) {
+	if !p.IsLeader() {
+		return false, ErrNotLeader
+	}
+
+	p.apps.Lock()
+	info, ok := p.apps.m[name]
+	p.apps.Unlock()
+	if !ok {
+		return false, ErrAppNotFound
+	}
+
+	p.schedules.Lock()
+	defer p.schedules.Unlock()
+
+	sched := p.appScheduleLocked(info, time.Now())
+	if !sched.triggeredAt.IsZero() {
+		return false, nil
 	}
+	sched.triggeredAt = time.Now()
+
+	p.wakeScheduler()
+	return true, nil
 }
 
 // monitorAppNameRefresh runs in the background and periodically refreshes the
@@ -189,20 +714,41 @@ func (p *ReconcilerPool) monitorAppNameRefresh(ctx context.Context) {
 	ticker := time.NewTicker(p.AppListRefreshInterval)
 	defer ticker.Stop()
 
-	var initialized bool
 	for {
 		if err := p.updateAppNameList(ctx); err != nil {
 			slog.Error("app list update failed", slog.Any("err", err))
 		}
 
-		// Start the work generator once we have our initial list.
-		if !initialized {
-			initialized = true
-			p.wg.Add(1)
-			go func() { defer p.wg.Done(); p.monitorWorkQueueGenerator(p.ctx) }()
+		// Notify the scheduler so new apps are scheduled.
+		p.wakeScheduler()
+
+		// Wait for the next time we fetch the app name list. Membership
+		// changes cause an immediate refresh so apps are rebalanced.
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.refresh:
+		}
+	}
+}
+
+// monitorMembership runs in the background and periodically sends a heartbeat
+// for this instance. The app list is refreshed when the membership changes.
+func (p *ReconcilerPool) monitorMembership(ctx context.Context) {
+	ticker := time.NewTicker(p.MembershipHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		if changed, err := p.updateMembership(ctx); err != nil {
+			slog.Error("membership update failed", slog.Any("err", err))
+		} else if changed {
+			select {
+			case p.refresh <- struct{}{}:
+			default:
+			}
 		}
 
-		// Wait for the next time we fetch the app name list.
 		select {
 		case <-ctx.Done():
 			return
@@ -211,6 +757,62 @@ func (p *ReconcilerPool) monitorAppNameRefresh(ctx context.Context) {
 	}
 }
 
+// updateMembership sends a heartbeat for this instance and rebuilds the hash
+// ring if the set of live instances has changed. Returns true if the ring was
+// rebuilt.
+func (p *ReconcilerPool) updateMembership(ctx context.Context) (bool, error) {
+	members, err := p.Membership.Heartbeat(ctx, p.InstanceID)
+	if err != nil {
+		return false, err
+	}
+	sort.Strings(members)
+
+	p.shard.Lock()
+	defer p.shard.Unlock()
+
+	if p.shard.ring != nil && slices.Equal(members, p.shard.members) {
+		return false, nil
+	}
+	p.shard.members = members
+	p.shard.ring = NewHashRing(members)
+
+	slog.Info("shard membership changed, rebalancing apps",
+		slog.String("id", p.InstanceID),
+		slog.Any("members", members))
+
+	return true, nil
+}
+
+// ownsApp returns true if this instance is responsible for reconciling the app.
+// Always returns true if sharding is disabled. If sharding is enabled but the
+// membership has not been fetched yet then no apps are owned.
+func (p *ReconcilerPool) ownsApp(name string) bool {
+	if p.Membership == nil {
+		return true
+	}
+
+	p.shard.Lock()
+	defer p.shard.Unlock()
+
+	if p.shard.ring == nil {
+		return false
+	}
+	return p.shard.ring.Owner(name) == p.InstanceID
+}
+
+// AppNames returns a sorted list of apps currently managed by this instance.
+func (p *ReconcilerPool) AppNames() []string {
+	p.apps.Lock()
+	defer p.apps.Unlock()
+
+	a := make([]string, 0, len(p.apps.m))
+	for name := range p.apps.m {
+		a = append(a, name)
+	}
+	sort.Strings(a)
+	return a
+}
+
 func (p *ReconcilerPool) updateAppNameList(ctx context.Context) error {
 	// Compile the wildcard expression as a regex so we can use it to match.
 	re, err := regexp.Compile(FormatWildcardAsRegexp(p.AppName))
@@ -244,6 +846,11 @@ func (p *ReconcilerPool) updateAppNameList(ctx context.Context) error {
 			continue
 		}
 
+		// Skip apps that are owned by another instance.
+		if !p.ownsApp(name) {
+			continue
+		}
+
 		// Reuse client, if possible.
 		if info, ok := p.apps.m[name]; ok {
 			m[name] = info
@@ -251,7 +858,7 @@ func (p *ReconcilerPool) updateAppNameList(ctx context.Context) error {
 		}
 
 		// Otherwise build a new client with our constructor.
-		client, err := p.NewFlapsClient(ctx, name)
+		client, err := p.newFlapsClient(ctx, name)
 		if err != nil {
 			return fmt.Errorf("cannot build flaps client for app %q: %w", name, err)
 		}
@@ -269,145 +876,277 @@ func (p *ReconcilerPool) updateAppNameList(ctx context.Context) error {
 
 // monitorReconciler monitors the work queue and passes apps to the reconciler.
 func (p *ReconcilerPool) monitorReconciler(ctx context.Context, r *Reconciler) {
-	errReconciliationTimeout := fmt.Errorf("reconciliation timeout")
-
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case info := <-p.ch:
-			ctx, cancel := context.WithTimeoutCause(p.ctx, p.ReconcileTimeout, errReconciliationTimeout)
-			defer cancel()
-
-			r.AppName = info.name
-			r.Client = info.client
-
-			release, err := p.flyClient.GetAppCurrentReleaseMachines(ctx, info.name)
-			if err != nil {
-				slog.Error("get current release failed",
-					slog.String("app", info.name),
-					slog.Any("err", err))
-				continue
-			}
+		case job := <-p.ch:
+			p.queueLag.Store(int64(time.Since(job.dueAt)))
+			err := p.reconcile(ctx, r, job.appInfo)
+			p.completeJob(job.name, err)
+		}
+	}
+}
 
-			if release.Status == "running" {
-				slog.Warn("release in progress, skipping reconciliation",
-					slog.String("app", r.AppName),
-				)
-				continue
-			}
+// reconcile collects metrics and performs reconciliation for a single app.
+// Errors are logged & also returned so repeated failures can be tracked.
+func (p *ReconcilerPool) reconcile(ctx context.Context, r *Reconciler, info appInfo) (err error) {
+	defer func(start time.Time) {
+		observeDuration(p.reconcileDuration, start, statusLabel(err))
+	}(time.Now())
 
-			if err := r.CollectMetrics(ctx); err != nil {
-				slog.Error("metrics collection failed",
-					slog.String("app", info.name),
-					slog.Any("err", err))
-				continue
-			}
+	ctx, cancel := context.WithTimeoutCause(ctx, p.ReconcileTimeout, errReconciliationTimeout)
+	defer cancel()
 
-			if err := r.Reconcile(ctx); err != nil {
-				slog.Error("reconciliation failed",
-					slog.String("app", info.name),
-					slog.Any("err", err))
-				continue
-			}
+	ctx, span := tracer.Start(ctx, "reconcile", trace.WithAttributes(attribute.String("fas.app", info.name)))
+	defer func() { endSpan(span, err) }()
 
+	r.AppName = info.name
+	r.Client = info.client
+
+	r.State = p.AppState(info.name)
+	if r.State == nil {
+		r.State = NewAppState(info.name)
+	}
+
+	// Skip the app entirely while it is paused by an override.
+	if o := r.State.Override; o.Active(time.Now()) && o.Paused {
+		p.Stats.Paused.Add(1)
+		span.SetAttributes(attribute.Bool("fas.paused", true))
+		slog.Debug("app paused by override, skipping reconciliation",
+			slog.String("app", info.name),
+			slog.Time("expires_at", o.ExpiresAt))
+		return nil
+	}
+
+	// Skipped reconciliations are neither failures nor recoveries.
+	prev, skipNotify := r.State.LastDecision, false
+	defer func() {
+		if !skipNotify {
+			p.notify(r, prev, err)
 		}
+	}()
+
+	release, err := p.flyClient.GetAppCurrentReleaseMachines(ctx, info.name)
+	if err != nil {
+		p.Stats.ReleaseFailed.Add(1)
+		slog.Error("get current release failed",
+			slog.String("app", info.name),
+			slog.Any("err", err))
+		p.recordAppMetrics(r, prev, false, err)
+		p.writeAuditEvent(ctx, r, prev, false, err)
+		return err
+	}
+
+	if release.Status == "running" {
+		p.Stats.ReleaseInProgress.Add(1)
+		span.SetAttributes(attribute.Bool("fas.release_in_progress", true))
+		slog.Warn("release in progress, skipping reconciliation",
+			slog.String("app", r.AppName),
+		)
+		skipNotify = true
+		return nil
+	}
+
+	if err := r.CollectMetrics(ctx); err != nil {
+		p.Stats.CollectFailed.Add(1)
+		slog.Error("metrics collection failed",
+			slog.String("app", info.name),
+			slog.Any("err", err))
+		p.recordAppMetrics(r, prev, true, err)
+		p.writeAuditEvent(ctx, r, prev, true, err)
+		return err
 	}
+
+	err = r.Reconcile(ctx)
+	p.saveAppState(ctx, r.State)
+	p.recordAppMetrics(r, prev, true, err)
+	p.writeAuditEvent(ctx, r, prev, true, err)
+	if d := r.State.LastDecision; d != nil && d != prev {
+		span.SetAttributes(attribute.String("fas.action", d.Action), attribute.Int("fas.n", d.N))
+	}
+	if err != nil {
+		p.Stats.ReconcileFailed.Add(1)
+		slog.Error("reconciliation failed",
+			slog.String("app", info.name),
+			slog.Any("err", err))
+		return err
+	}
+	return nil
 }
 
 func (p *ReconcilerPool) RegisterPromMetrics(reg prometheus.Registerer) {
+	p.registerMachineCreateCount(reg)
+	p.registerMachineDestroyCount(reg)
 	p.registerMachineStartCount(reg)
 	p.registerMachineStoppedCount(reg)
 	p.registerReconcileCount(reg)
+	p.registerQueueLag(reg)
+	p.registerGuardrailViolationCount(reg)
+	reg.MustRegister(p.reconcileDuration, p.collectDuration, p.apiCallDuration, p.collectErrors)
+	reg.MustRegister(&circuitBreakerCollector{pool: p})
+	if p.AppMetrics {
+		reg.MustRegister(&appMetricsCollector{pool: p})
+	}
+}
+
+func (p *ReconcilerPool) registerMachineCreateCount(reg prometheus.Registerer) {
+	const name = "fas_machine_create_count"
+	registerCounter(reg, name, "ok", &p.Stats.MachineCreated)
+	registerCounter(reg, name, "failed", &p.Stats.MachineCreateFailed)
+}
+
+func (p *ReconcilerPool) registerMachineDestroyCount(reg prometheus.Registerer) {
+	const name = "fas_machine_destroy_count"
+	registerCounter(reg, name, "ok", &p.Stats.MachineDestroyed)
+	registerCounter(reg, name, "failed", &p.Stats.MachineDestroyFailed)
 }
 
 func (p *ReconcilerPool) registerMachineStartCount(reg prometheus.Registerer) {
 	const name = "fas_machine_start_count"
-
-	reg.MustRegister(prometheus.NewCounterFunc(
-		prometheus.CounterOpts{
-			Name:        name,
-			ConstLabels: prometheus.Labels{"status": "ok"},
-		},
-		func() float64 { return float64(p.Stats.MachineStarted.Load()) },
-	))
-	reg.MustRegister(prometheus.NewCounterFunc(
-		prometheus.CounterOpts{
-			Name:        name,
-			ConstLabels: prometheus.Labels{"status": "failed"},
-		},
-		func() float64 { return float64(p.Stats.MachineStartFailed.Load()) },
-	))
+	registerCounter(reg, name, "ok", &p.Stats.MachineStarted)
+	registerCounter(reg, name, "failed", &p.Stats.MachineStartFailed)
 }
 
 func (p *ReconcilerPool) registerMachineStoppedCount(reg prometheus.Registerer) {
 	const name = "fas_machine_stop_count"
-
-	reg.MustRegister(prometheus.NewCounterFunc(
-		prometheus.CounterOpts{
-			Name:        name,
-			ConstLabels: prometheus.Labels{"status": "ok"},
-		},
-		func() float64 { return float64(p.Stats.MachineStopped.Load()) },
-	))
-	reg.MustRegister(prometheus.NewCounterFunc(
-		prometheus.CounterOpts{
-			Name:        name,
-			ConstLabels: prometheus.Labels{"status": "failed"},
-		},
-		func() float64 { return float64(p.Stats.MachineStopFailed.Load()) },
-	))
+	registerCounter(reg, name, "ok", &p.Stats.MachineStopped)
+	registerCounter(reg, name, "failed", &p.Stats.MachineStopFailed)
 }
 
+// registerReconcileCount registers a counter for each outcome of a
+// reconciliation, including reconciliations that were skipped or that failed
+// before a scaling action was taken.
 func (p *ReconcilerPool) registerReconcileCount(reg prometheus.Registerer) {
 	const name = "fas_reconcile_count"
+	registerCounter(reg, name, "create", &p.Stats.BulkCreate)
+	registerCounter(reg, name, "destroy", &p.Stats.BulkDestroy)
+	registerCounter(reg, name, "start", &p.Stats.BulkStart)
+	registerCounter(reg, name, "stop", &p.Stats.BulkStop)
+	registerCounter(reg, name, "no_scale", &p.Stats.NoScale)
+	registerCounter(reg, name, "paused", &p.Stats.Paused)
+	registerCounter(reg, name, "release_in_progress", &p.Stats.ReleaseInProgress)
+	registerCounter(reg, name, "release_failed", &p.Stats.ReleaseFailed)
+	registerCounter(reg, name, "collect_failed", &p.Stats.CollectFailed)
+	registerCounter(reg, name, "failed", &p.Stats.ReconcileFailed)
+}
 
+// registerCounter registers a counter with a "status" label that reports v.
+func registerCounter(reg prometheus.Registerer, name, status string, v *atomic.Int64) {
 	reg.MustRegister(prometheus.NewCounterFunc(
 		prometheus.CounterOpts{
 			Name:        name,
-			ConstLabels: prometheus.Labels{"status": "create"},
+			ConstLabels: prometheus.Labels{"status": status},
 		},
-		func() float64 { return float64(p.Stats.BulkCreate.Load()) },
+		func() float64 { return float64(v.Load()) },
 	))
+}
 
+func (p *ReconcilerPool) registerGuardrailViolationCount(reg prometheus.Registerer) {
 	reg.MustRegister(prometheus.NewCounterFunc(
 		prometheus.CounterOpts{
-			Name:        name,
-			ConstLabels: prometheus.Labels{"status": "destroy"},
+			Name: "fas_guardrail_violation_count",
+			Help: "Number of machine count targets or actions that exceeded a guardrail.",
 		},
-		func() float64 { return float64(p.Stats.BulkDestroy.Load()) },
+		func() float64 { return float64(p.Stats.GuardrailViolation.Load()) },
 	))
+}
 
-	reg.MustRegister(prometheus.NewCounterFunc(
-		prometheus.CounterOpts{
-			Name:        name,
-			ConstLabels: prometheus.Labels{"status": "start"},
+func (p *ReconcilerPool) registerQueueLag(reg prometheus.Registerer) {
+	reg.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "fas_reconcile_queue_lag_seconds",
+			Help: "Delay between an app becoming due and a reconciler picking it up.",
 		},
-		func() float64 { return float64(p.Stats.BulkStart.Load()) },
+		func() float64 { return p.QueueLag().Seconds() },
 	))
+}
 
-	reg.MustRegister(prometheus.NewCounterFunc(
-		prometheus.CounterOpts{
-			Name:        name,
-			ConstLabels: prometheus.Labels{"status": "stop"},
-		},
-		func() float64 { return float64(p.Stats.BulkStop.Load()) },
-	))
+var circuitBreakerStateDesc = prometheus.NewDesc(
+	"fas_circuit_breaker_state",
+	"State of each app's circuit breaker (0=closed, 1=open, 2=half-open).",
+	[]string{"app"}, nil,
+)
 
-	reg.MustRegister(prometheus.NewCounterFunc(
-		prometheus.CounterOpts{
-			Name:        name,
-			ConstLabels: prometheus.Labels{"status": "no_scale"},
-		},
-		func() float64 { return float64(p.Stats.NoScale.Load()) },
-	))
+// circuitBreakerCollector reports the circuit breaker state for each app.
+type circuitBreakerCollector struct {
+	pool *ReconcilerPool
+}
+
+func (c *circuitBreakerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- circuitBreakerStateDesc
+}
+
+func (c *circuitBreakerCollector) Collect(ch chan<- prometheus.Metric) {
+	c.pool.schedules.Lock()
+	defer c.pool.schedules.Unlock()
+
+	now := time.Now()
+	for name, sched := range c.pool.schedules.m {
+		var value float64
+		switch c.pool.circuitBreakerStateLocked(sched, now) {
+		case CircuitBreakerOpen:
+			value = 1
+		case CircuitBreakerHalfOpen:
+			value = 2
+		}
+		ch <- prometheus.MustNewConstMetric(circuitBreakerStateDesc, prometheus.GaugeValue, value, name)
+	}
 }
 
+// Circuit breaker states.
+const (
+	CircuitBreakerClosed   = "closed"
+	CircuitBreakerOpen     = "open"
+	CircuitBreakerHalfOpen = "half_open"
+)
+
 type appInfo struct {
 	name   string
 	client FlapsClient
 }
 
+// reconcileJob represents an app pushed onto the work queue.
+type reconcileJob struct {
+	appInfo
+	dueAt time.Time
+}
+
+// appSchedule tracks when an app should next be reconciled.
+type appSchedule struct {
+	info        appInfo
+	interval    time.Duration
+	nextRunAt   time.Time
+	triggeredAt time.Time // set if an out-of-band reconciliation is pending
+	inFlight    bool      // true while queued or reconciling
+
+	// Circuit breaker state.
+	failureN  int       // consecutive failed reconciliations
+	openUntil time.Time // app is not reconciled until this time
+}
+
+// isDue returns true if the app should be reconciled at now.
+func (s *appSchedule) isDue(now time.Time) bool {
+	if now.Before(s.openUntil) {
+		return false
+	}
+	return !s.triggeredAt.IsZero() || !now.Before(s.nextRunAt)
+}
+
+// dueAt returns the time the app became due.
+func (s *appSchedule) dueAt() time.Time {
+	if !s.triggeredAt.IsZero() && s.triggeredAt.Before(s.nextRunAt) {
+		return s.triggeredAt
+	}
+	return s.nextRunAt
+}
+
+// AppReconcileInterval overrides the reconcile interval for matching apps.
+type AppReconcileInterval struct {
+	Pattern  string // wildcard app name
+	Interval time.Duration
+}
+
 // FormatWildcardAsRegexp returns a regexp for a given wildcard expression.
 func FormatWildcardAsRegexp(s string) string {
 	if s == "" {
@@ -421,4 +1160,15 @@ func FormatWildcardAsRegexp(s string) string {
 	return "^" + strings.Join(a, ".*") + "$"
 }
 
-var errReconcilerPoolClosing = errors.New("reconciler pool closing")
+// randDuration returns a random duration in [0, d). Returns zero if d <= 0.
+func randDuration(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
+
+var (
+	errReconcilerPoolClosing = errors.New("reconciler pool closing")
+	errReconciliationTimeout = errors.New("reconciliation timeout")
+)
@@ -3,6 +3,9 @@ package fas_test
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -12,6 +15,7 @@ import (
 	fas "github.com/superfly/fly-autoscaler"
 	"github.com/superfly/fly-autoscaler/mock"
 	fly "github.com/superfly/fly-go"
+	"github.com/superfly/fly-go/flaps"
 )
 
 func TestFormatWildcardAsRegexp(t *testing.T) {
@@ -183,3 +187,687 @@ func TestReconcilerPool_Run_SingleApp(t *testing.T) {
 
 	t.Log("Test complete")
 }
+
+// Ensure the pool restores app state from the store and persists updates.
+func TestReconcilerPool_Run_Store(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return &fly.Release{Status: "completed"}, nil
+	}
+
+	var startN atomic.Int64
+	var flapsClient mock.FlapsClient
+	flapsClient.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+	flapsClient.StartFunc = func(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+		startN.Add(1)
+		return &fly.MachineStartResponse{}, nil
+	}
+
+	// Initialize a store with an app that is still in its cooldown period.
+	store := fas.NewMemoryStore()
+	state := fas.NewAppState("my-app")
+	state.CooldownUntil = time.Now().Add(time.Hour)
+	if err := store.PutAppState(context.Background(), state); err != nil {
+		t.Fatal(err)
+	}
+
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.AppName = "my-app"
+	p.Store = store
+	p.ReconcileInterval = 10 * time.Millisecond
+	p.NewReconciler = func() *fas.Reconciler {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN, r.MaxStartedMachineN = "1", "1"
+		return r
+	}
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &flapsClient, nil
+	}
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	time.Sleep(10 * p.ReconcileInterval)
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := startN.Load(), int64(0); got != want {
+		t.Fatalf("startN=%v, want %v", got, want)
+	}
+
+	states, err := store.AppStates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := states[0].LastDecision.Action, fas.ActionNoScale; got != want {
+		t.Fatalf("Action=%v, want %v", got, want)
+	}
+}
+
+// Ensure only the leader performs reconciliation.
+func TestReconcilerPool_Run_LeaderElection(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return &fly.Release{Status: "completed"}, nil
+	}
+
+	var listN atomic.Int64
+	var flapsClient mock.FlapsClient
+	flapsClient.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		listN.Add(1)
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStarted, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+
+	var isLeader, released atomic.Bool
+	var elector mock.LeaderElector
+	elector.AcquireFunc = func(ctx context.Context) (bool, error) {
+		return isLeader.Load(), nil
+	}
+	elector.ReleaseFunc = func(ctx context.Context) error {
+		released.Store(true)
+		return nil
+	}
+
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.AppName = "my-app"
+	p.LeaderElector = &elector
+	p.LeaderElectionInterval = 10 * time.Millisecond
+	p.ReconcileInterval = 10 * time.Millisecond
+	p.NewReconciler = func() *fas.Reconciler {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN, r.MaxStartedMachineN = "1", "1"
+		return r
+	}
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &flapsClient, nil
+	}
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	// Followers should not reconcile.
+	time.Sleep(100 * time.Millisecond)
+	if p.IsLeader() {
+		t.Fatal("expected follower")
+	} else if got, want := listN.Load(), int64(0); got != want {
+		t.Fatalf("listN=%v, want %v", got, want)
+	}
+
+	// Once leadership is acquired, reconciliation should begin.
+	isLeader.Store(true)
+	time.Sleep(100 * time.Millisecond)
+	if !p.IsLeader() {
+		t.Fatal("expected leader")
+	} else if listN.Load() == 0 {
+		t.Fatal("expected reconciliation")
+	}
+
+	// Leadership should be released on close.
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	} else if !released.Load() {
+		t.Fatal("expected leadership to be released")
+	}
+}
+
+// Ensure apps are split across sharded instances without overlap.
+func TestReconcilerPool_Run_Sharding(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetOrganizationBySlugFunc = func(ctx context.Context, slug string) (*fly.Organization, error) {
+		return &fly.Organization{ID: "123"}, nil
+	}
+	flyClient.GetAppsForOrganizationFunc = func(ctx context.Context, orgID string) ([]fly.App, error) {
+		var apps []fly.App
+		for i := 0; i < 20; i++ {
+			apps = append(apps, fly.App{Name: fmt.Sprintf("my-app-%d", i)})
+		}
+		return apps, nil
+	}
+
+	membership := fas.NewStaticMembership(2)
+
+	var pools []*fas.ReconcilerPool
+	for i := 0; i < 2; i++ {
+		p := fas.NewReconcilerPool(&flyClient, 1)
+		p.OrganizationSlug = "myorg"
+		p.AppName = "my-app-*"
+		p.Membership = membership
+		p.InstanceID = fmt.Sprint(i)
+		p.ReconcileInterval = time.Hour
+		p.NewReconciler = fas.NewReconciler
+		p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+			return &mock.FlapsClient{}, nil
+		}
+		if err := p.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = p.Close() }()
+		pools = append(pools, p)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	owners := make(map[string]int)
+	for i, p := range pools {
+		names := p.AppNames()
+		if len(names) == 0 {
+			t.Fatalf("pool %d owns no apps", i)
+		}
+		for _, name := range names {
+			if j, ok := owners[name]; ok {
+				t.Fatalf("app %q owned by pools %d & %d", name, j, i)
+			}
+			owners[name] = i
+		}
+	}
+	if got, want := len(owners), 20; got != want {
+		t.Fatalf("owned=%v, want %v", got, want)
+	}
+}
+
+// Ensure heartbeats are sent on their own interval, independent of the app
+// list refresh, so the instance does not expire from the membership.
+func TestReconcilerPool_Run_Heartbeat(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetOrganizationBySlugFunc = func(ctx context.Context, slug string) (*fly.Organization, error) {
+		return &fly.Organization{ID: "123"}, nil
+	}
+	flyClient.GetAppsForOrganizationFunc = func(ctx context.Context, orgID string) ([]fly.App, error) {
+		return []fly.App{{Name: "my-app-1"}}, nil
+	}
+
+	var heartbeatN atomic.Int64
+	var membership mock.Membership
+	membership.HeartbeatFunc = func(ctx context.Context, id string) ([]string, error) {
+		heartbeatN.Add(1)
+		return []string{"0"}, nil
+	}
+	membership.LeaveFunc = func(ctx context.Context, id string) error { return nil }
+
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.OrganizationSlug = "myorg"
+	p.AppName = "my-app-*"
+	p.Membership = &membership
+	p.InstanceID = "0"
+	p.ReconcileInterval = time.Hour
+	p.AppListRefreshInterval = time.Hour
+	p.MembershipHeartbeatInterval = 10 * time.Millisecond
+	p.NewReconciler = fas.NewReconciler
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &mock.FlapsClient{}, nil
+	}
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	time.Sleep(100 * time.Millisecond)
+	if n := heartbeatN.Load(); n < 3 {
+		t.Fatalf("heartbeatN=%v, want at least 3", n)
+	}
+
+	// The app list is refreshed once the first heartbeat builds the ring.
+	if got, want := p.AppNames(), []string{"my-app-1"}; !slices.Equal(got, want) {
+		t.Fatalf("AppNames()=%v, want %v", got, want)
+	}
+}
+
+// Ensure triggers cause an immediate reconciliation & bursts are collapsed.
+func TestReconcilerPool_Trigger(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return &fly.Release{Status: "completed"}, nil
+	}
+
+	// Block the first list so we can queue up triggers while it's running.
+	var listN atomic.Int64
+	ready, unblock := make(chan struct{}), make(chan struct{})
+	var flapsClient mock.FlapsClient
+	flapsClient.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		if listN.Add(1) == 1 {
+			close(ready)
+			<-unblock
+		}
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStarted, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.AppName = "my-app"
+	p.ReconcileInterval = time.Hour
+	p.NewReconciler = func() *fas.Reconciler {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN, r.MaxStartedMachineN = "1", "1"
+		return r
+	}
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &flapsClient, nil
+	}
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	if _, err := p.Trigger("no-such-app"); err != fas.ErrAppNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if queued, err := p.Trigger("my-app"); err != nil {
+		t.Fatal(err)
+	} else if !queued {
+		t.Fatal("expected trigger to be queued")
+	}
+	<-ready
+
+	// Trigger several times while the first reconciliation is in progress.
+	if queued, err := p.Trigger("my-app"); err != nil {
+		t.Fatal(err)
+	} else if !queued {
+		t.Fatal("expected trigger to be queued")
+	}
+	for i := 0; i < 5; i++ {
+		if queued, err := p.Trigger("my-app"); err != nil {
+			t.Fatal(err)
+		} else if queued {
+			t.Fatal("expected trigger to be collapsed")
+		}
+	}
+
+	close(unblock)
+	time.Sleep(100 * time.Millisecond)
+	if got, want := listN.Load(), int64(2); got != want {
+		t.Fatalf("listN=%v, want %v", got, want)
+	}
+}
+
+// Ensure apps are reconciled on their own interval & an app is never
+// reconciled concurrently with itself.
+func TestReconcilerPool_Run_Schedule(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetOrganizationBySlugFunc = func(ctx context.Context, slug string) (*fly.Organization, error) {
+		return &fly.Organization{ID: "123"}, nil
+	}
+	flyClient.GetAppsForOrganizationFunc = func(ctx context.Context, orgID string) ([]fly.App, error) {
+		return []fly.App{{Name: "my-app-fast"}, {Name: "my-app-slow"}}, nil
+	}
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return &fly.Release{Status: "completed"}, nil
+	}
+
+	var mu sync.Mutex
+	listN := make(map[string]int)
+	var inFlightN, maxInFlightN atomic.Int64
+	newFlapsClient := func(name string) *mock.FlapsClient {
+		var client mock.FlapsClient
+		client.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+			mu.Lock()
+			listN[name]++
+			mu.Unlock()
+
+			// Reconciliation takes longer than the interval.
+			if n := inFlightN.Add(1); n > maxInFlightN.Load() {
+				maxInFlightN.Store(n)
+			}
+			time.Sleep(30 * time.Millisecond)
+			inFlightN.Add(-1)
+
+			return []*fly.Machine{
+				{ID: "1", State: fly.MachineStateStarted, HostStatus: fly.HostStatusOk},
+			}, nil
+		}
+		return &client
+	}
+
+	p := fas.NewReconcilerPool(&flyClient, 2)
+	p.OrganizationSlug = "myorg"
+	p.AppName = "my-app-*"
+	p.ReconcileInterval = time.Hour
+	p.AppReconcileIntervals = []fas.AppReconcileInterval{
+		{Pattern: "*-fast", Interval: 10 * time.Millisecond},
+	}
+	p.NewReconciler = func() *fas.Reconciler {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN, r.MaxStartedMachineN = "1", "1"
+		return r
+	}
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return newFlapsClient(name), nil
+	}
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	time.Sleep(300 * time.Millisecond)
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if n := listN["my-app-fast"]; n < 3 {
+		t.Fatalf("fast app reconciled %d times, expected at least 3", n)
+	} else if n := listN["my-app-slow"]; n != 0 {
+		t.Fatalf("slow app reconciled %d times, expected none", n)
+	} else if got, want := maxInFlightN.Load(), int64(1); got != want {
+		t.Fatalf("max in flight=%v, want %v", got, want)
+	}
+}
+
+// Ensure an app's circuit breaker opens after repeated failures and closes
+// again once a reconciliation succeeds.
+func TestReconcilerPool_CircuitBreaker(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return &fly.Release{Status: "completed"}, nil
+	}
+
+	var listN atomic.Int64
+	var failing atomic.Bool
+	failing.Store(true)
+	var flapsClient mock.FlapsClient
+	flapsClient.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		listN.Add(1)
+		if failing.Load() {
+			return nil, fmt.Errorf("marker")
+		}
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStarted, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.AppName = "my-app"
+	p.ReconcileInterval = 10 * time.Millisecond
+	p.CircuitBreakerThreshold = 3
+	p.CircuitBreakerBackoff = 200 * time.Millisecond
+	p.CircuitBreakerMaxBackoff = time.Second
+	p.NewReconciler = func() *fas.Reconciler {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN, r.MaxStartedMachineN = "1", "1"
+		return r
+	}
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &flapsClient, nil
+	}
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	// Wait for the breaker to open & ensure no further attempts are made.
+	time.Sleep(100 * time.Millisecond)
+	if state, failureN := p.CircuitBreakerState("my-app"); state != fas.CircuitBreakerOpen {
+		t.Fatalf("state=%v, want %v", state, fas.CircuitBreakerOpen)
+	} else if got, want := failureN, 3; got != want {
+		t.Fatalf("failureN=%v, want %v", got, want)
+	} else if got, want := listN.Load(), int64(3); got != want {
+		t.Fatalf("listN=%v, want %v", got, want)
+	}
+
+	// Triggers are ignored while the breaker is open.
+	if _, err := p.Trigger("my-app"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got, want := listN.Load(), int64(3); got != want {
+		t.Fatalf("listN=%v, want %v", got, want)
+	}
+
+	// Once the backoff elapses, a successful attempt closes the breaker.
+	failing.Store(false)
+	time.Sleep(200 * time.Millisecond)
+	if state, failureN := p.CircuitBreakerState("my-app"); state != fas.CircuitBreakerClosed {
+		t.Fatalf("state=%v, want %v", state, fas.CircuitBreakerClosed)
+	} else if got, want := failureN, 0; got != want {
+		t.Fatalf("failureN=%v, want %v", got, want)
+	}
+}
+
+// Ensure labeled metrics are exported for each app & limited to max apps.
+func TestReconcilerPool_AppMetrics(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetOrganizationBySlugFunc = func(ctx context.Context, slug string) (*fly.Organization, error) {
+		return &fly.Organization{ID: "123"}, nil
+	}
+	flyClient.GetAppsForOrganizationFunc = func(ctx context.Context, orgID string) ([]fly.App, error) {
+		return []fly.App{{Name: "my-app-1"}, {Name: "my-app-2"}}, nil
+	}
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return &fly.Release{Status: "completed"}, nil
+	}
+
+	var flapsClient mock.FlapsClient
+	flapsClient.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStarted, Region: "iad", HostStatus: fly.HostStatusOk},
+			{ID: "2", State: fly.MachineStateStopped, Region: "iad", HostStatus: fly.HostStatusOk},
+			{ID: "3", State: fly.MachineStateStarted, Region: "ord", HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+
+	collector := mock.NewMetricCollector("queue_depth")
+	collector.CollectMetricFunc = func(ctx context.Context, app string) (float64, error) {
+		return 20, nil
+	}
+
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.OrganizationSlug = "myorg"
+	p.AppName = "my-app-*"
+	p.ReconcileInterval = 10 * time.Millisecond
+	p.AppMetrics = true
+	p.AppMetricsMaxApps = 1
+	p.AppMetricsRegions = true
+	p.NewReconciler = func() *fas.Reconciler {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN, r.MaxStartedMachineN = "2", "queue_depth / 5"
+		r.Collectors = []fas.MetricCollector{collector}
+		return r
+	}
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &flapsClient, nil
+	}
+
+	reg := prometheus.NewRegistry()
+	p.RegisterPromMetrics(reg)
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+	time.Sleep(100 * time.Millisecond)
+
+	values := gatherMetrics(t, reg)
+	for key, want := range map[string]float64{
+		"fas_app_machines,app=my-app-1,process_group=,region=iad,state=started":          1,
+		"fas_app_machines,app=my-app-1,process_group=,region=iad,state=stopped":          1,
+		"fas_app_machines,app=my-app-1,process_group=,region=ord,state=created":          1,
+		"fas_app_target_machines,app=my-app-1,bound=max,machines=started,process_group=": 4,
+		"fas_app_metric_value,app=my-app-1,metric=queue_depth,process_group=":            20,
+		"fas_app_metrics_dropped_apps":                                                   1,
+	} {
+		if got, ok := values[key]; !ok {
+			t.Fatalf("missing metric: %s", key)
+		} else if got != want {
+			t.Fatalf("%s=%v, want %v", key, got, want)
+		}
+	}
+
+	if _, ok := values["fas_app_reconcile_count,action=no_scale,app=my-app-1,process_group="]; !ok {
+		t.Fatal("expected no_scale action count")
+	}
+	if _, ok := values["fas_app_last_reconcile_success_timestamp_seconds,app=my-app-1,process_group="]; !ok {
+		t.Fatal("expected last reconcile timestamp")
+	}
+	for key := range values {
+		if strings.HasPrefix(key, "fas_app_") && strings.Contains(key, "app=my-app-2") {
+			t.Fatalf("unexpected metric for dropped app: %s", key)
+		}
+	}
+}
+
+// Ensure reconciliation, collection & API call durations are recorded.
+func TestReconcilerPool_Histograms(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return &fly.Release{Status: "completed"}, nil
+	}
+
+	var flapsClient mock.FlapsClient
+	flapsClient.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+	flapsClient.StartFunc = func(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+		return nil, &flaps.FlapsError{ResponseStatusCode: http.StatusServiceUnavailable}
+	}
+
+	collector := mock.NewMetricCollector("queue_depth")
+	collector.CollectMetricFunc = func(ctx context.Context, app string) (float64, error) {
+		return 1, nil
+	}
+
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.AppName = "my-app"
+	p.ReconcileInterval = 10 * time.Millisecond
+	p.CircuitBreakerThreshold = 0
+	p.NewReconciler = func() *fas.Reconciler {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN, r.MaxStartedMachineN = "queue_depth", "queue_depth"
+		r.Collectors = []fas.MetricCollector{collector}
+		return r
+	}
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &flapsClient, nil
+	}
+
+	reg := prometheus.NewRegistry()
+	p.RegisterPromMetrics(reg)
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+	time.Sleep(50 * time.Millisecond)
+
+	values := gatherMetrics(t, reg)
+	for _, key := range []string{
+		"fas_reconcile_duration_seconds,status=ok",
+		"fas_metric_collect_duration_seconds,collector=queue_depth,status=ok",
+		"fas_api_call_duration_seconds,api=fly,method=GetAppCurrentReleaseMachines,status=ok",
+		"fas_api_call_duration_seconds,api=machines,method=List,status=ok",
+		"fas_api_call_duration_seconds,api=machines,method=Start,status=503",
+	} {
+		if got := values[key]; got == 0 {
+			t.Fatalf("expected observations for %s", key)
+		}
+	}
+}
+
+// gatherMetrics returns the value of each metric in reg, keyed by name & sorted
+// labels. Histograms are reported by their sample count.
+func gatherMetrics(tb testing.TB, reg *prometheus.Registry) map[string]float64 {
+	tb.Helper()
+
+	families, err := reg.Gather()
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range m.GetLabel() {
+				key += "," + label.GetName() + "=" + label.GetValue()
+			}
+			values[key] = m.GetGauge().GetValue() + m.GetCounter().GetValue() + float64(m.GetHistogram().GetSampleCount())
+		}
+	}
+	return values
+}
+
+// Ensure reconciliations that are skipped or fail are counted by outcome.
+func TestReconcilerPool_ReconcileCount(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		releaseStatus string
+		releaseErr    error
+		collectErr    error
+		listErr       error
+		keys          []string
+	}{
+		{name: "ReleaseFailed", releaseErr: fmt.Errorf("marker"), keys: []string{"fas_reconcile_count,status=release_failed"}},
+		{name: "ReleaseInProgress", releaseStatus: "running", keys: []string{"fas_reconcile_count,status=release_in_progress"}},
+		{name: "CollectFailed", collectErr: fmt.Errorf("marker"), keys: []string{
+			"fas_reconcile_count,status=collect_failed",
+			"fas_metric_collect_error_count,collector=queue_depth",
+		}},
+		{name: "ReconcileFailed", listErr: fmt.Errorf("marker"), keys: []string{"fas_reconcile_count,status=failed"}},
+		{name: "Create", keys: []string{
+			"fas_reconcile_count,status=create",
+			"fas_machine_create_count,status=ok",
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var flyClient mock.FlyClient
+			flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+				return &fly.Release{Status: tt.releaseStatus}, tt.releaseErr
+			}
+
+			var flapsClient mock.FlapsClient
+			flapsClient.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+				return []*fly.Machine{
+					{ID: "1", State: fly.MachineStateStarted, HostStatus: fly.HostStatusOk, Config: &fly.MachineConfig{}},
+				}, tt.listErr
+			}
+			flapsClient.LaunchFunc = func(ctx context.Context, input fly.LaunchMachineInput) (*fly.Machine, error) {
+				return &fly.Machine{ID: "2"}, nil
+			}
+
+			collector := mock.NewMetricCollector("queue_depth")
+			collector.CollectMetricFunc = func(ctx context.Context, app string) (float64, error) {
+				return 2, tt.collectErr
+			}
+
+			p := fas.NewReconcilerPool(&flyClient, 1)
+			p.AppName = "my-app"
+			p.ReconcileInterval = time.Hour
+			p.CircuitBreakerThreshold = 0
+			p.NewReconciler = func() *fas.Reconciler {
+				r := fas.NewReconciler()
+				r.MinCreatedMachineN = "queue_depth"
+				r.Collectors = []fas.MetricCollector{collector}
+				return r
+			}
+			p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+				return &flapsClient, nil
+			}
+
+			reg := prometheus.NewRegistry()
+			p.RegisterPromMetrics(reg)
+			if err := p.Open(); err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = p.Close() }()
+			if _, err := p.Trigger("my-app"); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(50 * time.Millisecond)
+
+			values := gatherMetrics(t, reg)
+			for _, key := range tt.keys {
+				if got, want := values[key], float64(1); got != want {
+					t.Fatalf("%s=%v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"math"
 	"os"
 	"testing"
+	"time"
 
 	fas "github.com/superfly/fly-autoscaler"
 	"github.com/superfly/fly-autoscaler/mock"
@@ -487,6 +488,92 @@ func TestReconciler_Scale_Stop(t *testing.T) {
 	})
 }
 
+// Ensure the reconciler records its decision & enforces the cooldown period.
+func TestReconciler_Scale_Cooldown(t *testing.T) {
+	var invokeStartN int
+	var client mock.FlapsClient
+	client.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+			{ID: "2", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+	client.StartFunc = func(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+		invokeStartN++
+		return &fly.MachineStartResponse{}, nil
+	}
+
+	r := fas.NewReconciler()
+	r.Client = &client
+	r.MinStartedMachineN = "1"
+	r.MaxStartedMachineN = "1"
+	r.Cooldown = time.Hour
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got, want := invokeStartN, 1; got != want {
+		t.Fatalf("startN=%v, want %v", got, want)
+	}
+
+	if got, want := r.State.LastDecision.Action, fas.ActionStart; got != want {
+		t.Fatalf("Action=%v, want %v", got, want)
+	} else if got, want := r.State.LastDecision.N, 1; got != want {
+		t.Fatalf("N=%v, want %v", got, want)
+	} else if got, want := *r.State.LastDecision.MinStartedN, 1; got != want {
+		t.Fatalf("MinStartedN=%v, want %v", got, want)
+	} else if got, want := r.State.CooldownUntil, r.State.LastScaledAt.Add(time.Hour); !got.Equal(want) {
+		t.Fatalf("CooldownUntil=%v, want %v", got, want)
+	}
+
+	// Machines are still stopped but no action should be taken during cooldown.
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got, want := invokeStartN, 1; got != want {
+		t.Fatalf("startN=%v, want %v", got, want)
+	} else if got, want := r.State.LastDecision.Action, fas.ActionNoScale; got != want {
+		t.Fatalf("Action=%v, want %v", got, want)
+	}
+}
+
+// Ensure a failed scaling action does not start the cooldown period so that it
+// is retried on the next reconciliation.
+func TestReconciler_Scale_CooldownAfterFailure(t *testing.T) {
+	var invokeStartN int
+	var client mock.FlapsClient
+	client.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+	client.StartFunc = func(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+		if invokeStartN++; invokeStartN == 1 {
+			return nil, fmt.Errorf("marker")
+		}
+		return &fly.MachineStartResponse{}, nil
+	}
+
+	r := fas.NewReconciler()
+	r.Client = &client
+	r.MinStartedMachineN = "1"
+	r.MaxStartedMachineN = "1"
+	r.Cooldown = time.Hour
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !r.State.CooldownUntil.IsZero() {
+		t.Fatalf("unexpected cooldown: %v", r.State.CooldownUntil)
+	} else if !r.State.LastScaledAt.IsZero() {
+		t.Fatalf("unexpected scale time: %v", r.State.LastScaledAt)
+	}
+
+	// The start is retried & the cooldown begins once it succeeds.
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got, want := invokeStartN, 2; got != want {
+		t.Fatalf("startN=%v, want %v", got, want)
+	} else if got, want := r.State.CooldownUntil, r.State.LastScaledAt.Add(time.Hour); !got.Equal(want) {
+		t.Fatalf("CooldownUntil=%v, want %v", got, want)
+	}
+}
+
 func machineCountByState(a []*fly.Machine, state string) (n int) {
 	for _, m := range a {
 		if m.State == state {
@@ -495,3 +582,147 @@ func machineCountByState(a []*fly.Machine, state string) (n int) {
 	}
 	return n
 }
+
+func TestReconciler_Compile(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN = "foo"
+		r.MaxStartedMachineN = "foo * 2"
+		r.Collectors = []fas.MetricCollector{mock.NewMetricCollector("foo")}
+		if err := r.Compile(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("ErrUnknownIdentifier", func(t *testing.T) {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN = "1"
+		r.MaxStartedMachineN = "bar"
+		r.Collectors = []fas.MetricCollector{mock.NewMetricCollector("foo")}
+		if err := r.Compile(); err == nil || err.Error() != `max started machine count: unknown identifier "bar" at column 1 in expression "bar" (available metrics: foo)` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestReconciler_ExprVars(t *testing.T) {
+	var client mock.FlapsClient
+	client.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", Region: "iad", State: fly.MachineStateStarted, HostStatus: fly.HostStatusOk},
+			{ID: "2", Region: "iad", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+			{ID: "3", Region: "ord", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+			{ID: "4", Region: "ord", State: fly.MachineStateStopped, HostStatus: fly.HostStatusUnreachable},
+		}, nil
+	}
+	client.StartFunc = func(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+		return &fly.MachineStartResponse{}, nil
+	}
+
+	r := fas.NewReconciler()
+	r.Client = &client
+	r.AppName = "my-app"
+	r.MinStartedMachineN = `app_name == "my-app" ? started_machines + stopped_machines_by_region["ord"] : 0`
+	r.MaxStartedMachineN = `created_machines + created_machines_by_region["xxx"]`
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got, want := *r.State.LastDecision.MinStartedN, 2; got != want {
+		t.Fatalf("MinStartedN=%v, want %v", got, want)
+	} else if got, want := *r.State.LastDecision.MaxStartedN, 3; got != want {
+		t.Fatalf("MaxStartedN=%v, want %v", got, want)
+	}
+
+	// Previous target & time since scale are based on the last decision.
+	r.MinStartedMachineN = `seconds_since_scale < 60 ? previous_target + 1 : 0`
+	if v, _, err := r.CalcMinStartedMachineN(); err != nil {
+		t.Fatal(err)
+	} else if got, want := v, 3; got != want {
+		t.Fatalf("MinStartedN=%v, want %v", got, want)
+	}
+}
+
+func TestReconciler_Policy(t *testing.T) {
+	var startN int
+	var client mock.FlapsClient
+	client.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStarted, HostStatus: fly.HostStatusOk},
+			{ID: "2", State: fly.MachineStateStarted, HostStatus: fly.HostStatusOk},
+			{ID: "3", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+			{ID: "4", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+	client.StartFunc = func(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+		startN++
+		return &fly.MachineStartResponse{}, nil
+	}
+
+	r := fas.NewReconciler()
+	r.Client = &client
+	r.StartedMachinePolicy = &fas.TargetTrackingPolicy{Metric: "cpu", Target: 50}
+	r.SetValue("cpu", 80)
+	if err := r.Compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two machines at 80% should scale to four machines at 40%.
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got, want := startN, 2; got != want {
+		t.Fatalf("startN=%v, want %v", got, want)
+	} else if got, want := *r.State.LastDecision.MinStartedN, 4; got != want {
+		t.Fatalf("MinStartedN=%v, want %v", got, want)
+	} else if got, want := *r.State.LastDecision.MaxStartedN, 4; got != want {
+		t.Fatalf("MaxStartedN=%v, want %v", got, want)
+	} else if got, want := len(r.State.Recommendations[fas.PolicyNameStarted]), 1; got != want {
+		t.Fatalf("len(Recommendations)=%v, want %v", got, want)
+	}
+
+	t.Run("ErrUnknownMetric", func(t *testing.T) {
+		r := fas.NewReconciler()
+		r.StartedMachinePolicy = &fas.TargetTrackingPolicy{Metric: "cpu", Target: 50}
+		if err := r.Compile(); err == nil || err.Error() != `started machine policy: unknown metric "cpu"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestReconciler_Forecast(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c := mock.NewRangeMetricCollector("requests")
+	c.CollectMetricFunc = func(ctx context.Context, app string) (float64, error) { return 10, nil }
+	c.CollectMetricRangeFunc = func(ctx context.Context, app string, start, end time.Time, step time.Duration) ([]fas.MetricSample, error) {
+		return []fas.MetricSample{
+			{Timestamp: end.Add(-2 * step), Value: 100},
+			{Timestamp: end.Add(-1 * step), Value: 200},
+			{Timestamp: end, Value: 300},
+		}, nil
+	}
+
+	r := fas.NewReconciler()
+	r.Collectors = []fas.MetricCollector{c}
+	r.Forecasters = []*fas.Forecaster{{Metric: "requests", Lookback: time.Hour, Step: time.Minute, Beta: 1}}
+	r.Now = func() time.Time { return now }
+	if err := r.Compile(); err != nil {
+		t.Fatal(err)
+	} else if err := r.CollectMetrics(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Range query history is used instead of the single local sample.
+	if v, err := r.Forecast("requests", 2*time.Minute); err != nil {
+		t.Fatal(err)
+	} else if got, want := v, 500.0; got != want {
+		t.Fatalf("Forecast=%v, want %v", got, want)
+	}
+
+	t.Run("ErrRangeNotSupported", func(t *testing.T) {
+		r := fas.NewReconciler()
+		r.Collectors = []fas.MetricCollector{mock.NewMetricCollector("requests")}
+		r.Forecasters = []*fas.Forecaster{{Metric: "requests", Lookback: time.Hour}}
+		if err := r.Compile(); err == nil || err.Error() != `forecast: metric "requests" does not support range queries` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
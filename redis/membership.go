@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+// Default membership settings.
+const (
+	DefaultMembershipKey = "fly-autoscaler:members"
+	DefaultMembershipTTL = 3 * time.Minute
+)
+
+var _ fas.Membership = (*Membership)(nil)
+
+// Membership implements fas.Membership using a Redis sorted set where each
+// member is scored by the time of its last heartbeat. Members that have not
+// sent a heartbeat within the TTL are considered dead and are removed.
+type Membership struct {
+	client *redis.Client
+
+	// Redis connection URL (e.g. "redis://localhost:6379/0").
+	// Must be set before calling Open().
+	URL string
+
+	// Name of the sorted set key.
+	Key string
+
+	// Time after the last heartbeat when a member is considered dead. This
+	// should be several times larger than the membership heartbeat interval.
+	TTL time.Duration
+
+	// Returns the current time. Used for testing.
+	Now func() time.Time
+}
+
+// NewMembership returns a new instance of Membership.
+func NewMembership(url string) *Membership {
+	return &Membership{
+		URL: url,
+		Key: DefaultMembershipKey,
+		TTL: DefaultMembershipTTL,
+		Now: time.Now,
+	}
+}
+
+func (m *Membership) Open() error {
+	if m.URL == "" {
+		return fmt.Errorf("redis url required")
+	}
+
+	opt, err := redis.ParseURL(m.URL)
+	if err != nil {
+		return fmt.Errorf("parse redis url: %w", err)
+	}
+	m.client = redis.NewClient(opt)
+	return nil
+}
+
+func (m *Membership) Close() error {
+	if m.client != nil {
+		return m.client.Close()
+	}
+	return nil
+}
+
+// Heartbeat updates the score for id, removes expired members, and returns
+// the IDs of all live members.
+func (m *Membership) Heartbeat(ctx context.Context, id string) ([]string, error) {
+	now := m.Now()
+	expiry := strconv.FormatInt(now.Add(-m.TTL).UnixMilli(), 10)
+
+	var members *redis.StringSliceCmd
+	if _, err := m.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, m.Key, redis.Z{Score: float64(now.UnixMilli()), Member: id})
+		pipe.ZRemRangeByScore(ctx, m.Key, "-inf", "("+expiry)
+		members = pipe.ZRange(ctx, m.Key, 0, -1)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("heartbeat: %w", err)
+	}
+	return members.Val(), nil
+}
+
+// Leave removes id from the membership.
+func (m *Membership) Leave(ctx context.Context, id string) error {
+	if err := m.client.ZRem(ctx, m.Key, id).Err(); err != nil {
+		return fmt.Errorf("leave: %w", err)
+	}
+	return nil
+}
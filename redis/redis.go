@@ -0,0 +1,134 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+// Default settings.
+const (
+	DefaultLockKey = "fly-autoscaler:leader"
+	DefaultLockTTL = 15 * time.Second
+)
+
+// acquireScript sets the lock if it is not held or extends its TTL if it is
+// already held by the caller. Returns 1 if the caller holds the lock.
+var acquireScript = redis.NewScript(`
+local v = redis.call("GET", KEYS[1])
+if v == false then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+elseif v == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lock only if it is held by the caller.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+var _ fas.LeaderElector = (*LeaderElector)(nil)
+
+// LeaderElector implements fas.LeaderElector using a Redis key with a TTL.
+// The leader must renew the key before the TTL expires or it loses leadership.
+type LeaderElector struct {
+	mu     sync.Mutex
+	client *redis.Client
+	held   bool
+
+	// Redis connection URL (e.g. "redis://localhost:6379/0").
+	// Must be set before calling Open().
+	URL string
+
+	// Name of the key used as the lock.
+	Key string
+
+	// Unique identifier for this instance. Generated on Open() if blank.
+	ID string
+
+	// Time until the lock expires if not renewed. This should be several
+	// times larger than the leader election interval.
+	TTL time.Duration
+}
+
+// NewLeaderElector returns a new instance of LeaderElector.
+func NewLeaderElector(url string) *LeaderElector {
+	return &LeaderElector{
+		URL: url,
+		Key: DefaultLockKey,
+		TTL: DefaultLockTTL,
+	}
+}
+
+func (e *LeaderElector) Open() error {
+	if e.URL == "" {
+		return fmt.Errorf("redis url required")
+	}
+
+	opt, err := redis.ParseURL(e.URL)
+	if err != nil {
+		return fmt.Errorf("parse redis url: %w", err)
+	}
+	e.client = redis.NewClient(opt)
+
+	if e.ID == "" {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		e.ID = hex.EncodeToString(buf)
+	}
+	return nil
+}
+
+func (e *LeaderElector) Close() error {
+	if e.client != nil {
+		return e.client.Close()
+	}
+	return nil
+}
+
+// Acquire attempts to set the lock key or renews it if it is already held.
+//
+// On error, the lock may still be held in Redis so the held state is left
+// unchanged. The next call reacquires our own key & Release still deletes it.
+func (e *LeaderElector) Acquire(ctx context.Context) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	n, err := acquireScript.Run(ctx, e.client, []string{e.Key}, e.ID, e.TTL.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("acquire lock: %w", err)
+	}
+	e.held = n == 1
+	return e.held, nil
+}
+
+// Release deletes the lock key if it is held by this instance.
+func (e *LeaderElector) Release(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.held {
+		return nil
+	}
+	e.held = false
+
+	if err := releaseScript.Run(ctx, e.client, []string{e.Key}, e.ID).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("release lock: %w", err)
+	}
+	return nil
+}
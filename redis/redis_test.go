@@ -0,0 +1,151 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/superfly/fly-autoscaler/redis"
+)
+
+func TestLeaderElector(t *testing.T) {
+	url := os.Getenv("FAS_TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("FAS_TEST_REDIS_URL not set, skipping")
+	}
+
+	e0, e1 := redis.NewLeaderElector(url), redis.NewLeaderElector(url)
+	if err := e0.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = e0.Close() }()
+	if err := e1.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = e1.Close() }()
+
+	if ok, err := e0.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected first elector to acquire leadership")
+	}
+
+	if ok, err := e1.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("expected second elector to not acquire leadership")
+	}
+
+	if ok, err := e0.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected first elector to retain leadership")
+	}
+
+	if err := e0.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := e1.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected second elector to acquire leadership")
+	}
+	if err := e1.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure a failed renewal does not lock out the leader until the TTL expires.
+func TestLeaderElector_RenewError(t *testing.T) {
+	url := os.Getenv("FAS_TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("FAS_TEST_REDIS_URL not set, skipping")
+	}
+
+	e0, e1 := redis.NewLeaderElector(url), redis.NewLeaderElector(url)
+	if err := e0.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = e0.Close() }()
+	if err := e1.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = e1.Close() }()
+
+	if ok, err := e0.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected first elector to acquire leadership")
+	}
+
+	// Fail the renewal with a canceled context to simulate a network error.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := e0.Acquire(ctx); err == nil {
+		t.Fatal("expected renew error")
+	}
+
+	if ok, err := e0.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected first elector to regain leadership")
+	}
+	if ok, err := e1.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("expected second elector to not acquire leadership")
+	}
+
+	// Release still deletes the key after a failed renewal.
+	if _, err := e0.Acquire(ctx); err == nil {
+		t.Fatal("expected renew error")
+	}
+	if err := e0.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := e1.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected second elector to acquire leadership")
+	}
+	if err := e1.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMembership(t *testing.T) {
+	url := os.Getenv("FAS_TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("FAS_TEST_REDIS_URL not set, skipping")
+	}
+
+	m := redis.NewMembership(url)
+	m.Key = "fly-autoscaler:test-members"
+	if err := m.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = m.Close() }()
+
+	if _, err := m.Heartbeat(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if members, err := m.Heartbeat(context.Background(), "b"); err != nil {
+		t.Fatal(err)
+	} else if got, want := len(members), 2; got != want {
+		t.Fatalf("len=%v, want %v", got, want)
+	}
+
+	if err := m.Leave(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if members, err := m.Heartbeat(context.Background(), "b"); err != nil {
+		t.Fatal(err)
+	} else if got, want := members, []string{"b"}; len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("members=%v, want %v", got, want)
+	}
+	if err := m.Leave(context.Background(), "b"); err != nil {
+		t.Fatal(err)
+	}
+}
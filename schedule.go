@@ -0,0 +1,202 @@
+package fas
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Schedule represents a calendar-based override of the machine counts computed
+// by expressions & policies. A schedule is either recurring, where it is active
+// for a duration after each time a cron spec fires, or is active for a single
+// fixed time range.
+//
+// While active, a schedule can raise the minimum (floor) or lower the maximum
+// (ceiling) number of machines or it can pause scaling entirely. When multiple
+// schedules are active, the highest floor & lowest ceiling are used.
+type Schedule struct {
+	Name string
+
+	// Wildcard pattern of app names the schedule applies to.
+	// A blank pattern matches all apps.
+	App string
+
+	// Recurring schedule. Active for Duration after each time Cron fires.
+	// Cron uses the standard 5-field format & is evaluated in Timezone.
+	Cron     string
+	Duration time.Duration
+	Timezone string
+
+	// Fixed time range. Active from From until Until.
+	From  time.Time
+	Until time.Time
+
+	// Floors & ceilings on machine counts. Nil values are not overridden.
+	MinCreatedN *int
+	MaxCreatedN *int
+	MinStartedN *int
+	MaxStartedN *int
+
+	// If true, no scaling is performed while the schedule is active.
+	Pause bool
+
+	// Compiled App pattern. Set on first match.
+	appRe *regexp.Regexp
+}
+
+// Validate returns an error if the schedule is misconfigured.
+func (s *Schedule) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("schedule name required")
+	} else if _, err := loadLocation(s.Timezone); err != nil {
+		return err
+	}
+
+	switch hasCron, hasRange := s.Cron != "", !s.From.IsZero() || !s.Until.IsZero(); {
+	case hasCron && hasRange:
+		return fmt.Errorf("schedule cannot define both cron and time range")
+	case hasCron:
+		if _, err := parseCronSpec(s.cronSpec()); err != nil {
+			return err
+		} else if s.Duration <= 0 {
+			return fmt.Errorf("schedule duration must be greater than zero")
+		}
+	case hasRange:
+		if s.From.IsZero() || s.Until.IsZero() {
+			return fmt.Errorf("schedule time range requires both from and until")
+		} else if !s.Until.After(s.From) {
+			return fmt.Errorf("schedule until must be after from")
+		}
+	default:
+		return fmt.Errorf("schedule requires either cron or time range")
+	}
+
+	if _, err := regexp.Compile(FormatWildcardAsRegexp(s.App)); err != nil {
+		return fmt.Errorf("invalid schedule app pattern %q", s.App)
+	}
+
+	for _, b := range []struct {
+		name     string
+		min, max *int
+	}{
+		{"created", s.MinCreatedN, s.MaxCreatedN},
+		{"started", s.MinStartedN, s.MaxStartedN},
+	} {
+		if (b.min != nil && *b.min < 0) || (b.max != nil && *b.max < 0) {
+			return fmt.Errorf("schedule %s machine count cannot be negative", b.name)
+		} else if b.min != nil && b.max != nil && *b.max < *b.min {
+			return fmt.Errorf("schedule max %s machine count cannot be less than min", b.name)
+		}
+	}
+
+	if !s.Pause && s.MinCreatedN == nil && s.MaxCreatedN == nil && s.MinStartedN == nil && s.MaxStartedN == nil {
+		return fmt.Errorf("schedule must set a machine count or pause")
+	}
+	return nil
+}
+
+// Matches returns true if the schedule applies to the given app. The app
+// pattern is compiled on the first call & reused afterward.
+func (s *Schedule) Matches(appName string) bool {
+	if s.appRe == nil {
+		re, err := regexp.Compile(FormatWildcardAsRegexp(s.App))
+		if err != nil {
+			return false
+		}
+		s.appRe = re
+	}
+	return s.appRe.MatchString(appName)
+}
+
+// Active returns true if the schedule is in effect at the given time.
+func (s *Schedule) Active(now time.Time) (bool, error) {
+	if s.Cron == "" {
+		return !now.Before(s.From) && now.Before(s.Until), nil
+	}
+
+	sched, err := parseCronSpec(s.cronSpec())
+	if err != nil {
+		return false, err
+	}
+	return !sched.Next(now.Add(-s.Duration)).After(now), nil
+}
+
+// cronSpec returns the cron spec with the schedule's time zone, if any.
+func (s *Schedule) cronSpec() string {
+	if s.Timezone == "" {
+		return s.Cron
+	}
+	return "CRON_TZ=" + s.Timezone + " " + s.Cron
+}
+
+// ApplySchedules overrides the targets on d with all schedules that are active
+// for the current app. The names of the active schedules are recorded on d.
+// Returns true if any active schedule pauses scaling.
+func (r *Reconciler) ApplySchedules(d *Decision) (paused bool, err error) {
+	var minCreatedN, maxCreatedN, minStartedN, maxStartedN *int
+	for i := range r.Schedules {
+		s := &r.Schedules[i]
+		if !s.Matches(r.AppName) {
+			continue
+		}
+
+		active, err := s.Active(d.Timestamp)
+		if err != nil {
+			return false, fmt.Errorf("schedule %q: %w", s.Name, err)
+		} else if !active {
+			continue
+		}
+
+		d.Schedules = append(d.Schedules, s.Name)
+		paused = paused || s.Pause
+
+		// Combine into the highest floor & lowest ceiling.
+		minCreatedN = combineBound(minCreatedN, s.MinCreatedN, true)
+		maxCreatedN = combineBound(maxCreatedN, s.MaxCreatedN, false)
+		minStartedN = combineBound(minStartedN, s.MinStartedN, true)
+		maxStartedN = combineBound(maxStartedN, s.MaxStartedN, false)
+	}
+
+	d.MinCreatedN, d.MaxCreatedN = applyScheduleBounds(d.MinCreatedN, d.MaxCreatedN, minCreatedN, maxCreatedN)
+	d.MinStartedN, d.MaxStartedN = applyScheduleBounds(d.MinStartedN, d.MaxStartedN, minStartedN, maxStartedN)
+	return paused, nil
+}
+
+// combineBound returns the higher (or lower) of a & b, ignoring nil values.
+func combineBound(a, b *int, higher bool) *int {
+	if a == nil {
+		return b
+	} else if b == nil {
+		return a
+	} else if higher {
+		return ptr(max(*a, *b))
+	}
+	return ptr(min(*a, *b))
+}
+
+// applyScheduleBounds raises min & max to at least floor & lowers them to at
+// most ceiling. The ceiling takes precedence if they conflict.
+func applyScheduleBounds(minN, maxN, floor, ceiling *int) (*int, *int) {
+	if floor != nil {
+		minN = ptr(max(deref(minN, *floor), *floor))
+		if maxN != nil {
+			maxN = ptr(max(*maxN, *floor))
+		}
+	}
+	if ceiling != nil {
+		maxN = ptr(min(deref(maxN, *ceiling), *ceiling))
+		if minN != nil {
+			minN = ptr(min(*minN, *ceiling))
+		}
+	}
+	return minN, maxN
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func deref(v *int, def int) int {
+	if v == nil {
+		return def
+	}
+	return *v
+}
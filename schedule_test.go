@@ -0,0 +1,157 @@
+package fas_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/mock"
+	"github.com/superfly/fly-go"
+)
+
+func TestSchedule_Active(t *testing.T) {
+	t.Run("Cron", func(t *testing.T) {
+		s := &fas.Schedule{Name: "business-hours", Cron: "0 8 * * 1-5", Duration: 12 * time.Hour, Timezone: "America/New_York"}
+		if err := s.Validate(); err == nil {
+			t.Fatal("expected error for schedule without an effect")
+		}
+
+		for _, tt := range []struct {
+			now  string
+			want bool
+		}{
+			{"2024-01-08T13:00:00Z", true},  // Monday 08:00 ET
+			{"2024-01-08T12:59:00Z", false}, // Monday 07:59 ET
+			{"2024-01-09T00:59:00Z", true},  // Monday 19:59 ET
+			{"2024-01-09T01:00:00Z", false}, // Monday 20:00 ET
+			{"2024-01-06T15:00:00Z", false}, // Saturday
+		} {
+			now, _ := time.Parse(time.RFC3339, tt.now)
+			if got, err := s.Active(now); err != nil {
+				t.Fatal(err)
+			} else if got != tt.want {
+				t.Errorf("Active(%s)=%v, want %v", tt.now, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		from := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+		s := &fas.Schedule{Name: "freeze", From: from, Until: from.Add(96 * time.Hour), Pause: true}
+		if err := s.Validate(); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tt := range []struct {
+			now  time.Time
+			want bool
+		}{
+			{from.Add(-time.Second), false},
+			{from, true},
+			{from.Add(95 * time.Hour), true},
+			{from.Add(96 * time.Hour), false},
+		} {
+			if got, err := s.Active(tt.now); err != nil {
+				t.Fatal(err)
+			} else if got != tt.want {
+				t.Errorf("Active(%s)=%v, want %v", tt.now, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		s    fas.Schedule
+		err  string
+	}{
+		{"NoName", fas.Schedule{}, `schedule name required`},
+		{"NoTime", fas.Schedule{Name: "x", Pause: true}, `schedule requires either cron or time range`},
+		{"NoDuration", fas.Schedule{Name: "x", Cron: "0 8 * * *", Pause: true}, `schedule duration must be greater than zero`},
+		{"InvalidTimezone", fas.Schedule{Name: "x", Cron: "0 8 * * *", Duration: time.Hour, Timezone: "Mars/Base", Pause: true}, `invalid time zone "Mars/Base"`},
+		{"MissingUntil", fas.Schedule{Name: "x", From: time.Now(), Pause: true}, `schedule time range requires both from and until`},
+		{"MaxLessThanMin", fas.Schedule{Name: "x", Cron: "0 8 * * *", Duration: time.Hour, MinStartedN: ptr(2), MaxStartedN: ptr(1)}, `schedule max started machine count cannot be less than min`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.s.Validate(); err == nil || err.Error() != tt.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestReconciler_ApplySchedules(t *testing.T) {
+	now := time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)
+	always := func(name, app string) fas.Schedule {
+		return fas.Schedule{Name: name, App: app, From: now.Add(-time.Hour), Until: now.Add(time.Hour)}
+	}
+
+	floor := always("floor", "")
+	floor.MinStartedN = ptr(5)
+	ceiling := always("ceiling", "my-*")
+	ceiling.MaxStartedN = ptr(8)
+	other := always("other", "other-app")
+	other.Pause = true
+
+	r := fas.NewReconciler()
+	r.AppName = "my-app"
+	r.Schedules = []fas.Schedule{floor, ceiling, other}
+
+	for _, tt := range []struct {
+		name               string
+		minN, maxN         *int
+		wantMinN, wantMaxN int
+	}{
+		{"RaiseFloor", ptr(2), ptr(2), 5, 5},
+		{"LowerCeiling", ptr(10), ptr(10), 8, 8},
+		{"WithinBounds", ptr(6), ptr(7), 6, 7},
+		{"Unset", nil, nil, 5, 8},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fas.Decision{Timestamp: now, MinStartedN: tt.minN, MaxStartedN: tt.maxN}
+			if paused, err := r.ApplySchedules(d); err != nil {
+				t.Fatal(err)
+			} else if paused {
+				t.Fatal("expected not paused")
+			} else if got, want := *d.MinStartedN, tt.wantMinN; got != want {
+				t.Fatalf("MinStartedN=%v, want %v", got, want)
+			} else if got, want := *d.MaxStartedN, tt.wantMaxN; got != want {
+				t.Fatalf("MaxStartedN=%v, want %v", got, want)
+			} else if got, want := len(d.Schedules), 2; got != want {
+				t.Fatalf("len(Schedules)=%v, want %v", got, want)
+			} else if d.MinCreatedN != nil || d.MaxCreatedN != nil {
+				t.Fatal("expected created machine counts to be unset")
+			}
+		})
+	}
+}
+
+func TestReconciler_Reconcile_SchedulePause(t *testing.T) {
+	now := time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)
+
+	var client mock.FlapsClient
+	client.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+
+	r := fas.NewReconciler()
+	r.Client = &client
+	r.Now = func() time.Time { return now }
+	r.MinStartedMachineN, r.MaxStartedMachineN = "1", "1"
+	r.Schedules = []fas.Schedule{{Name: "freeze", From: now.Add(-time.Hour), Until: now.Add(time.Hour), Pause: true}}
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got, want := r.State.LastDecision.Action, fas.ActionNoScale; got != want {
+		t.Fatalf("Action=%v, want %v", got, want)
+	} else if got, want := r.State.LastDecision.Schedules, []string{"freeze"}; len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("Schedules=%v, want %v", got, want)
+	} else if got, want := r.Stats.NoScale.Load(), int64(1); got != want {
+		t.Fatalf("NoScale=%v, want %v", got, want)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,103 @@
+package fas
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// DefaultHashRingReplicaN is the number of virtual nodes per ring member.
+// A higher number spreads apps more evenly across members.
+const DefaultHashRingReplicaN = 128
+
+// HashRing distributes keys across a set of members using consistent hashing.
+// Adding or removing a member only moves the keys owned by that member.
+type HashRing struct {
+	points []hashRingPoint
+}
+
+type hashRingPoint struct {
+	hash   uint64
+	member string
+}
+
+// NewHashRing returns a new ring for the given members.
+func NewHashRing(members []string) *HashRing {
+	r := &HashRing{
+		points: make([]hashRingPoint, 0, len(members)*DefaultHashRingReplicaN),
+	}
+	for _, member := range members {
+		for i := 0; i < DefaultHashRingReplicaN; i++ {
+			r.points = append(r.points, hashRingPoint{
+				hash:   hashKey(member + "#" + strconv.Itoa(i)),
+				member: member,
+			})
+		}
+	}
+
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash == r.points[j].hash {
+			return r.points[i].member < r.points[j].member
+		}
+		return r.points[i].hash < r.points[j].hash
+	})
+	return r
+}
+
+// Owner returns the member that owns key. Returns blank if the ring is empty.
+func (r *HashRing) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+
+	h := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+	if i == len(r.points) {
+		i = 0 // wrap around
+	}
+	return r.points[i].member
+}
+
+// hashKey returns a 64-bit hash of s. FNV-1a output is passed through a
+// finalizer as similar keys (e.g. "my-app-1", "my-app-2") otherwise cluster.
+func hashKey(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+var _ Membership = (*StaticMembership)(nil)
+
+// StaticMembership is a Membership with a fixed number of instances which are
+// identified by their index (e.g. "0", "1", "2"). Each instance must be
+// configured with its own unique index.
+type StaticMembership struct {
+	n int
+}
+
+// NewStaticMembership returns a membership of n instances.
+func NewStaticMembership(n int) *StaticMembership {
+	return &StaticMembership{n: n}
+}
+
+// Heartbeat returns the IDs of all instances. All instances are assumed alive.
+func (m *StaticMembership) Heartbeat(ctx context.Context, id string) ([]string, error) {
+	ids := make([]string, m.n)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i)
+	}
+	return ids, nil
+}
+
+// Leave is a no-op as static membership does not change.
+func (m *StaticMembership) Leave(ctx context.Context, id string) error {
+	return nil
+}
@@ -0,0 +1,54 @@
+package fas_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	fas "github.com/superfly/fly-autoscaler"
+)
+
+func TestHashRing_Owner(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		if got, want := fas.NewHashRing(nil).Owner("foo"), ""; got != want {
+			t.Fatalf("owner=%q, want %q", got, want)
+		}
+	})
+
+	// Ensure keys are spread across all members.
+	t.Run("Distribution", func(t *testing.T) {
+		r := fas.NewHashRing([]string{"a", "b", "c"})
+
+		counts := make(map[string]int)
+		for i := 0; i < 3000; i++ {
+			counts[r.Owner(fmt.Sprintf("app-%d", i))]++
+		}
+		for _, member := range []string{"a", "b", "c"} {
+			if n := counts[member]; n < 600 || n > 1400 {
+				t.Fatalf("member %q owns %d keys, expected roughly even split", member, n)
+			}
+		}
+	})
+
+	// Ensure removing a member only moves keys that it owned.
+	t.Run("Rebalance", func(t *testing.T) {
+		r0 := fas.NewHashRing([]string{"a", "b", "c"})
+		r1 := fas.NewHashRing([]string{"a", "b"})
+
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("app-%d", i)
+			if prev := r0.Owner(key); prev != "c" && r1.Owner(key) != prev {
+				t.Fatalf("key %q moved from %q to %q", key, prev, r1.Owner(key))
+			}
+		}
+	})
+}
+
+func TestStaticMembership_Heartbeat(t *testing.T) {
+	ids, err := fas.NewStaticMembership(3).Heartbeat(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := fmt.Sprint(ids), "[0 1 2]"; got != want {
+		t.Fatalf("ids=%v, want %v", got, want)
+	}
+}
@@ -0,0 +1,188 @@
+package fas
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// AppStatus represents the current status of an app managed by the pool.
+type AppStatus struct {
+	App          string `json:"app"`
+	ProcessGroup string `json:"process_group,omitempty"`
+
+	// Machine counts from the most recent reconciliation.
+	Machines *MachineCounts `json:"machines,omitempty"`
+
+	// Values collected by each metric collector on the most recent reconciliation.
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+
+	// Most recent decision, including the computed targets & action taken.
+	LastDecision *Decision `json:"last_decision,omitempty"`
+
+	LastReconcileAt *time.Time `json:"last_reconcile_at,omitempty"`
+	LastSuccessAt   *time.Time `json:"last_success_at,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastErrorAt     *time.Time `json:"last_error_at,omitempty"`
+
+	// Time of the next scheduled reconciliation. Nil if the app has not been
+	// scheduled yet or this instance is not the leader.
+	NextReconcileAt *time.Time `json:"next_reconcile_at,omitempty"`
+
+	// True while the app is queued or being reconciled.
+	InFlight bool `json:"in_flight"`
+
+	CircuitBreaker      string `json:"circuit_breaker,omitempty"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+
+	// Active pause or manual machine count override, if any.
+	Override *Override `json:"override,omitempty"`
+}
+
+// MachineCounts represents the number of machines in each state.
+type MachineCounts struct {
+	Created int `json:"created"`
+	Started int `json:"started"`
+	Stopped int `json:"stopped"`
+
+	// Counts broken down by region.
+	Regions map[string]*MachineCounts `json:"regions,omitempty"`
+}
+
+// MaxAppSampleN is the maximum number of status samples retained per app.
+const MaxAppSampleN = MaxMetricSampleN
+
+// AppSample represents an app's machine counts & targets after a single
+// reconciliation. Machine counts are nil if machines were not listed and
+// targets are nil if no decision was made.
+type AppSample struct {
+	Timestamp time.Time `json:"timestamp"`
+
+	Created *int `json:"created,omitempty"`
+	Started *int `json:"started,omitempty"`
+
+	MinCreatedN *int `json:"min_created,omitempty"`
+	MaxCreatedN *int `json:"max_created,omitempty"`
+	MinStartedN *int `json:"min_started,omitempty"`
+	MaxStartedN *int `json:"max_started,omitempty"`
+
+	Action string `json:"action,omitempty"`
+	N      int    `json:"n,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// AppStatuses returns the status of every app managed by this instance,
+// sorted by app name.
+func (p *ReconcilerPool) AppStatuses() []*AppStatus {
+	names := p.AppNames()
+	a := make([]*AppStatus, 0, len(names))
+	for _, name := range names {
+		a = append(a, p.appStatus(name))
+	}
+	return a
+}
+
+// AppStatus returns the status of a single app.
+// Returns ErrAppNotFound if the app is not managed by this instance.
+func (p *ReconcilerPool) AppStatus(name string) (*AppStatus, error) {
+	p.apps.Lock()
+	_, ok := p.apps.m[name]
+	p.apps.Unlock()
+	if !ok {
+		return nil, ErrAppNotFound
+	}
+	return p.appStatus(name), nil
+}
+
+// AppSamples returns the recent status samples for an app, oldest first.
+// Returns ErrAppNotFound if the app is not managed by this instance.
+func (p *ReconcilerPool) AppSamples(name string) ([]AppSample, error) {
+	p.apps.Lock()
+	_, ok := p.apps.m[name]
+	p.apps.Unlock()
+	if !ok {
+		return nil, ErrAppNotFound
+	}
+
+	p.appMetrics.Lock()
+	defer p.appMetrics.Unlock()
+
+	if m := p.appMetrics.m[name]; m != nil {
+		return slices.Clone(m.samples), nil
+	}
+	return []AppSample{}, nil
+}
+
+func (p *ReconcilerPool) appStatus(name string) *AppStatus {
+	status := &AppStatus{App: name, Override: p.activeOverride(name, time.Now())}
+
+	// The last decision is read from the app's state so it is available
+	// after a restart if a persistent store is used.
+	if state := p.AppState(name); state != nil {
+		status.LastDecision = state.LastDecision
+	}
+
+	p.appMetrics.Lock()
+	if m := p.appMetrics.m[name]; m != nil {
+		status.ProcessGroup = m.processGroup
+		status.Metrics = maps.Clone(m.values)
+		status.LastReconcileAt = timePtr(m.lastReconcileAt)
+		status.LastSuccessAt = timePtr(m.lastSuccessAt)
+		status.LastError = m.lastError
+		status.LastErrorAt = timePtr(m.lastErrorAt)
+
+		if m.fleet.CreatedNByRegion != nil {
+			status.Machines = &MachineCounts{
+				Created: m.fleet.CreatedN,
+				Started: m.fleet.StartedN,
+				Stopped: m.fleet.StoppedN,
+				Regions: make(map[string]*MachineCounts),
+			}
+			for region := range m.fleet.CreatedNByRegion {
+				status.Machines.Regions[region] = &MachineCounts{
+					Created: m.fleet.CreatedNByRegion[region],
+					Started: m.fleet.StartedNByRegion[region],
+					Stopped: m.fleet.StoppedNByRegion[region],
+				}
+			}
+		}
+	}
+	p.appMetrics.Unlock()
+
+	p.schedules.Lock()
+	if sched := p.schedules.m[name]; sched != nil {
+		status.NextReconcileAt = timePtr(sched.dueAt())
+		status.InFlight = sched.inFlight
+		status.CircuitBreaker = p.circuitBreakerStateLocked(sched, time.Now())
+		status.ConsecutiveFailures = sched.failureN
+	}
+	p.schedules.Unlock()
+
+	return status
+}
+
+// timePtr returns a pointer to t or nil if t is zero.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// newAppSample returns a sample of the app's machine counts & new decision, if
+// any, after reconciling r.
+func newAppSample(r *Reconciler, prev *Decision, collected bool, t time.Time, err error) AppSample {
+	sample := AppSample{Timestamp: t}
+	if fleet := r.MachineCounts(); collected && fleet.CreatedNByRegion != nil {
+		sample.Created, sample.Started = &fleet.CreatedN, &fleet.StartedN
+	}
+	if d := r.State.LastDecision; d != nil && d != prev {
+		sample.MinCreatedN, sample.MaxCreatedN = d.MinCreatedN, d.MaxCreatedN
+		sample.MinStartedN, sample.MaxStartedN = d.MinStartedN, d.MaxStartedN
+		sample.Action, sample.N = d.Action, d.N
+	}
+	if err != nil {
+		sample.Error = err.Error()
+	}
+	return sample
+}
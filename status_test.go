@@ -0,0 +1,140 @@
+package fas_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	fas "github.com/superfly/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/mock"
+	fly "github.com/superfly/fly-go"
+)
+
+func TestReconcilerPool_AppStatus(t *testing.T) {
+	var flyClient mock.FlyClient
+	flyClient.GetAppCurrentReleaseMachinesFunc = func(ctx context.Context, appName string) (*fly.Release, error) {
+		return &fly.Release{Status: "completed"}, nil
+	}
+
+	var flapsClient mock.FlapsClient
+	flapsClient.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStarted, Region: "iad", HostStatus: fly.HostStatusOk},
+			{ID: "2", State: fly.MachineStateStopped, Region: "ord", HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+	flapsClient.StartFunc = func(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+		return &fly.MachineStartResponse{}, nil
+	}
+
+	var fail atomic.Bool
+	collector := mock.NewMetricCollector("queue_depth")
+	collector.CollectMetricFunc = func(ctx context.Context, app string) (float64, error) {
+		if fail.Load() {
+			return 0, errors.New("marker")
+		}
+		return 2, nil
+	}
+
+	p := fas.NewReconcilerPool(&flyClient, 1)
+	p.AppName = "my-app"
+	p.ReconcileInterval = time.Hour
+	p.NewReconciler = func() *fas.Reconciler {
+		r := fas.NewReconciler()
+		r.MinStartedMachineN = "queue_depth"
+		r.Collectors = []fas.MetricCollector{collector}
+		return r
+	}
+	p.NewFlapsClient = func(ctx context.Context, name string) (fas.FlapsClient, error) {
+		return &flapsClient, nil
+	}
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	if _, err := p.AppStatus("other-app"); !errors.Is(err, fas.ErrAppNotFound) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := p.Trigger("my-app"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	statuses := p.AppStatuses()
+	if got, want := len(statuses), 1; got != want {
+		t.Fatalf("len=%v, want %v", got, want)
+	}
+
+	status := statuses[0]
+	if got, want := status.App, "my-app"; got != want {
+		t.Fatalf("App=%v, want %v", got, want)
+	} else if got, want := status.Machines.Started, 1; got != want {
+		t.Fatalf("Machines.Started=%v, want %v", got, want)
+	} else if got, want := status.Machines.Regions["ord"].Stopped, 1; got != want {
+		t.Fatalf("Machines.Regions[ord].Stopped=%v, want %v", got, want)
+	} else if got, want := status.Metrics["queue_depth"], 2.0; got != want {
+		t.Fatalf("Metrics[queue_depth]=%v, want %v", got, want)
+	} else if got, want := *status.LastDecision.MinStartedN, 2; got != want {
+		t.Fatalf("LastDecision.MinStartedN=%v, want %v", got, want)
+	} else if got, want := status.LastDecision.Action, fas.ActionStart; got != want {
+		t.Fatalf("LastDecision.Action=%v, want %v", got, want)
+	} else if status.LastReconcileAt == nil {
+		t.Fatal("expected last reconcile time")
+	} else if status.NextReconcileAt == nil || !status.NextReconcileAt.After(time.Now()) {
+		t.Fatalf("unexpected next reconcile time: %v", status.NextReconcileAt)
+	} else if status.LastError != "" {
+		t.Fatalf("unexpected last error: %s", status.LastError)
+	}
+
+	// Fail metric collection & ensure the error is reported while the last
+	// decision is retained.
+	fail.Store(true)
+	if _, err := p.Trigger("my-app"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if status, err := p.AppStatus("my-app"); err != nil {
+		t.Fatal(err)
+	} else if got, want := status.LastError, `collect metric ("queue_depth"): marker`; got != want {
+		t.Fatalf("LastError=%v, want %v", got, want)
+	} else if got, want := status.ConsecutiveFailures, 1; got != want {
+		t.Fatalf("ConsecutiveFailures=%v, want %v", got, want)
+	} else if got, want := status.LastDecision.Action, fas.ActionStart; got != want {
+		t.Fatalf("LastDecision.Action=%v, want %v", got, want)
+	}
+
+	// Samples are recorded for each reconciliation.
+	samples, err := p.AppSamples("my-app")
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := len(samples), 2; got != want {
+		t.Fatalf("len=%v, want %v", got, want)
+	} else if got, want := *samples[0].Started, 1; got != want {
+		t.Fatalf("Started=%v, want %v", got, want)
+	} else if got, want := *samples[0].MinStartedN, 2; got != want {
+		t.Fatalf("MinStartedN=%v, want %v", got, want)
+	} else if samples[1].Started != nil || samples[1].MinStartedN != nil {
+		t.Fatalf("unexpected sample: %#v", samples[1])
+	} else if samples[1].Error == "" {
+		t.Fatal("expected sample error")
+	}
+
+	// The collector's most recent collection failed.
+	collectors := p.CollectorHealth()
+	if got, want := len(collectors), 1; got != want {
+		t.Fatalf("len=%v, want %v", got, want)
+	} else if got, want := collectors[0].SuccessN, int64(1); got != want {
+		t.Fatalf("SuccessN=%v, want %v", got, want)
+	} else if got, want := collectors[0].ErrorN, int64(1); got != want {
+		t.Fatalf("ErrorN=%v, want %v", got, want)
+	} else if got, want := collectors[0].LastErrorApp, "my-app"; got != want {
+		t.Fatalf("LastErrorApp=%v, want %v", got, want)
+	} else if collectors[0].Healthy() {
+		t.Fatal("expected unhealthy collector")
+	}
+}
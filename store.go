@@ -0,0 +1,206 @@
+package fas
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MaxMetricSampleN is the maximum number of samples retained per metric. This
+// covers one hour of history at the default reconcile interval.
+const MaxMetricSampleN = 240
+
+// MetricHistoryDuration returns the length of metric history that is retained
+// when an app is reconciled every interval.
+func MetricHistoryDuration(interval time.Duration) time.Duration {
+	return MaxMetricSampleN * interval
+}
+
+// Scaling actions recorded in a Decision.
+const (
+	ActionCreate  = "create"
+	ActionDestroy = "destroy"
+	ActionStart   = "start"
+	ActionStop    = "stop"
+	ActionNoScale = "no_scale"
+)
+
+// Store represents persistent storage of per-app autoscaler state. This allows
+// the autoscaler to retain knowledge of recent scaling actions across restarts.
+type Store interface {
+	// AppStates returns the state for all apps in the store.
+	AppStates(ctx context.Context) ([]*AppState, error)
+
+	// PutAppState creates or replaces the state for a single app.
+	PutAppState(ctx context.Context, state *AppState) error
+}
+
+// AppState represents the state of a single app that is tracked across
+// reconciliations.
+type AppState struct {
+	AppName string `json:"app_name"`
+
+	// The most recent decision made by the reconciler.
+	LastDecision *Decision `json:"last_decision,omitempty"`
+
+	// Time of the most recent scaling action (e.g. not a no-op).
+	LastScaledAt time.Time `json:"last_scaled_at,omitempty"`
+
+	// Scaling actions are skipped until this time has passed.
+	CooldownUntil time.Time `json:"cooldown_until,omitempty"`
+
+	// Recently collected metric values, keyed by metric name.
+	Metrics map[string][]MetricSample `json:"metrics,omitempty"`
+
+	// Recent unstabilized recommendations made by scaling policies, keyed by
+	// the machine count the policy computes (e.g. "started").
+	Recommendations map[string][]MetricSample `json:"recommendations,omitempty"`
+
+	// State of PID controllers, keyed by the machine count they compute.
+	Controllers map[string]PIDState `json:"controllers,omitempty"`
+
+	// Manual pause or fixed machine counts set through the admin API.
+	Override *Override `json:"override,omitempty"`
+}
+
+// NewAppState returns a new instance of AppState for the given app.
+func NewAppState(appName string) *AppState {
+	return &AppState{
+		AppName: appName,
+		Metrics: make(map[string][]MetricSample),
+	}
+}
+
+// Clone returns a deep copy of s.
+func (s *AppState) Clone() *AppState {
+	if s == nil {
+		return nil
+	}
+
+	other := *s
+	if s.LastDecision != nil {
+		decision := *s.LastDecision
+		other.LastDecision = &decision
+	}
+
+	other.Metrics = cloneSamples(s.Metrics)
+	if s.Recommendations != nil {
+		other.Recommendations = cloneSamples(s.Recommendations)
+	}
+	if s.Controllers != nil {
+		other.Controllers = maps.Clone(s.Controllers)
+	}
+	other.Override = s.Override.Clone()
+	return &other
+}
+
+func cloneSamples(m map[string][]MetricSample) map[string][]MetricSample {
+	other := make(map[string][]MetricSample, len(m))
+	for name, samples := range m {
+		other[name] = append([]MetricSample(nil), samples...)
+	}
+	return other
+}
+
+// AddMetricSample appends a sample for the named metric. Only the most recent
+// MaxMetricSampleN samples are retained.
+func (s *AppState) AddMetricSample(name string, sample MetricSample) {
+	if s.Metrics == nil {
+		s.Metrics = make(map[string][]MetricSample)
+	}
+
+	s.Metrics[name] = appendSample(s.Metrics[name], sample)
+}
+
+// AddRecommendation appends a policy recommendation for the named machine
+// count. Only the most recent MaxMetricSampleN recommendations are retained.
+func (s *AppState) AddRecommendation(name string, sample MetricSample) {
+	if s.Recommendations == nil {
+		s.Recommendations = make(map[string][]MetricSample)
+	}
+	s.Recommendations[name] = appendSample(s.Recommendations[name], sample)
+}
+
+// SetController sets the state of the PID controller for the named machine
+// count.
+func (s *AppState) SetController(name string, state PIDState) {
+	if s.Controllers == nil {
+		s.Controllers = make(map[string]PIDState)
+	}
+	s.Controllers[name] = state
+}
+
+func appendSample(samples []MetricSample, sample MetricSample) []MetricSample {
+	samples = append(samples, sample)
+	if len(samples) > MaxMetricSampleN {
+		samples = samples[len(samples)-MaxMetricSampleN:]
+	}
+	return samples
+}
+
+// MetricSample represents a single collected metric value.
+type MetricSample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// Decision represents the outcome of a single reconciliation.
+type Decision struct {
+	Timestamp time.Time `json:"timestamp"`
+	Action    string    `json:"action"`
+	N         int       `json:"n,omitempty"` // number of machines acted upon
+
+	// Target bounds computed from the expressions, if defined.
+	MinCreatedN *int `json:"min_created,omitempty"`
+	MaxCreatedN *int `json:"max_created,omitempty"`
+	MinStartedN *int `json:"min_started,omitempty"`
+	MaxStartedN *int `json:"max_started,omitempty"`
+
+	// Names of schedules that were active & applied to the targets.
+	Schedules []string `json:"schedules,omitempty"`
+
+	// True if the targets were fixed by a manual override.
+	Override bool `json:"override,omitempty"`
+
+	// IDs of machines that were successfully acted upon.
+	MachineIDs []string `json:"machine_ids,omitempty"`
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an implementation of Store that only retains state in memory.
+// This is the default store and its state is lost when the process exits.
+type MemoryStore struct {
+	mu sync.Mutex
+	m  map[string]*AppState
+}
+
+// NewMemoryStore returns a new instance of MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		m: make(map[string]*AppState),
+	}
+}
+
+// AppStates returns the state for all apps, sorted by app name.
+func (s *MemoryStore) AppStates(ctx context.Context) ([]*AppState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := make([]*AppState, 0, len(s.m))
+	for _, state := range s.m {
+		a = append(a, state.Clone())
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].AppName < a[j].AppName })
+	return a, nil
+}
+
+// PutAppState stores a copy of state.
+func (s *MemoryStore) PutAppState(ctx context.Context, state *AppState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[state.AppName] = state.Clone()
+	return nil
+}
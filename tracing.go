@@ -0,0 +1,66 @@
+package fas
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates spans for each reconciliation. Spans are not recorded unless
+// a tracer provider is registered with otel.SetTracerProvider().
+var tracer = otel.Tracer("github.com/superfly/fly-autoscaler")
+
+// endSpan records err on span, if any, and ends the span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// exprAttributes returns the inputs used to compute the app's targets: the
+// machine count expressions, current machine counts & all metric values.
+func (r *Reconciler) exprAttributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("fas.expr.min_created", r.MinCreatedMachineN),
+		attribute.String("fas.expr.max_created", r.MaxCreatedMachineN),
+		attribute.String("fas.expr.min_started", r.MinStartedMachineN),
+		attribute.String("fas.expr.max_started", r.MaxStartedMachineN),
+		attribute.Int("fas.machines.created", r.fleet.CreatedN),
+		attribute.Int("fas.machines.started", r.fleet.StartedN),
+		attribute.Int("fas.machines.stopped", r.fleet.StoppedN),
+	}
+	for _, name := range r.MetricNames() {
+		if v, ok := r.Value(name); ok {
+			attrs = append(attrs, attribute.Float64("fas.metric."+name, v))
+		}
+	}
+	return attrs
+}
+
+// decisionAttributes returns the targets, active schedules & override of d.
+func decisionAttributes(d *Decision) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	for _, target := range []struct {
+		key string
+		v   *int
+	}{
+		{"fas.target.min_created", d.MinCreatedN},
+		{"fas.target.max_created", d.MaxCreatedN},
+		{"fas.target.min_started", d.MinStartedN},
+		{"fas.target.max_started", d.MaxStartedN},
+	} {
+		if target.v != nil {
+			attrs = append(attrs, attribute.Int(target.key, *target.v))
+		}
+	}
+	if len(d.Schedules) > 0 {
+		attrs = append(attrs, attribute.StringSlice("fas.schedules", d.Schedules))
+	}
+	if d.Override {
+		attrs = append(attrs, attribute.Bool("fas.override", true))
+	}
+	return attrs
+}
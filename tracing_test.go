@@ -0,0 +1,70 @@
+package fas_test
+
+import (
+	"context"
+	"testing"
+
+	fas "github.com/superfly/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/mock"
+	"github.com/superfly/fly-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Ensure each step of a reconciliation is recorded as a span.
+func TestReconciler_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(sdktrace.NewTracerProvider()) })
+
+	var client mock.FlapsClient
+	client.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+	client.StartFunc = func(ctx context.Context, id, nonce string) (*fly.MachineStartResponse, error) {
+		return &fly.MachineStartResponse{}, nil
+	}
+
+	collector := mock.NewMetricCollector("queue_depth")
+	collector.CollectMetricFunc = func(ctx context.Context, app string) (float64, error) {
+		return 1, nil
+	}
+
+	r := fas.NewReconciler()
+	r.AppName = "my-app"
+	r.Client = &client
+	r.MinStartedMachineN = "queue_depth"
+	r.Collectors = []fas.MetricCollector{collector}
+	if err := r.CollectMetrics(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	if got, want := len(names), 4; got != want {
+		t.Fatalf("spans=%v, want %v", names, want)
+	} else if names[0] != "collect_metric" || names[1] != "list_machines" || names[2] != "evaluate" || names[3] != "start_machine" {
+		t.Fatalf("unexpected spans: %v", names)
+	}
+
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range spans[2].Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if got, want := attrs["fas.metric.queue_depth"].AsFloat64(), 1.0; got != want {
+		t.Fatalf("fas.metric.queue_depth=%v, want %v", got, want)
+	} else if got, want := attrs["fas.expr.min_started"].AsString(), "queue_depth"; got != want {
+		t.Fatalf("fas.expr.min_started=%v, want %v", got, want)
+	} else if got, want := attrs["fas.target.min_started"].AsInt64(), int64(1); got != want {
+		t.Fatalf("fas.target.min_started=%v, want %v", got, want)
+	}
+}
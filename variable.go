@@ -0,0 +1,126 @@
+package fas
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+)
+
+// Variable represents a named expression that is evaluated after metrics are
+// collected. Its value is available to other variables & to the machine count
+// expressions as if it were a collected metric.
+type Variable struct {
+	Name string
+	Expr string
+}
+
+var variableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate returns an error if the variable does not have a valid name or
+// does not have an expression.
+func (v *Variable) Validate() error {
+	if v.Name == "" {
+		return fmt.Errorf("variable name required")
+	} else if !variableNameRegex.MatchString(v.Name) {
+		return fmt.Errorf("invalid variable name %q", v.Name)
+	} else if v.Expr == "" {
+		return fmt.Errorf("variable %q: %w", v.Name, ErrExprRequired)
+	}
+	return nil
+}
+
+// SortVariables returns variables in the order they must be evaluated so that
+// each variable is evaluated after the variables it references. Variables with
+// no dependency between them retain their original order. Returns an error if
+// a variable is defined twice or if there is a dependency cycle.
+func SortVariables(vars []Variable) ([]Variable, error) {
+	byName := make(map[string]Variable, len(vars))
+	for _, v := range vars {
+		if _, ok := byName[v.Name]; ok {
+			return nil, fmt.Errorf("duplicate variable %q", v.Name)
+		}
+		byName[v.Name] = v
+	}
+
+	// Determine which variables each variable references.
+	deps := make(map[string][]string, len(vars))
+	for _, v := range vars {
+		idents, err := exprIdentifiers(v.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("variable %q: %w", v.Name, err)
+		}
+		for _, ident := range idents {
+			if _, ok := byName[ident]; ok {
+				deps[v.Name] = append(deps[v.Name], ident)
+			}
+		}
+	}
+
+	// Depth-first search, tracking the current path to report cycles.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(vars))
+	sorted := make([]Variable, 0, len(vars))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			i := slices.Index(path, name)
+			cycle := append(path[i:len(path):len(path)], name)
+			return fmt.Errorf("variable dependency cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range deps[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		sorted = append(sorted, byName[name])
+		return nil
+	}
+
+	for _, v := range vars {
+		if err := visit(v.Name); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// exprIdentifiers returns the names of all identifiers referenced by s.
+func exprIdentifiers(s string) ([]string, error) {
+	tree, err := parser.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("parse expression: %w", err)
+	}
+
+	v := &identifierCollector{}
+	ast.Walk(&tree.Node, v)
+	return v.names, nil
+}
+
+type identifierCollector struct {
+	names []string
+}
+
+func (v *identifierCollector) Visit(node *ast.Node) {
+	if ident, ok := (*node).(*ast.IdentifierNode); ok {
+		v.names = append(v.names, ident.Value)
+	}
+}
@@ -0,0 +1,90 @@
+package fas_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	fas "github.com/superfly/fly-autoscaler"
+	"github.com/superfly/fly-autoscaler/mock"
+	"github.com/superfly/fly-go"
+)
+
+func TestSortVariables(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		sorted, err := fas.SortVariables([]fas.Variable{
+			{Name: "c", Expr: "a + b"},
+			{Name: "a", Expr: "1"},
+			{Name: "b", Expr: "a * 2"},
+			{Name: "d", Expr: "queue_depth"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, v := range sorted {
+			names = append(names, v.Name)
+		}
+		if got, want := fmt.Sprint(names), "[a b c d]"; got != want {
+			t.Fatalf("order=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrCycle", func(t *testing.T) {
+		_, err := fas.SortVariables([]fas.Variable{
+			{Name: "a", Expr: "b"},
+			{Name: "b", Expr: "a"},
+		})
+		if err == nil || err.Error() != `variable dependency cycle: a -> b -> a` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrSelfReference", func(t *testing.T) {
+		_, err := fas.SortVariables([]fas.Variable{{Name: "a", Expr: "a + 1"}})
+		if err == nil || err.Error() != `variable dependency cycle: a -> a` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrDuplicate", func(t *testing.T) {
+		_, err := fas.SortVariables([]fas.Variable{{Name: "a", Expr: "1"}, {Name: "a", Expr: "2"}})
+		if err == nil || err.Error() != `duplicate variable "a"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestReconciler_Variables(t *testing.T) {
+	var client mock.FlapsClient
+	client.ListFunc = func(ctx context.Context, state string) ([]*fly.Machine, error) {
+		return []*fly.Machine{
+			{ID: "1", State: fly.MachineStateStarted, HostStatus: fly.HostStatusOk},
+			{ID: "2", State: fly.MachineStateStopped, HostStatus: fly.HostStatusOk},
+		}, nil
+	}
+
+	r := fas.NewReconciler()
+	r.Client = &client
+	r.Variables = []fas.Variable{
+		{Name: "demand", Expr: "ceil(queue_depth / per_machine)"},
+		{Name: "per_machine", Expr: "10"},
+	}
+	r.MinStartedMachineN = "demand"
+	r.MaxStartedMachineN = "demand + started_machines"
+	r.SetValue("queue_depth", 5)
+	if err := r.Compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if v, _ := r.Value("demand"); v != 1 {
+		t.Fatalf("demand=%v, want 1", v)
+	} else if got, want := *r.State.LastDecision.MaxStartedN, 2; got != want {
+		t.Fatalf("MaxStartedN=%v, want %v", got, want)
+	} else if got, want := len(r.State.Metrics["demand"]), 1; got != want {
+		t.Fatalf("len(history)=%v, want %v", got, want)
+	}
+}